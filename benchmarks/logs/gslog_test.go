@@ -17,6 +17,8 @@
 package benchmarks
 
 import (
+	"context"
+	"testing"
 	"time"
 
 	"github.com/go-spring/log"
@@ -47,3 +49,28 @@ func fakeGSlogFields() []log.Field {
 		log.Any("error", errExample),
 	}
 }
+
+// BenchmarkGSMinimalInfof measures a minimal Infof that reaches the
+// appender with no context hooks configured, where record copies the
+// fields into the pooled event instead of retaining the caller's slice.
+func BenchmarkGSMinimalInfof(b *testing.B) {
+	err := log.RefreshConfig(map[string]string{
+		"appender.discard.type":       "DiscardAppender",
+		"logger.root.type":            "Logger",
+		"logger.root.level":           "info",
+		"logger.root.appenderRef.ref": "discard",
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	log.StringFromContext = nil
+	log.FieldsFromContext = nil
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			log.Infof(ctx, log.TagAppDef, "minimal message")
+		}
+	})
+}
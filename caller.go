@@ -17,10 +17,13 @@
 package log
 
 import (
+	"bytes"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/go-spring/stdlib/errutil"
 )
@@ -90,3 +93,24 @@ func FastCaller(skip int) (file string, line int) {
 	frameCache.Store(pc, frame)
 	return frame.File, frame.Line
 }
+
+// captureGoroutineID reports whether record captures the goroutine ID of
+// the caller into the event. Refresh turns it on only while one of the
+// installed layouts has IncludeGoroutineID, since the capture costs about
+// a microsecond per event, and Destroy turns it off.
+var captureGoroutineID atomic.Bool
+
+// goroutineID returns the ID of the current goroutine, parsed from the
+// header of its stack trace, e.g. "goroutine 18 [running]:". Go does not
+// expose the ID, and it cannot be cached per goroutine without knowing
+// it, so this is meant for debugging only.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
@@ -17,8 +17,15 @@
 package log
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 	"unsafe"
 
 	"github.com/go-spring/stdlib/ordered"
@@ -26,6 +33,15 @@ import (
 
 const MsgKey = "msg"
 
+// DetailKey is the key of the longer message part created by Detail.
+const DetailKey = "detail"
+
+// MarkerKey is the key of the fields created by Marker.
+const MarkerKey = "marker"
+
+// BadKey is the key used for a dangling key without a value in key-value pairs.
+const BadKey = "!BADKEY"
+
 // ValueType represents the underlying type stored in a Field.
 // The Type determines how Num and Any should be interpreted.
 type ValueType int
@@ -40,6 +56,9 @@ const (
 	ValueTypeArray
 	ValueTypeObject
 	ValueTypeFromMap
+	ValueTypePreEncoded
+	ValueTypeBytes
+	ValueTypeObjectMap
 )
 
 // Field represents a structured log field with a key and a typed value.
@@ -72,6 +91,21 @@ func Msgf(format string, args ...any) Field {
 	return String(MsgKey, fmt.Sprintf(format, args...))
 }
 
+// Detail creates a string Field with the fixed key "detail", for schemas
+// that separate a short message from a longer description.
+func Detail(s string) Field {
+	return String(DetailKey, s)
+}
+
+// Marker creates a string Field with the fixed key "marker", which tags
+// a single event with a category such as AUDIT or SECURITY, like an
+// slf4j marker. An event may carry several markers. Unlike tags, which
+// route all the events of a tag to one logger, markers can be matched
+// per event, e.g. by a MarkerFilter, see Event.HasMarker.
+func Marker(name string) Field {
+	return String(MarkerKey, name)
+}
+
 // Nil creates a Field whose value is nil (Type = ValueTypeReflect).
 func Nil(key string) Field {
 	return Reflect(key, nil)
@@ -165,6 +199,66 @@ func StringPtr(key string, val *string) Field {
 	return String(key, *val)
 }
 
+// Time creates a Field for a time.Time value, which is encoded in RFC 3339
+// format. A zero time may be encoded as null, see BaseLayout.ZeroTimeAsNull.
+func Time(key string, val time.Time) Field {
+	return Reflect(key, val)
+}
+
+// Duration creates a Field for a time.Duration, encoded as a string
+// like "1.5s", see time.Duration.String.
+func Duration(key string, val time.Duration) Field {
+	return String(key, val.String())
+}
+
+// DurationBucketed returns a Duration field, followed by a "<key>_bucket"
+// String field labeling the bucket the duration falls into, e.g. "100ms-1s".
+// The buckets are ascending bounds, each bucket includes its lower bound
+// and excludes its upper bound, so with bounds 100ms and 1s the labels are
+// "<100ms", "100ms-1s" and ">=1s". Without bounds, no bucket is added.
+func DurationBucketed(key string, d time.Duration, buckets []time.Duration) []Field {
+	fields := []Field{Duration(key, d)}
+	if len(buckets) == 0 {
+		return fields
+	}
+	i, _ := slices.BinarySearch(buckets, d)
+	if i < len(buckets) && buckets[i] == d {
+		i++
+	}
+	var label string
+	switch {
+	case i == 0:
+		label = "<" + buckets[0].String()
+	case i == len(buckets):
+		label = ">=" + buckets[i-1].String()
+	default:
+		label = buckets[i-1].String() + "-" + buckets[i].String()
+	}
+	return append(fields, String(key+"_bucket", label))
+}
+
+// Number creates a Field for a json.Number, encoded as an unquoted number
+// token, e.g. 12345678901234567890 or 1.50, so that its precision and
+// format are kept. A Number that is not a valid JSON number is encoded as
+// a string instead.
+func Number(key string, n json.Number) Field {
+	if !isJSONNumber(string(n)) {
+		return String(key, string(n))
+	}
+	return PreEncoded(key, []byte(n))
+}
+
+// isJSONNumber reports whether s is a valid JSON number. A valid JSON value
+// that starts with a minus sign or a digit, and ends with a digit so that
+// there is no trailing space, can only be a number.
+func isJSONNumber(s string) bool {
+	if s == "" || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) {
+		return false
+	}
+	c := s[len(s)-1]
+	return c >= '0' && c <= '9' && json.Valid([]byte(s))
+}
+
 // Reflect wraps any value into a Field using reflection.
 func Reflect(key string, val any) Field {
 	return Field{Key: key, Type: ValueTypeReflect, Any: val}
@@ -240,6 +334,67 @@ func Strings(key string, val []string) Field {
 	return Array(key, sliceOfString(val))
 }
 
+type sliceOfFrame []runtime.Frame
+
+// EncodeArray encodes each frame as an object with its function, file and line.
+func (arr sliceOfFrame) EncodeArray(enc Encoder) {
+	for _, f := range arr {
+		enc.AppendObjectBegin()
+		enc.AppendKey("func")
+		enc.AppendString(f.Function)
+		enc.AppendKey("file")
+		enc.AppendString(f.File)
+		enc.AppendKey("line")
+		enc.AppendInt64(int64(f.Line))
+		enc.AppendObjectEnd()
+	}
+}
+
+// Frames creates a Field with an array of stack frames, each written as
+// an object like {"func":"main.run","file":"/app/main.go","line":42},
+// e.g. for frames collected with runtime.CallersFrames. The frames are
+// encoded through the structured encoder without reflection.
+func Frames(key string, frames []runtime.Frame) Field {
+	return Array(key, sliceOfFrame(frames))
+}
+
+// Err creates a Field with the messages of an error chain, from the
+// outermost error to the root cause, so that the hierarchy of an error
+// wrapped with "%w" (e.g. by errutil.Explain or errutil.Stack) is kept.
+// The message of each wrapping layer has the message of the wrapped error
+// and the separator (": " or " >> ") removed. A nil error gives a nil value.
+func Err(key string, err error) Field {
+	if err == nil {
+		return Nil(key)
+	}
+	var msgs []string
+	for err != nil {
+		msg := err.Error()
+		cause := errors.Unwrap(err)
+		if cause != nil {
+			msg = trimCause(msg, cause.Error())
+		}
+		msgs = append(msgs, msg)
+		err = cause
+	}
+	return Strings(key, msgs)
+}
+
+// trimCause removes the message of the wrapped error and the
+// separator before it from the message of the wrapping error.
+func trimCause(msg, cause string) string {
+	s, ok := strings.CutSuffix(msg, cause)
+	if !ok {
+		return msg
+	}
+	for _, sep := range []string{": ", " >> "} {
+		if r, ok := strings.CutSuffix(s, sep); ok {
+			return r
+		}
+	}
+	return s
+}
+
 // ArrayValue is an interface for types that can be encoded as array.
 type ArrayValue interface {
 	EncodeArray(enc Encoder)
@@ -255,6 +410,62 @@ func Object(key string, fields ...Field) Field {
 	return Field{Key: key, Type: ValueTypeObject, Any: fields}
 }
 
+type objects[T any] struct {
+	items []T
+	fn    func(T) []Field
+}
+
+// EncodeArray encodes each item as an object built from its fields.
+func (arr objects[T]) EncodeArray(enc Encoder) {
+	for _, item := range arr.items {
+		enc.AppendObjectBegin()
+		EncodeFields(enc, arr.fn(item))
+		enc.AppendObjectEnd()
+	}
+}
+
+// Len returns the number of items, see Field.IsEmpty.
+func (arr objects[T]) Len() int {
+	return len(arr.items)
+}
+
+// Objects creates a Field with an array of objects, one per item, whose
+// fields are extracted by fn. Unlike Any, it encodes the items through
+// the structured encoder without reflection.
+func Objects[T any](key string, items []T, fn func(T) []Field) Field {
+	return Array(key, objects[T]{items: items, fn: fn})
+}
+
+// ObjectsMap creates a Field containing a nested object for each entry of
+// the map, in sorted key order, e.g. {"key":{"a":{...},"b":{...}}}.
+func ObjectsMap(key string, m map[string][]Field) Field {
+	fields := make([]Field, 0, len(m))
+	for _, k := range ordered.MapKeys(m) {
+		fields = append(fields, Object(k, m[k]...))
+	}
+	return Object(key, fields...)
+}
+
+// PreEncoded creates a Field whose value is written verbatim, without any
+// escaping or validation, e.g. a value that is already rendered as JSON.
+//
+// DANGER: the caller is fully responsible for raw being valid for the
+// output format. Untrusted or malformed bytes, e.g. an unescaped quote or
+// newline, corrupt the log line and may allow log injection. Encoders that
+// don't implement PreEncodedAppender write raw with AppendReflect as a
+// json.RawMessage instead.
+func PreEncoded(key string, raw []byte) Field {
+	return Field{Key: key, Type: ValueTypePreEncoded, Any: raw}
+}
+
+// Bytes creates a Field for a byte count, e.g. a payload size. It is
+// written as a number by the JSONEncoder, for aggregation, and in
+// human-readable form like 1.5MB by the TextEncoder, see HumanizeBytes.
+// Encoders that don't implement ByteSizeAppender write it as an int64.
+func Bytes(key string, n int64) Field {
+	return Field{Key: key, Type: ValueTypeBytes, Num: uint64(n)}
+}
+
 // FieldsFromMap creates a special Field that wraps a map[string]any.
 // When encoded, it expands the map into individual key-value fields.
 // This allows existing map structures to be easily converted into log fields
@@ -263,6 +474,33 @@ func FieldsFromMap(m map[string]any) Field {
 	return Field{Key: "", Type: ValueTypeFromMap, Any: m}
 }
 
+// Map creates a Field that nests a map[string]any under the key as an
+// object, encoding each value with Any in sorted key order. Unlike
+// FieldsFromMap, the entries are not expanded into top-level fields.
+func Map(key string, m map[string]any) Field {
+	return Field{Key: key, Type: ValueTypeObjectMap, Any: m}
+}
+
+// FieldsFromPairs converts alternating key-value pairs into Fields, keeping
+// the order in which they are given. Keys are expected to be strings, other
+// keys are formatted with fmt.Sprint. A trailing key without a value is not
+// dropped but reported as the value of a Field with the BadKey key.
+func FieldsFromPairs(pairs ...any) []Field {
+	fields := make([]Field, 0, (len(pairs)+1)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			key = fmt.Sprint(pairs[i])
+		}
+		if i+1 == len(pairs) {
+			fields = append(fields, String(BadKey, key))
+			break
+		}
+		fields = append(fields, Any(key, pairs[i+1]))
+	}
+	return fields
+}
+
 // Any creates a Field from a value of any type by inspecting its dynamic type.
 // It dispatches to the appropriate typed constructor based on the actual value.
 // If the type is not explicitly handled, it falls back to using Reflect.
@@ -369,11 +607,21 @@ func Any(key string, value any) Field {
 	case []string:
 		return Strings(key, val)
 
+	case time.Time:
+		return Time(key, val)
+	case json.Number:
+		return Number(key, val)
+
 	default:
 		return Reflect(key, val)
 	}
 }
 
+// stringValue returns the value of a Field of type ValueTypeString.
+func (f Field) stringValue() string {
+	return unsafe.String(f.Any.(*byte), f.Num)
+}
+
 // Encode encodes the Field into the Encoder based on its type.
 func (f Field) Encode(enc Encoder) {
 	switch f.Type {
@@ -391,7 +639,7 @@ func (f Field) Encode(enc Encoder) {
 		enc.AppendFloat64(math.Float64frombits(f.Num))
 	case ValueTypeString:
 		enc.AppendKey(f.Key)
-		enc.AppendString(unsafe.String(f.Any.(*byte), f.Num))
+		enc.AppendString(f.stringValue())
 	case ValueTypeReflect:
 		enc.AppendKey(f.Key)
 		enc.AppendReflect(f.Any)
@@ -410,10 +658,76 @@ func (f Field) Encode(enc Encoder) {
 		for _, k := range ordered.MapKeys(m) {
 			Any(k, m[k]).Encode(enc)
 		}
+	case ValueTypeObjectMap:
+		enc.AppendKey(f.Key)
+		enc.AppendObjectBegin()
+		m := f.Any.(map[string]any)
+		for _, k := range ordered.MapKeys(m) {
+			Any(k, m[k]).Encode(enc)
+		}
+		enc.AppendObjectEnd()
+	case ValueTypePreEncoded:
+		enc.AppendKey(f.Key)
+		if p, ok := enc.(PreEncodedAppender); ok {
+			p.AppendPreEncoded(f.Any.([]byte))
+		} else {
+			enc.AppendReflect(json.RawMessage(f.Any.([]byte)))
+		}
+	case ValueTypeBytes:
+		enc.AppendKey(f.Key)
+		if b, ok := enc.(ByteSizeAppender); ok {
+			b.AppendByteSize(int64(f.Num))
+		} else {
+			enc.AppendInt64(int64(f.Num))
+		}
 	default: // for linter
 	}
 }
 
+// IsEmpty reports whether the value of the Field is empty, which depends
+// on its type:
+//   - bool: false
+//   - integer and float: zero
+//   - string: ""
+//   - reflect: nil, or a nil pointer, map, slice or interface
+//   - array: a slice without elements, e.g. from Ints or Strings, or no
+//     items, from Objects
+//   - object: no fields
+//   - map from FieldsFromMap or Map: no entries
+//   - pre-encoded: no bytes
+func (f Field) IsEmpty() bool {
+	switch f.Type {
+	case ValueTypeBool, ValueTypeInt64, ValueTypeUint64, ValueTypeString, ValueTypeBytes:
+		return f.Num == 0
+	case ValueTypeFloat64:
+		return math.Float64frombits(f.Num) == 0
+	case ValueTypeReflect:
+		if f.Any == nil {
+			return true
+		}
+		switch v := reflect.ValueOf(f.Any); v.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			return v.IsNil()
+		default:
+			return false
+		}
+	case ValueTypeArray:
+		if arr, ok := f.Any.(interface{ Len() int }); ok {
+			return arr.Len() == 0
+		}
+		v := reflect.ValueOf(f.Any)
+		return v.Kind() == reflect.Slice && v.Len() == 0
+	case ValueTypeObject:
+		return len(f.Any.([]Field)) == 0
+	case ValueTypeFromMap, ValueTypeObjectMap:
+		return len(f.Any.(map[string]any)) == 0
+	case ValueTypePreEncoded:
+		return len(f.Any.([]byte)) == 0
+	default:
+		return false
+	}
+}
+
 // EncodeFields encodes a slice of Fields into the Encoder.
 func EncodeFields(enc Encoder, fields []Field) {
 	for _, f := range fields {
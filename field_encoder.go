@@ -20,6 +20,10 @@ import (
 	"encoding/json"
 	"io"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unicode/utf8"
 )
 
@@ -48,11 +52,80 @@ type Encoder interface {
 	AppendReflect(v any)
 }
 
+// PreEncodedAppender is implemented by encoders that can write a value
+// verbatim, see PreEncoded.
+type PreEncodedAppender interface {
+	AppendPreEncoded(raw []byte)
+}
+
+// ByteSizeAppender is implemented by encoders that choose how a byte
+// count is written, see Bytes.
+type ByteSizeAppender interface {
+	AppendByteSize(n int64)
+}
+
 var (
 	_ Encoder = (*JSONEncoder)(nil)
 	_ Encoder = (*TextEncoder)(nil)
+
+	_ PreEncodedAppender = (*JSONEncoder)(nil)
+	_ PreEncodedAppender = (*TextEncoder)(nil)
+	_ ByteSizeAppender   = (*JSONEncoder)(nil)
+	_ ByteSizeAppender   = (*TextEncoder)(nil)
 )
 
+// keyTransformer holds the registered key transformer, if any.
+var keyTransformer atomic.Pointer[keyTransform]
+
+// keyTransformCacheSize caps the number of transformed keys that are cached,
+// so that dynamic keys can't grow the cache without bound. Keys beyond the
+// cap are transformed on every use.
+const keyTransformCacheSize = 4096
+
+// keyTransform rewrites field keys and caches the results by original key.
+type keyTransform struct {
+	fn    func(key string) string
+	mutex sync.RWMutex
+	cache map[string]string
+}
+
+// RegisterKeyTransformer registers a function that rewrites every field key
+// written by the built-in encoders, including the keys of nested objects,
+// e.g. to normalize keys to snake_case. Up to keyTransformCacheSize
+// transformed keys are cached by the original key, so fn must be
+// deterministic. Passing nil removes the transformer.
+func RegisterKeyTransformer(fn func(key string) string) {
+	if fn == nil {
+		keyTransformer.Store(nil)
+		return
+	}
+	keyTransformer.Store(&keyTransform{
+		fn:    fn,
+		cache: make(map[string]string),
+	})
+}
+
+// transformKey returns the key rewritten by the registered key transformer.
+func transformKey(key string) string {
+	t := keyTransformer.Load()
+	if t == nil {
+		return key
+	}
+	t.mutex.RLock()
+	s, ok := t.cache[key]
+	t.mutex.RUnlock()
+	if ok {
+		return s
+	}
+	s = t.fn(key)
+	t.mutex.Lock()
+	if len(t.cache) < keyTransformCacheSize {
+		t.cache[key] = s
+	}
+	t.mutex.Unlock()
+	return s
+}
+
 // JSONTokenType represents the type of the last token written to JSONEncoder.
 // It is used to determine when separators (commas) are required.
 type JSONTokenType int
@@ -69,8 +142,20 @@ const (
 
 // JSONEncoder encodes log fields into standard JSON format.
 type JSONEncoder struct {
-	out  Writer        // Buffer to write JSON output.
-	last JSONTokenType // The last token type written.
+	out         Writer        // Buffer to write JSON output.
+	last        JSONTokenType // The last token type written.
+	emptyAsNull bool          // Whether empty arrays/objects are written as null.
+	pending     byte          // Opening bracket not yet written, see emptyAsNull.
+
+	zeroTimeAsNull bool // Whether a zero time.Time is written as null.
+
+	indent string // Indentation per nesting level, empty for compact output.
+	depth  int    // Nesting depth of the current container.
+
+	stripANSI    bool // Whether ANSI escape sequences are removed from strings.
+	stripControl bool // Whether control characters are removed from strings.
+
+	trustKeys bool // Whether keys take the fast path, see SetTrustKeys.
 }
 
 // NewJSONEncoder creates a new JSONEncoder.
@@ -78,14 +163,75 @@ func NewJSONEncoder(out Writer) *JSONEncoder {
 	return &JSONEncoder{out: out, last: JSONTokenUnknown}
 }
 
+// NewPrettyJSONEncoder creates a new JSONEncoder that writes each element
+// of arrays and objects on its own line, indented by indent per nesting
+// level, which is easier to read during local debugging.
+func NewPrettyJSONEncoder(out Writer, indent string) *JSONEncoder {
+	return &JSONEncoder{out: out, last: JSONTokenUnknown, indent: indent}
+}
+
+// SetEmptyAsNull sets whether zero-length arrays and objects are written
+// as null instead of [] and {}.
+func (enc *JSONEncoder) SetEmptyAsNull(v bool) {
+	enc.emptyAsNull = v
+}
+
+// SetZeroTimeAsNull sets whether a zero time.Time is written as null
+// instead of "0001-01-01T00:00:00Z".
+func (enc *JSONEncoder) SetZeroTimeAsNull(v bool) {
+	enc.zeroTimeAsNull = v
+}
+
+// isNullTime reports whether v is a zero time.Time to be written as null.
+func (enc *JSONEncoder) isNullTime(v any) bool {
+	t, ok := v.(time.Time)
+	return ok && enc.zeroTimeAsNull && t.IsZero()
+}
+
+// SetStripANSI sets whether ANSI escape sequences, e.g. color codes
+// injected through user input, are removed from string values.
+func (enc *JSONEncoder) SetStripANSI(v bool) {
+	enc.stripANSI = v
+}
+
+// SetStripControl sets whether control characters other than tab, newline
+// and carriage return, as well as bidirectional overrides, are removed from
+// string values. This mitigates log forging through user input.
+func (enc *JSONEncoder) SetStripControl(v bool) {
+	enc.stripControl = v
+}
+
+// SetTrustKeys sets whether keys are expected to be constant printable
+// ASCII, as they usually are. Such keys are written in one piece after a
+// cheap scan instead of rune by rune through WriteLogString. A key that
+// fails the scan, e.g. a non-ASCII key or one needing escapes, is still
+// written by WriteLogString, so the output is the same either way.
+func (enc *JSONEncoder) SetTrustKeys(v bool) {
+	enc.trustKeys = v
+}
+
+// strip removes the unwanted characters from a string value.
+func (enc *JSONEncoder) strip(v string) string {
+	if enc.stripANSI || enc.stripControl {
+		return StripString(v, enc.stripANSI, enc.stripControl)
+	}
+	return v
+}
+
 // Reset resets the encoder's state.
 func (enc *JSONEncoder) Reset() {
 	enc.last = JSONTokenUnknown
+	enc.pending = 0
+	enc.depth = 0
 }
 
 // AppendEncoderBegin writes the start of an encoder section.
+// The outermost object is always written, even if it is empty.
 func (enc *JSONEncoder) AppendEncoderBegin() {
-	enc.AppendObjectBegin()
+	enc.appendSeparator()
+	enc.last = JSONTokenObjectBegin
+	enc.depth++
+	_ = enc.out.WriteByte('{')
 }
 
 // AppendEncoderEnd writes the end of an encoder section.
@@ -95,35 +241,85 @@ func (enc *JSONEncoder) AppendEncoderEnd() {
 
 // AppendObjectBegin writes the beginning of a JSON object.
 func (enc *JSONEncoder) AppendObjectBegin() {
-	enc.appendSeparator()
-	enc.last = JSONTokenObjectBegin
-	_ = enc.out.WriteByte('{')
+	enc.appendBegin(JSONTokenObjectBegin, '{')
 }
 
 // AppendObjectEnd writes the end of a JSON object.
 func (enc *JSONEncoder) AppendObjectEnd() {
-	enc.last = JSONTokenObjectEnd
-	_ = enc.out.WriteByte('}')
+	enc.appendEnd(JSONTokenObjectEnd, '}')
 }
 
 // AppendArrayBegin writes the beginning of a JSON array.
 func (enc *JSONEncoder) AppendArrayBegin() {
-	enc.appendSeparator()
-	enc.last = JSONTokenArrayBegin
-	_ = enc.out.WriteByte('[')
+	enc.appendBegin(JSONTokenArrayBegin, '[')
 }
 
 // AppendArrayEnd writes the end of a JSON array.
 func (enc *JSONEncoder) AppendArrayEnd() {
-	enc.last = JSONTokenArrayEnd
-	_ = enc.out.WriteByte(']')
+	enc.appendEnd(JSONTokenArrayEnd, ']')
+}
+
+// appendBegin writes the opening bracket of an array or object. When
+// emptyAsNull is set, the bracket is held back until the first element
+// is written, so that an empty container can still become null.
+func (enc *JSONEncoder) appendBegin(token JSONTokenType, b byte) {
+	enc.appendSeparator()
+	enc.last = token
+	enc.depth++
+	if enc.emptyAsNull {
+		enc.pending = b
+		return
+	}
+	_ = enc.out.WriteByte(b)
+}
+
+// appendEnd writes the closing bracket of an array or object,
+// or null if the container is empty and emptyAsNull is set.
+func (enc *JSONEncoder) appendEnd(token JSONTokenType, b byte) {
+	empty := enc.last == JSONTokenObjectBegin || enc.last == JSONTokenArrayBegin
+	enc.last = token
+	enc.depth--
+	if enc.pending != 0 {
+		enc.pending = 0
+		_, _ = enc.out.WriteString("null")
+		return
+	}
+	if !empty {
+		enc.appendNewline()
+	}
+	_ = enc.out.WriteByte(b)
 }
 
 // appendSeparator writes a comma if the previous token
 // requires separation (e.g., between values).
+// A held back opening bracket is written first.
+// When pretty-printing, each element starts on a new line.
 func (enc *JSONEncoder) appendSeparator() {
-	if enc.last == JSONTokenObjectEnd || enc.last == JSONTokenArrayEnd || enc.last == JSONTokenValue {
+	if enc.pending != 0 {
+		_ = enc.out.WriteByte(enc.pending)
+		enc.pending = 0
+		enc.appendNewline()
+		return
+	}
+	switch enc.last {
+	case JSONTokenObjectEnd, JSONTokenArrayEnd, JSONTokenValue:
 		_ = enc.out.WriteByte(',')
+		enc.appendNewline()
+	case JSONTokenObjectBegin, JSONTokenArrayBegin:
+		enc.appendNewline()
+	default: // for linter
+	}
+}
+
+// appendNewline writes a newline and the indentation of the current depth,
+// if pretty-printing.
+func (enc *JSONEncoder) appendNewline() {
+	if enc.indent == "" {
+		return
+	}
+	_ = enc.out.WriteByte('\n')
+	for range enc.depth {
+		_, _ = enc.out.WriteString(enc.indent)
 	}
 }
 
@@ -132,9 +328,12 @@ func (enc *JSONEncoder) AppendKey(key string) {
 	enc.appendSeparator()
 	enc.last = JSONTokenKey
 	_ = enc.out.WriteByte('"')
-	WriteLogString(enc.out, key)
+	writeKey(enc.out, transformKey(key), enc.trustKeys)
 	_ = enc.out.WriteByte('"')
 	_ = enc.out.WriteByte(':')
+	if enc.indent != "" {
+		_ = enc.out.WriteByte(' ')
+	}
 }
 
 // AppendBool writes a boolean value.
@@ -170,7 +369,7 @@ func (enc *JSONEncoder) AppendString(v string) {
 	enc.appendSeparator()
 	enc.last = JSONTokenValue
 	_ = enc.out.WriteByte('"')
-	WriteLogString(enc.out, v)
+	WriteLogString(enc.out, enc.strip(v))
 	_ = enc.out.WriteByte('"')
 }
 
@@ -179,6 +378,10 @@ func (enc *JSONEncoder) AppendString(v string) {
 func (enc *JSONEncoder) AppendReflect(v any) {
 	enc.appendSeparator()
 	enc.last = JSONTokenValue
+	if enc.isNullTime(v) {
+		_, _ = enc.out.WriteString("null")
+		return
+	}
 	b, err := json.Marshal(v)
 	if err != nil {
 		_ = enc.out.WriteByte('"')
@@ -189,6 +392,18 @@ func (enc *JSONEncoder) AppendReflect(v any) {
 	_, _ = enc.out.Write(b)
 }
 
+// AppendPreEncoded writes raw as is, without escaping, see PreEncoded.
+func (enc *JSONEncoder) AppendPreEncoded(raw []byte) {
+	enc.appendSeparator()
+	enc.last = JSONTokenValue
+	_, _ = enc.out.Write(raw)
+}
+
+// AppendByteSize writes a byte count as a number.
+func (enc *JSONEncoder) AppendByteSize(n int64) {
+	enc.AppendInt64(n)
+}
+
 // TextEncoder encodes fields as "key=value" pairs separated by a delimiter.
 // For nested objects and arrays, it delegates to the embedded JSONEncoder.
 type TextEncoder struct {
@@ -197,6 +412,8 @@ type TextEncoder struct {
 	jsonEncoder *JSONEncoder // Embedded JSON encoder for nested objects/arrays
 	jsonDepth   int8         // Tracks depth of nested JSON structures
 	hasWritten  bool         // Tracks if the first key-value has been written
+	hasKey      bool         // Tracks if a top-level key awaits its value
+	trustKeys   bool         // Whether keys take the fast path, see SetTrustKeys
 }
 
 // NewTextEncoder creates a new TextEncoder, using the specified separator.
@@ -208,6 +425,34 @@ func NewTextEncoder(out Writer, separator string) *TextEncoder {
 	}
 }
 
+// SetEmptyAsNull sets whether zero-length arrays and objects are written
+// as null instead of [] and {}.
+func (enc *TextEncoder) SetEmptyAsNull(v bool) {
+	enc.jsonEncoder.SetEmptyAsNull(v)
+}
+
+// SetZeroTimeAsNull sets whether a zero time.Time is written as null
+// instead of "0001-01-01T00:00:00Z".
+func (enc *TextEncoder) SetZeroTimeAsNull(v bool) {
+	enc.jsonEncoder.SetZeroTimeAsNull(v)
+}
+
+// SetStripANSI sets whether ANSI escape sequences are removed from string values.
+func (enc *TextEncoder) SetStripANSI(v bool) {
+	enc.jsonEncoder.SetStripANSI(v)
+}
+
+// SetStripControl sets whether control characters are removed from string values.
+func (enc *TextEncoder) SetStripControl(v bool) {
+	enc.jsonEncoder.SetStripControl(v)
+}
+
+// SetTrustKeys sets whether keys take the fast path for printable ASCII.
+func (enc *TextEncoder) SetTrustKeys(v bool) {
+	enc.trustKeys = v
+	enc.jsonEncoder.SetTrustKeys(v)
+}
+
 // AppendEncoderBegin writes the start of an encoder section.
 func (enc *TextEncoder) AppendEncoderBegin() {}
 
@@ -217,6 +462,9 @@ func (enc *TextEncoder) AppendEncoderEnd() {}
 // AppendObjectBegin signals the start of a JSON object.
 // Increments the depth and delegates to the JSON encoder.
 func (enc *TextEncoder) AppendObjectBegin() {
+	if enc.jsonDepth == 0 {
+		enc.beginValue()
+	}
 	enc.jsonDepth++
 	enc.jsonEncoder.AppendObjectBegin()
 }
@@ -234,6 +482,9 @@ func (enc *TextEncoder) AppendObjectEnd() {
 // AppendArrayBegin signals the start of a JSON array.
 // Increments the depth and delegates to the JSON encoder.
 func (enc *TextEncoder) AppendArrayBegin() {
+	if enc.jsonDepth == 0 {
+		enc.beginValue()
+	}
 	enc.jsonDepth++
 	enc.jsonEncoder.AppendArrayBegin()
 }
@@ -261,8 +512,19 @@ func (enc *TextEncoder) AppendKey(key string) {
 	} else {
 		enc.hasWritten = true
 	}
-	WriteLogString(enc.out, key)
+	writeKey(enc.out, transformKey(key), enc.trustKeys)
 	_ = enc.out.WriteByte('=')
+	enc.hasKey = true
+}
+
+// beginValue is called before a top-level value is written. A value
+// without a preceding key, e.g. from a malformed custom field, is given
+// the BadKey so that the output stays well-formed.
+func (enc *TextEncoder) beginValue() {
+	if !enc.hasKey {
+		enc.AppendKey(BadKey)
+	}
+	enc.hasKey = false
 }
 
 // AppendBool appends a boolean value, using JSON encoder if nested.
@@ -271,6 +533,7 @@ func (enc *TextEncoder) AppendBool(v bool) {
 		enc.jsonEncoder.AppendBool(v)
 		return
 	}
+	enc.beginValue()
 	_, _ = enc.out.WriteString(strconv.FormatBool(v))
 }
 
@@ -280,6 +543,7 @@ func (enc *TextEncoder) AppendInt64(v int64) {
 		enc.jsonEncoder.AppendInt64(v)
 		return
 	}
+	enc.beginValue()
 	_, _ = enc.out.WriteString(strconv.FormatInt(v, 10))
 }
 
@@ -289,6 +553,7 @@ func (enc *TextEncoder) AppendUint64(v uint64) {
 		enc.jsonEncoder.AppendUint64(v)
 		return
 	}
+	enc.beginValue()
 	_, _ = enc.out.WriteString(strconv.FormatUint(v, 10))
 }
 
@@ -298,6 +563,7 @@ func (enc *TextEncoder) AppendFloat64(v float64) {
 		enc.jsonEncoder.AppendFloat64(v)
 		return
 	}
+	enc.beginValue()
 	_, _ = enc.out.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
 }
 
@@ -307,7 +573,8 @@ func (enc *TextEncoder) AppendString(v string) {
 		enc.jsonEncoder.AppendString(v)
 		return
 	}
-	WriteLogString(enc.out, v)
+	enc.beginValue()
+	WriteLogString(enc.out, enc.jsonEncoder.strip(v))
 }
 
 // AppendReflect uses reflection to marshal any value as JSON.
@@ -317,6 +584,11 @@ func (enc *TextEncoder) AppendReflect(v any) {
 		enc.jsonEncoder.AppendReflect(v)
 		return
 	}
+	enc.beginValue()
+	if enc.jsonEncoder.isNullTime(v) {
+		_, _ = enc.out.WriteString("null")
+		return
+	}
 	b, err := json.Marshal(v)
 	if err != nil {
 		WriteLogString(enc.out, err.Error())
@@ -325,8 +597,127 @@ func (enc *TextEncoder) AppendReflect(v any) {
 	_, _ = enc.out.Write(b)
 }
 
+// AppendPreEncoded writes raw as is, without escaping, see PreEncoded.
+// If nested, delegates to JSON encoder.
+func (enc *TextEncoder) AppendPreEncoded(raw []byte) {
+	if enc.jsonDepth > 0 {
+		enc.jsonEncoder.AppendPreEncoded(raw)
+		return
+	}
+	enc.beginValue()
+	_, _ = enc.out.Write(raw)
+}
+
+// AppendByteSize appends a byte count in human-readable form, see
+// HumanizeBytes. Nested in a JSON structure, it is written as a number.
+func (enc *TextEncoder) AppendByteSize(n int64) {
+	if enc.jsonDepth > 0 {
+		enc.jsonEncoder.AppendByteSize(n)
+		return
+	}
+	enc.beginValue()
+	_, _ = enc.out.WriteString(HumanizeBytes(n))
+}
+
 /************************************* string ********************************/
 
+// StripString removes ANSI escape sequences from s if ansi is set, and
+// control characters if control is set. Tab, newline and carriage return
+// are kept, since they are escaped when written. The bidirectional
+// override characters are treated as control characters as well.
+// s is returned as is if nothing needs to be removed.
+func StripString(s string, ansi, control bool) string {
+	var (
+		sb    strings.Builder
+		dirty bool
+	)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		n := 0 // length of the sequence to remove
+		switch {
+		case ansi && (r == 0x1b || r == 0x9b):
+			n = ansiSequenceLen(s[i:])
+		case control && isControlRune(r):
+			n = size
+		}
+		if n == 0 {
+			if dirty {
+				sb.WriteString(s[i : i+size])
+			}
+			i += size
+			continue
+		}
+		if !dirty {
+			dirty = true
+			sb.Grow(len(s))
+			sb.WriteString(s[:i])
+		}
+		i += n
+	}
+	if !dirty {
+		return s
+	}
+	return sb.String()
+}
+
+// ansiSequenceLen returns the length of the ANSI escape sequence at the
+// beginning of s, which starts with ESC or the C1 CSI character.
+func ansiSequenceLen(s string) int {
+	i := 2 // after CSI, either "ESC [" or U+009B
+	if s[0] == 0x1b {
+		if len(s) == 1 {
+			return 1
+		}
+		switch c := s[1]; {
+		case c == ']': // OSC, terminated by BEL or "ESC \"
+			for j := 2; j < len(s); j++ {
+				if s[j] == 0x07 {
+					return j + 1
+				}
+				if s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\' {
+					return j + 2
+				}
+			}
+			return len(s)
+		case c != '[': // intermediate bytes, then a final byte
+			j := 1
+			for j < len(s) && 0x20 <= s[j] && s[j] <= 0x2f {
+				j++
+			}
+			if j < len(s) && 0x30 <= s[j] && s[j] <= 0x7e {
+				j++
+			}
+			return j
+		}
+	}
+	// CSI: parameter bytes, intermediate bytes, then a final byte
+	for i < len(s) && 0x30 <= s[i] && s[i] <= 0x3f {
+		i++
+	}
+	for i < len(s) && 0x20 <= s[i] && s[i] <= 0x2f {
+		i++
+	}
+	if i < len(s) && 0x40 <= s[i] && s[i] <= 0x7e {
+		i++
+	}
+	return i
+}
+
+// isControlRune reports whether r is a control character that may be
+// abused to forge log output.
+func isControlRune(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return false
+	case r < 0x20 || r == 0x7f || (0x80 <= r && r <= 0x9f):
+		return true
+	case 0x202a <= r && r <= 0x202e, 0x2066 <= r && r <= 0x2069:
+		return true // bidirectional overrides
+	default:
+		return false
+	}
+}
+
 // WriteLogString escapes and writes a string according to JSON rules.
 func WriteLogString(out Writer, s string) {
 	for i := 0; i < len(s); {
@@ -348,6 +739,26 @@ func WriteLogString(out Writer, s string) {
 	}
 }
 
+// writeKey writes a key. With trust, a key of printable ASCII characters
+// that need no escaping is written as is, skipping WriteLogString.
+func writeKey(out Writer, key string, trust bool) {
+	if trust && isPlainKey(key) {
+		_, _ = out.WriteString(key)
+		return
+	}
+	WriteLogString(out, key)
+}
+
+// isPlainKey reports whether WriteLogString would write s unchanged.
+func isPlainKey(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if b := s[i]; b < 0x20 || b >= utf8.RuneSelf || b == '\\' || b == '"' {
+			return false
+		}
+	}
+	return true
+}
+
 // tryAddRuneSelf handles ASCII characters and escapes control/quote characters.
 func tryAddRuneSelf(out Writer, b byte) bool {
 	const _hex = "0123456789abcdef"
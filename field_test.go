@@ -18,8 +18,17 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+	"unicode"
 
+	"github.com/go-spring/stdlib/errutil"
 	"github.com/go-spring/stdlib/testing/assert"
 )
 
@@ -515,3 +524,910 @@ func TestTextEncoder(t *testing.T) {
 		assert.String(t, buf.String()).Equal("true_val=true false_val=false")
 	})
 }
+
+func TestFieldsFromPairs(t *testing.T) {
+
+	t.Run("keep order", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, FieldsFromPairs("zeta", 1, "alpha", "a", "mid", true))
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"zeta":1,"alpha":"a","mid":true}`)
+	})
+
+	t.Run("non-string key", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, " ")
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, FieldsFromPairs(1, "a", "b", 2.5))
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`1=a b=2.5`)
+	})
+
+	t.Run("odd length", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, FieldsFromPairs("a", 1, "dangling"))
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"a":1,"!BADKEY":"dangling"}`)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert.That(t, len(FieldsFromPairs())).Equal(0)
+	})
+}
+
+func TestEmptyAsNull(t *testing.T) {
+	fields := []Field{
+		Strings("strings", []string{}),
+		Object("object"),
+		Object("nested", Strings("inner", nil), Object("empty")),
+		Strings("values", []string{"a"}),
+	}
+
+	t.Run("json default", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `{"strings":[],"object":{},"nested":{"inner":[],"empty":{}},"values":["a"]}`
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("json empty as null", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.SetEmptyAsNull(true)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `{"strings":null,"object":null,"nested":{"inner":null,"empty":null},"values":["a"]}`
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("json empty encoder", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.SetEmptyAsNull(true)
+		enc.AppendEncoderBegin()
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{}`)
+	})
+
+	t.Run("text default", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `strings=[]||object={}||nested={"inner":[],"empty":{}}||values=["a"]`
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("text empty as null", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.SetEmptyAsNull(true)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `strings=null||object=null||nested={"inner":null,"empty":null}||values=["a"]`
+		assert.String(t, buf.String()).Equal(expect)
+	})
+}
+
+func TestRegisterKeyTransformer(t *testing.T) {
+	snakeCase := func(key string) string {
+		var sb strings.Builder
+		for i, r := range key {
+			if unicode.IsUpper(r) {
+				if i > 0 {
+					sb.WriteByte('_')
+				}
+				r = unicode.ToLower(r)
+			}
+			sb.WriteRune(r)
+		}
+		return sb.String()
+	}
+
+	RegisterKeyTransformer(snakeCase)
+	defer RegisterKeyTransformer(nil)
+
+	fields := []Field{
+		String("UserName", "tom"),
+		Object("RequestInfo", Int("StatusCode", 200), Strings("TagList", []string{"a"})),
+		String("UserName", "jerry"),
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `{"user_name":"tom","request_info":{"status_code":200,"tag_list":["a"]},"user_name":"jerry"}`
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `user_name=tom||request_info={"status_code":200,"tag_list":["a"]}||user_name=jerry`
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("cache size", func(t *testing.T) {
+		for i := range keyTransformCacheSize + 10 {
+			assert.String(t, transformKey(fmt.Sprint("Key", i))).Equal(fmt.Sprint("key", i))
+		}
+		assert.Number(t, len(keyTransformer.Load().cache)).Equal(keyTransformCacheSize)
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		RegisterKeyTransformer(nil)
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields[:1])
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"UserName":"tom"}`)
+	})
+}
+
+func TestStripString(t *testing.T) {
+	tests := []struct {
+		s       string
+		ansi    bool
+		control bool
+		expect  string
+	}{
+		{"plain text", true, true, "plain text"},
+		{"\x1b[31mred\x1b[0m", false, false, "\x1b[31mred\x1b[0m"},
+		{"\x1b[31mred\x1b[0m", true, false, "red"},
+		{"\x1b[1;32;40mok\x1b[K", true, false, "ok"},
+		{"\x1b]0;title\x07text", true, false, "text"},
+		{"\x1b]8;;http://x\x1b\\link", true, false, "link"},
+		{"a\x1bcb", true, false, "ab"},
+		{"a\x1b(Bb", true, false, "ab"},
+		{"a\u009b2Jb", true, false, "ab"},
+		{"tail\x1b", true, false, "tail"},
+		{"\x1b[31mred", false, true, "[31mred"},
+		{"a\x00b\x7fc\u0085d", false, true, "abcd"},
+		{"keep\t\r\n", false, true, "keep\t\r\n"},
+		{"user‮exe.txt", false, true, "userexe.txt"},
+		{"中国\x1b[0m", true, true, "中国"},
+	}
+	for _, tt := range tests {
+		assert.String(t, StripString(tt.s, tt.ansi, tt.control)).Equal(tt.expect)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.SetStripANSI(true)
+		enc.SetStripControl(true)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, []Field{
+			String("user", "\x1b[2J\x1b[Hadmin\x00"),
+			Object("obj", String("name", "\x1b[31mtom\x1b[0m")),
+		})
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"user":"admin","obj":{"name":"tom"}}`)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.SetStripANSI(true)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, []Field{
+			String("user", "\x1b[2J\x1b[Hadmin\nINFO forged"),
+			Strings("names", []string{"\x1b[31mtom\x1b[0m"}),
+		})
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`user=admin\nINFO forged||names=["tom"]`)
+	})
+}
+
+func TestErr(t *testing.T) {
+	encode := func(f Field) string {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		f.Encode(enc)
+		enc.AppendEncoderEnd()
+		return buf.String()
+	}
+
+	assert.String(t, encode(Err("err", nil))).Equal(`{"err":null}`)
+
+	err := errors.New("connection refused")
+	assert.String(t, encode(Err("err", err))).Equal(`{"err":["connection refused"]}`)
+
+	err = errutil.Explain(err, "dial %s error", "db:3306")
+	err = errutil.Stack(err, "query user %d error", 42)
+	err = fmt.Errorf("handle request: %w", err)
+	const expect = `{"err":["handle request","query user 42 error","dial db:3306 error","connection refused"]}`
+	assert.String(t, encode(Err("err", err))).Equal(expect)
+
+	// a layer not ending with the cause message is kept as is
+	err = fmt.Errorf("%w (retry later)", errors.New("timeout"))
+	assert.String(t, encode(Err("err", err))).Equal(`{"err":["timeout (retry later)","timeout"]}`)
+}
+
+func TestZeroTimeAsNull(t *testing.T) {
+	ts := time.Date(2025, 6, 1, 8, 30, 0, 0, time.UTC)
+	fields := []Field{
+		Time("zero", time.Time{}),
+		Any("time", ts),
+		Object("obj", Time("at", time.Time{})),
+	}
+
+	t.Run("json default", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `{"zero":"0001-01-01T00:00:00Z","time":"2025-06-01T08:30:00Z","obj":{"at":"0001-01-01T00:00:00Z"}}`
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("json zero time as null", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.SetZeroTimeAsNull(true)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `{"zero":null,"time":"2025-06-01T08:30:00Z","obj":{"at":null}}`
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("text zero time as null", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.SetZeroTimeAsNull(true)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `zero=null||time="2025-06-01T08:30:00Z"||obj={"at":null}`
+		assert.String(t, buf.String()).Equal(expect)
+	})
+}
+
+// objectArray is an ArrayValue whose elements are objects.
+type objectArray [][]Field
+
+func (a objectArray) EncodeArray(enc Encoder) {
+	for _, fields := range a {
+		enc.AppendObjectBegin()
+		EncodeFields(enc, fields)
+		enc.AppendObjectEnd()
+	}
+}
+
+func TestTextEncoderDepth(t *testing.T) {
+	encode := func(fields ...Field) string {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		return buf.String()
+	}
+
+	t.Run("array of objects of arrays", func(t *testing.T) {
+		s := encode(
+			Array("a", objectArray{
+				{Strings("x", []string{"1", "2"}), Int("y", 1)},
+				{Strings("x", nil)},
+			}),
+			Int("b", 2),
+		)
+		assert.String(t, s).Equal(`a=[{"x":["1","2"],"y":1},{"x":[]}]||b=2`)
+	})
+
+	t.Run("consecutive nested fields", func(t *testing.T) {
+		s := encode(
+			Object("a", Object("b", Strings("c", []string{"d"}))),
+			Array("e", objectArray{{}}),
+			Object("f"),
+			String("g", "h"),
+		)
+		assert.String(t, s).Equal(`a={"b":{"c":["d"]}}||e=[{}]||f={}||g=h`)
+	})
+
+	t.Run("nested objects in array keep separators", func(t *testing.T) {
+		s := encode(Array("a", objectArray{{Int("x", 1)}, {Int("x", 2)}, {Int("x", 3)}}))
+		assert.String(t, s).Equal(`a=[{"x":1},{"x":2},{"x":3}]`)
+	})
+
+	t.Run("reuse after nesting", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		Object("a", Int("x", 1)).Encode(enc)
+		assert.Number(t, enc.jsonDepth).Equal(0)
+		assert.That(t, enc.jsonEncoder.last).Equal(JSONTokenUnknown)
+		Array("b", objectArray{{Object("c", Strings("d", nil))}}).Encode(enc)
+		assert.Number(t, enc.jsonDepth).Equal(0)
+		assert.String(t, buf.String()).Equal(`a={"x":1}||b=[{"c":{"d":[]}}]`)
+	})
+}
+
+func TestTrustKeys(t *testing.T) {
+	fields := []Field{
+		String("plain", "a"),
+		String("名字", "b"),
+		String("a\"b\\c", "c"),
+		String("tab\tkey", "d"),
+		String("bad\xffkey", "e"),
+		Object("obj", Int("ключ", 1)),
+	}
+	for _, trust := range []bool{false, true} {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.SetTrustKeys(trust)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"plain":"a","名字":"b","a\"b\\c":"c","tab\tkey":"d","bad\ufffdkey":"e","obj":{"ключ":1}}`)
+
+		buf.Reset()
+		text := NewTextEncoder(buf, "||")
+		text.SetTrustKeys(trust)
+		EncodeFields(text, fields)
+		assert.String(t, buf.String()).Equal(`plain=a||名字=b||a\"b\\c=c||tab\tkey=d||bad\ufffdkey=e||obj={"ключ":1}`)
+	}
+}
+
+func BenchmarkTrustKeys(b *testing.B) {
+
+	// Each key is written in one piece instead of byte by byte.
+	//
+	// BenchmarkTrustKeys/json        2495047  486.7 ns/op
+	// BenchmarkTrustKeys/text        2631366  516.0 ns/op
+	// BenchmarkTrustKeys/json_trust  3534739  335.2 ns/op
+	// BenchmarkTrustKeys/text_trust  4221590  274.9 ns/op
+
+	fields := []Field{
+		String("component", "db"),
+		Int("status_code", 200),
+		String("request_id", "abc"),
+		Bool("cache_hit", true),
+	}
+	for _, trust := range []bool{false, true} {
+		suffix := ""
+		if trust {
+			suffix = " trust"
+		}
+		b.Run("json"+suffix, func(b *testing.B) {
+			buf := bytes.NewBuffer(nil)
+			for b.Loop() {
+				buf.Reset()
+				enc := NewJSONEncoder(buf)
+				enc.SetTrustKeys(trust)
+				enc.AppendEncoderBegin()
+				EncodeFields(enc, fields)
+				enc.AppendEncoderEnd()
+			}
+		})
+		b.Run("text"+suffix, func(b *testing.B) {
+			buf := bytes.NewBuffer(nil)
+			for b.Loop() {
+				buf.Reset()
+				enc := NewTextEncoder(buf, "||")
+				enc.SetTrustKeys(trust)
+				EncodeFields(enc, fields)
+			}
+		})
+	}
+}
+
+func BenchmarkTextEncoder(b *testing.B) {
+
+	// The depth bookkeeping of nested fields costs about the same as the
+	// JSONEncoder itself, so there is no fast path for top-level objects.
+	//
+	// BenchmarkTextEncoder/text_flat    1797132  127.8 ns/op
+	// BenchmarkTextEncoder/json_flat    1637931  149.4 ns/op
+	// BenchmarkTextEncoder/text_nested  1000000  216.3 ns/op
+	// BenchmarkTextEncoder/json_nested  1000000  204.3 ns/op
+	// BenchmarkTextEncoder/text_deep    1000000  224.0 ns/op
+	// BenchmarkTextEncoder/json_deep    1000000  218.9 ns/op
+
+	flat := []Field{String("a", "x"), Int("b", 1), Bool("c", true)}
+	nested := []Field{Object("a", String("x", "y"), Int("z", 1)), Int("b", 1)}
+	deep := []Field{Array("a", objectArray{{Strings("x", []string{"1", "2"})}}), Int("b", 1)}
+
+	for _, c := range []struct {
+		name   string
+		fields []Field
+	}{
+		{"flat", flat},
+		{"nested", nested},
+		{"deep", deep},
+	} {
+		b.Run("text "+c.name, func(b *testing.B) {
+			buf := bytes.NewBuffer(nil)
+			for b.Loop() {
+				buf.Reset()
+				enc := NewTextEncoder(buf, "||")
+				EncodeFields(enc, c.fields)
+			}
+		})
+		b.Run("json "+c.name, func(b *testing.B) {
+			buf := bytes.NewBuffer(nil)
+			for b.Loop() {
+				buf.Reset()
+				enc := NewJSONEncoder(buf)
+				enc.AppendEncoderBegin()
+				EncodeFields(enc, c.fields)
+				enc.AppendEncoderEnd()
+			}
+		})
+	}
+}
+
+func TestTextEncoderKeyless(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	enc := NewTextEncoder(buf, "||")
+	enc.AppendEncoderBegin()
+	enc.AppendString("bare")
+	String("a", "b").Encode(enc)
+	enc.AppendInt64(1)
+	enc.AppendObjectBegin()
+	enc.AppendKey("x")
+	enc.AppendBool(true)
+	enc.AppendObjectEnd()
+	enc.AppendKey("c")
+	enc.AppendArrayBegin()
+	enc.AppendFloat64(0.5)
+	enc.AppendArrayEnd()
+	enc.AppendReflect(nil)
+	enc.AppendEncoderEnd()
+	const expect = `!BADKEY=bare||a=b||!BADKEY=1||!BADKEY={"x":true}||c=[0.5]||!BADKEY=null`
+	assert.String(t, buf.String()).Equal(expect)
+}
+
+func TestDurationBucketed(t *testing.T) {
+	buckets := []time.Duration{100 * time.Millisecond, time.Second, 5 * time.Second}
+
+	tests := []struct {
+		d     time.Duration
+		label string
+	}{
+		{0, "<100ms"},
+		{99 * time.Millisecond, "<100ms"},
+		{100 * time.Millisecond, "100ms-1s"},
+		{999 * time.Millisecond, "100ms-1s"},
+		{time.Second, "1s-5s"},
+		{5 * time.Second, ">=5s"},
+		{time.Minute, ">=5s"},
+	}
+	for _, tt := range tests {
+		fields := DurationBucketed("latency", tt.d, buckets)
+		assert.Number(t, len(fields)).Equal(2)
+		assert.String(t, fields[0].Key).Equal("latency")
+		assert.String(t, fields[0].stringValue()).Equal(tt.d.String())
+		assert.String(t, fields[1].Key).Equal("latency_bucket")
+		assert.String(t, fields[1].stringValue()).Equal(tt.label)
+	}
+
+	fields := DurationBucketed("latency", time.Second, nil)
+	assert.Number(t, len(fields)).Equal(1)
+
+	buf := bytes.NewBuffer(nil)
+	enc := NewJSONEncoder(buf)
+	enc.AppendEncoderBegin()
+	EncodeFields(enc, DurationBucketed("latency", 150*time.Millisecond, buckets))
+	enc.AppendEncoderEnd()
+	assert.String(t, buf.String()).Equal(`{"latency":"150ms","latency_bucket":"100ms-1s"}`)
+}
+
+func TestPrettyJSONEncoder(t *testing.T) {
+	fields := []Field{
+		String("a", "b"),
+		Object("obj", Int("x", 1), Strings("list", []string{"c", "d"}), Object("empty")),
+		Array("objs", objectArray{{Bool("ok", true)}, {}}),
+		Strings("none", nil),
+	}
+
+	t.Run("indented", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewPrettyJSONEncoder(buf, "  ")
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		const expect = `{
+  "a": "b",
+  "obj": {
+    "x": 1,
+    "list": [
+      "c",
+      "d"
+    ],
+    "empty": {}
+  },
+  "objs": [
+    {
+      "ok": true
+    },
+    {}
+  ],
+  "none": []
+}`
+		assert.String(t, buf.String()).Equal(expect)
+		assert.That(t, json.Valid(buf.Bytes())).True()
+	})
+
+	t.Run("empty as null", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewPrettyJSONEncoder(buf, "\t")
+		enc.SetEmptyAsNull(true)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, []Field{Object("o", Strings("s", nil)), Strings("t", []string{"u"})})
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal("{\n\t\"o\": {\n\t\t\"s\": null\n\t},\n\t\"t\": [\n\t\t\"u\"\n\t]\n}")
+	})
+
+	t.Run("empty encoder", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewPrettyJSONEncoder(buf, "  ")
+		enc.AppendEncoderBegin()
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{}`)
+	})
+
+	t.Run("layout", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		e := &Event{Level: InfoLevel, File: "file.go", Line: 100, Tag: "_def", Fields: []Field{Msg("hello")}}
+		(&JSONLayout{Pretty: true}).EncodeTo(e, buf)
+		const expect = "{\n" +
+			`  "level": "info",` + "\n" +
+			`  "time": "0001-01-01T00:00:00.000",` + "\n" +
+			`  "fileLine": "file.go:100",` + "\n" +
+			`  "tag": "_def",` + "\n" +
+			`  "msg": "hello"` + "\n" +
+			"}\n"
+		assert.String(t, buf.String()).Equal(expect)
+	})
+}
+
+type testUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func testUserFields(u testUser) []Field {
+	return []Field{String("name", u.Name), Int("age", u.Age)}
+}
+
+func TestObjects(t *testing.T) {
+	users := []testUser{{"alice", 30}, {"bob", 25}}
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		Objects("users", users, testUserFields).Encode(enc)
+		Objects("empty", []testUser(nil), testUserFields).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"users":[{"name":"alice","age":30},{"name":"bob","age":25}],"empty":[]}`)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.AppendEncoderBegin()
+		Objects("users", users, testUserFields).Encode(enc)
+		Int("n", 2).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`users=[{"name":"alice","age":30},{"name":"bob","age":25}]||n=2`)
+	})
+}
+
+func BenchmarkObjects(b *testing.B) {
+
+	// Objects is on par with Any: the time goes into the per-byte string
+	// escaping of the encoder, and the extra allocations are the field
+	// slices returned by the extractor.
+	//
+	// BenchmarkObjects/objects  450996  2479 ns/op  1056 B/op  12 allocs/op
+	// BenchmarkObjects/any      468950  2153 ns/op   400 B/op   4 allocs/op
+
+	users := make([]testUser, 10)
+	for i := range users {
+		users[i] = testUser{Name: fmt.Sprintf("user%d", i), Age: 20 + i}
+	}
+
+	b.Run("objects", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := bytes.NewBuffer(nil)
+		for b.Loop() {
+			buf.Reset()
+			enc := NewJSONEncoder(buf)
+			enc.AppendEncoderBegin()
+			Objects("users", users, testUserFields).Encode(enc)
+			enc.AppendEncoderEnd()
+		}
+	})
+	b.Run("any", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := bytes.NewBuffer(nil)
+		for b.Loop() {
+			buf.Reset()
+			enc := NewJSONEncoder(buf)
+			enc.AppendEncoderBegin()
+			Any("users", users).Encode(enc)
+			enc.AppendEncoderEnd()
+		}
+	})
+}
+
+func TestObjectsMap(t *testing.T) {
+	m := map[string][]Field{
+		"db":    {String("host", "localhost"), Int("port", 5432)},
+		"cache": {Bool("enabled", true)},
+		"queue": nil,
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		ObjectsMap("deps", m).Encode(enc)
+		ObjectsMap("empty", nil).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"deps":{"cache":{"enabled":true},"db":{"host":"localhost","port":5432},"queue":{}},"empty":{}}`)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.AppendEncoderBegin()
+		ObjectsMap("deps", m).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`deps={"cache":{"enabled":true},"db":{"host":"localhost","port":5432},"queue":{}}`)
+	})
+}
+
+func TestMap(t *testing.T) {
+	m := map[string]any{
+		"user":  "bob",
+		"id":    42,
+		"roles": []string{"admin", "dev"},
+		"addr":  map[string]any{"city": "paris"},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		Map("req", m).Encode(enc)
+		Map("empty", nil).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"req":{"addr":{"city":"paris"},"id":42,"roles":["admin","dev"],"user":"bob"},"empty":{}}`)
+
+		// FieldsFromMap expands the entries into top-level fields
+		buf.Reset()
+		enc = NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		FieldsFromMap(m).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"addr":{"city":"paris"},"id":42,"roles":["admin","dev"],"user":"bob"}`)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.AppendEncoderBegin()
+		Map("req", m).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`req={"addr":{"city":"paris"},"id":42,"roles":["admin","dev"],"user":"bob"}`)
+
+		buf.Reset()
+		enc = NewTextEncoder(buf, "||")
+		enc.AppendEncoderBegin()
+		FieldsFromMap(m).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`addr={"city":"paris"}||id=42||roles=["admin","dev"]||user=bob`)
+	})
+}
+
+func TestPreEncoded(t *testing.T) {
+	raw := []byte(`{"id":1,"tags":["a","b"]}`)
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		String("a", "x").Encode(enc)
+		PreEncoded("user", raw).Encode(enc)
+		Object("nested", PreEncoded("n", []byte("42")), Int("m", 1)).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"a":"x","user":{"id":1,"tags":["a","b"]},"nested":{"n":42,"m":1}}`)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		enc.AppendEncoderBegin()
+		PreEncoded("user", raw).Encode(enc)
+		String("a", "x").Encode(enc)
+		Object("nested", PreEncoded("n", []byte("42"))).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`user={"id":1,"tags":["a","b"]}||a=x||nested={"n":42}`)
+	})
+
+	t.Run("not escaped", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		PreEncoded("v", []byte(`"a\"b"`)).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"v":"a\"b"}`)
+	})
+}
+
+func TestNumber(t *testing.T) {
+	var v struct {
+		ID    json.Number `json:"id"`
+		Price json.Number `json:"price"`
+	}
+	dec := json.NewDecoder(strings.NewReader(`{"id":12345678901234567890,"price":-1.50e3}`))
+	dec.UseNumber()
+	err := dec.Decode(&v)
+	assert.Error(t, err).Nil()
+
+	fields := []Field{
+		Number("id", v.ID),
+		Any("price", v.Price),
+		Number("bad", "1 OR 1=1"),
+		Number("space", "1 "),
+		Any("empty", json.Number("")),
+	}
+
+	buf := bytes.NewBuffer(nil)
+	enc := NewJSONEncoder(buf)
+	enc.AppendEncoderBegin()
+	EncodeFields(enc, fields)
+	enc.AppendEncoderEnd()
+	assert.String(t, buf.String()).Equal(`{"id":12345678901234567890,"price":-1.50e3,"bad":"1 OR 1=1","space":"1 ","empty":""}`)
+
+	buf.Reset()
+	txt := NewTextEncoder(buf, "||")
+	txt.AppendEncoderBegin()
+	EncodeFields(txt, fields[:2])
+	txt.AppendEncoderEnd()
+	assert.String(t, buf.String()).Equal(`id=12345678901234567890||price=-1.50e3`)
+
+	// reflection reports an error instead of an invalid number
+	buf.Reset()
+	enc = NewJSONEncoder(buf)
+	enc.AppendEncoderBegin()
+	Reflect("bad", json.Number("1 OR 1=1")).Encode(enc)
+	enc.AppendEncoderEnd()
+	assert.String(t, buf.String()).Matches(`^{"bad":"json: error calling .*"}$`)
+}
+
+func TestFieldIsEmpty(t *testing.T) {
+	var (
+		nilPtr *int
+		nilMap map[string]int
+		zero   int
+	)
+	for _, f := range []Field{
+		Bool("a", false),
+		Int("a", 0),
+		Uint("a", uint(0)),
+		Float("a", 0.0),
+		Float("a", math.Copysign(0, -1)),
+		String("a", ""),
+		Nil("a"),
+		Reflect("a", nilPtr),
+		Reflect("a", nilMap),
+		IntPtr("a", nilPtr),
+		IntPtr("a", &zero), // dereferenced by IntPtr
+		Ints("a", []int{}),
+		Strings("a", nil),
+		Objects("a", []int{}, func(int) []Field { return nil }),
+		Object("a"),
+		FieldsFromMap(map[string]any{}),
+		Map("a", nil),
+		PreEncoded("a", nil),
+	} {
+		assert.That(t, f.IsEmpty()).True()
+	}
+	for _, f := range []Field{
+		Bool("a", true),
+		Int("a", -1),
+		Uint("a", uint(1)),
+		Float("a", 0.1),
+		String("a", " "),
+		Reflect("a", struct{}{}),
+		Reflect("a", &zero),
+		Time("a", time.Time{}),
+		Ints("a", []int{0}),
+		Objects("a", []int{0}, func(int) []Field { return nil }),
+		Object("a", Int("b", 0)),
+		FieldsFromMap(map[string]any{"b": 0}),
+		Map("a", map[string]any{"b": 0}),
+		PreEncoded("a", []byte("0")),
+	} {
+		assert.That(t, f.IsEmpty()).False()
+	}
+}
+
+func TestBytes(t *testing.T) {
+	fields := []Field{
+		Bytes("size", 1536*1024),
+		Bytes("empty", 0),
+		Object("nested", Bytes("n", 2048)),
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		EncodeFields(enc, fields)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"size":1572864,"empty":0,"nested":{"n":2048}}`)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		EncodeFields(enc, fields)
+		assert.String(t, buf.String()).Equal(`size=1.5MB||empty=0B||nested={"n":2048}`)
+	})
+
+	assert.That(t, Bytes("size", 0).IsEmpty()).True()
+	assert.That(t, Bytes("size", 1).IsEmpty()).False()
+}
+
+func TestFrames(t *testing.T) {
+	frames := []runtime.Frame{
+		{Function: "main.run", File: "/app/main.go", Line: 42},
+		{Function: "main.main", File: "/app/main.go", Line: 10},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		Frames("stack", frames).Encode(enc)
+		Frames("empty", nil).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Equal(`{"stack":[{"func":"main.run","file":"/app/main.go","line":42},` +
+			`{"func":"main.main","file":"/app/main.go","line":10}],"empty":[]}`)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		enc := NewTextEncoder(buf, "||")
+		Msg("failed").Encode(enc)
+		Frames("stack", frames[:1]).Encode(enc)
+		assert.String(t, buf.String()).Equal(`msg=failed||stack=[{"func":"main.run","file":"/app/main.go","line":42}]`)
+	})
+
+	t.Run("captured", func(t *testing.T) {
+		pcs := make([]uintptr, 1)
+		n := runtime.Callers(1, pcs)
+		f, _ := runtime.CallersFrames(pcs[:n]).Next()
+
+		buf := bytes.NewBuffer(nil)
+		enc := NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		Frames("stack", []runtime.Frame{f}).Encode(enc)
+		enc.AppendEncoderEnd()
+		assert.String(t, buf.String()).Matches(`^\{"stack":\[\{"func":"github.com/go-spring/log.TestFrames.func3","file":".*/field_test.go","line":\d+}]}$`)
+	})
+}
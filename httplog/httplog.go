@@ -0,0 +1,65 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package httplog builds standardized log fields for HTTP access logs.
+// It is a separate package so that the core package does not depend on
+// net/http.
+//
+//	start := time.Now()
+//	next.ServeHTTP(rw, r)
+//	log.Info(ctx, TagAccess, append(httplog.HTTPRequest(r),
+//		httplog.HTTPResponse(rw.status, rw.size, time.Since(start))...)...)
+package httplog
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-spring/log"
+)
+
+// Keys of the fields built by HTTPRequest and HTTPResponse.
+const (
+	MethodKey  = "method"
+	PathKey    = "path"
+	RemoteKey  = "remote"
+	StatusKey  = "status"
+	SizeKey    = "size"
+	LatencyKey = "latency"
+)
+
+// HTTPRequest returns the method, path and remote address of the request.
+func HTTPRequest(r *http.Request) []log.Field {
+	var path string
+	if r.URL != nil {
+		path = r.URL.Path
+	}
+	return []log.Field{
+		log.String(MethodKey, r.Method),
+		log.String(PathKey, path),
+		log.String(RemoteKey, r.RemoteAddr),
+	}
+}
+
+// HTTPResponse returns the status code, body size in bytes and latency
+// of the response. The latency is encoded like log.Duration, e.g. "1.5ms".
+func HTTPResponse(status int, size int64, dur time.Duration) []log.Field {
+	return []log.Field{
+		log.Int(StatusKey, status),
+		log.Int(SizeKey, size),
+		log.Duration(LatencyKey, dur),
+	}
+}
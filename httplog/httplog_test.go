@@ -0,0 +1,67 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httplog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spring/log"
+	"github.com/go-spring/stdlib/testing/assert"
+)
+
+func TestHTTPRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/users?id=1", nil)
+	r.RemoteAddr = "10.0.0.1:5678"
+	assert.That(t, HTTPRequest(r)).Equal([]log.Field{
+		log.String("method", "POST"),
+		log.String("path", "/api/users"),
+		log.String("remote", "10.0.0.1:5678"),
+	})
+
+	r = &http.Request{Method: http.MethodGet}
+	assert.That(t, HTTPRequest(r)).Equal([]log.Field{
+		log.String("method", "GET"),
+		log.String("path", ""),
+		log.String("remote", ""),
+	})
+}
+
+func TestHTTPResponse(t *testing.T) {
+	fields := HTTPResponse(http.StatusNotFound, 128, 1500*time.Microsecond)
+	assert.That(t, fields).Equal([]log.Field{
+		log.Int("status", 404),
+		log.Int("size", int64(128)),
+		log.Duration("latency", 1500*time.Microsecond),
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/health", nil)
+	e := &log.Event{
+		Level:  log.InfoLevel,
+		File:   "file.go",
+		Line:   100,
+		Tag:    "_access",
+		Fields: append(HTTPRequest(r), fields...),
+	}
+	buf := bytes.NewBuffer(nil)
+	(&log.JSONLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_access",` +
+		`"method":"GET","path":"/health","remote":"192.0.2.1:1234","status":404,"size":128,"latency":"1.5ms"}` + "\n")
+}
@@ -17,10 +17,17 @@
 package log
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/go-spring/stdlib/errutil"
 )
 
 var (
@@ -37,7 +44,7 @@ var (
 			AppenderBase: AppenderBase{
 				Layout: &TextLayout{
 					BaseLayout: BaseLayout{
-						FileLineMaxLength: 48,
+						FileLineMaxLength: UseDefaultFileLineLength,
 					},
 				},
 			},
@@ -50,10 +57,11 @@ var (
 	// TagBizDef is the default tag for business-related logs.
 	TagBizDef = RegisterBizTag("def", "")
 
-	// ReportError is an optional hook function that is invoked whenever an error
-	// occurs during logging. It can be overridden to handle error reporting, such as
-	// logging the error to a separate error log or sending alerts.
-	ReportError = func(err error) {}
+	// ReportError is the hook invoked whenever an error occurs inside the
+	// logging subsystem, e.g. a failed write or rotation. It writes to
+	// os.Stderr by default, and can be overridden to handle error reporting,
+	// such as logging the error to a separate error log or sending alerts.
+	ReportError = func(err error) { _, _ = fmt.Fprintln(os.Stderr, "log:", err) }
 
 	// TimeNow is an optional override function that provides a custom timestamp.
 	// It can be replaced during testing or in special cases where a fixed time
@@ -71,6 +79,20 @@ var (
 	// Avoid performing complex calculations in this function.
 	// It's recommended to use cached results for better performance.
 	FieldsFromContext func(ctx context.Context) []Field
+
+	// ExitOnFatal makes Fatal and Fatalf exit the process with code 1 after
+	// the event is logged and all loggers and appenders are stopped to flush
+	// it, and Panic and Panicf panic with the message after the buffered
+	// events are flushed and the appenders synced, keeping them running.
+	// It is off by default for backward compatibility, but recommended.
+	ExitOnFatal bool
+
+	// DisablePooling makes every event and encoding buffer freshly
+	// allocated instead of reused, so that a custom appender retaining an
+	// event after Append sees a reset event rather than another one, and
+	// such bugs show up under -race. It is meant for debugging only, and
+	// must be set before logging starts.
+	DisablePooling bool
 )
 
 // defaultLogLevel returns the default log level for the default logger.
@@ -89,6 +111,42 @@ func defaultLogLevel() Level {
 	return r.MinLevel
 }
 
+// eventSeq is the sequence number of the last recorded event.
+var eventSeq atomic.Uint64
+
+// internalErrorf reports an error of the logging subsystem via ReportError.
+// The cause may be nil, see errutil.Explain.
+func internalErrorf(cause error, format string, args ...any) {
+	ReportError(errutil.Explain(cause, format, args...))
+}
+
+// SetDefaultLogger replaces the fallback logger used by tags that are not
+// bound to any configured logger, e.g. before Refresh is called. The given
+// logger is started here and the previous default logger is stopped.
+//
+// It must be called during initialization, before Refresh, and is not
+// safe for concurrent use with logging.
+func SetDefaultLogger(l Logger) error {
+	if l == nil {
+		return errutil.Explain(nil, "default logger is nil")
+	}
+
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+
+	if global.loggers != nil {
+		return errutil.Explain(nil, "default logger must be set before refresh")
+	}
+	if err := l.Start(); err != nil {
+		return errutil.Explain(err, "start default logger error")
+	}
+	defaultStarted.Do(func() {}) // the built-in one is no longer used
+	old := defaultLogger
+	defaultLogger = l
+	old.Stop()
+	return nil
+}
+
 // RegisterAppTag registers or retrieves a Tag intended for application-layer logs,
 // which are typically used to log events related to the application lifecycle,
 // such as startup, shutdown, or health checks.
@@ -119,21 +177,61 @@ func getLogger(tag *Tag) Logger {
 	if l := tag.logger.Load().Logger; l != nil {
 		return l
 	}
+	ensureDefaultStarted()
 	return defaultLogger
 }
 
+// defaultStarted guards the start of the built-in default logger.
+var defaultStarted sync.Once
+
+// defaultLayoutStarted guards the start of DefaultLayout.
+var defaultLayoutStarted sync.Once
+
+// ensureDefaultStarted starts the layout of the built-in default logger
+// on first use, since the logger itself is never started. A default logger
+// set by SetDefaultLogger is started there instead. DefaultLayout, used by
+// appenders without a layout, is started here too.
+func ensureDefaultStarted() {
+	defaultLayoutStarted.Do(func() {
+		if err := startLayout(DefaultLayout); err != nil {
+			internalErrorf(err, "start default layout error")
+		}
+	})
+	defaultStarted.Do(func() {
+		if l, ok := defaultLogger.(*ConsoleLogger); ok && l.appender != nil {
+			if err := startLayout(l.appender.Layout); err != nil {
+				internalErrorf(err, "start default logger error")
+			}
+		}
+	})
+}
+
+// Enabled reports whether the given level is enabled for the logger
+// currently bound to the tag. It can be used to skip building expensive
+// fields when the event would be discarded anyway.
+func Enabled(ctx context.Context, tag *Tag, level Level) bool {
+	return getLogger(tag).GetLevel().Enable(level)
+}
+
 // Trace logs a message at TraceLevel using a lazy field generator.
 // The generator function is only invoked if the level is enabled.
 func Trace(ctx context.Context, tag *Tag, fn func() []Field) {
 	if l := getLogger(tag); l.GetLevel().Enable(TraceLevel) {
-		record(ctx, TraceLevel, tag.tag, l, 2, fn()...)
+		record(ctx, TraceLevel, tag, l, 2, fn()...)
 	}
 }
 
 // Tracef logs a formatted message at TraceLevel.
 func Tracef(ctx context.Context, tag *Tag, format string, args ...any) {
 	if l := getLogger(tag); l.GetLevel().Enable(TraceLevel) {
-		record(ctx, TraceLevel, tag.tag, l, 2, Msgf(format, args...))
+		record(ctx, TraceLevel, tag, l, 2, Msgf(format, args...))
+	}
+}
+
+// Tracew logs a message with alternating key-value pairs at TraceLevel.
+func Tracew(ctx context.Context, tag *Tag, msg string, keysAndValues ...any) {
+	if l := getLogger(tag); l.GetLevel().Enable(TraceLevel) {
+		record(ctx, TraceLevel, tag, l, 2, msgWithPairs(msg, keysAndValues)...)
 	}
 }
 
@@ -141,96 +239,248 @@ func Tracef(ctx context.Context, tag *Tag, format string, args ...any) {
 // The generator function is only invoked if the level is enabled.
 func Debug(ctx context.Context, tag *Tag, fn func() []Field) {
 	if l := getLogger(tag); l.GetLevel().Enable(DebugLevel) {
-		record(ctx, DebugLevel, tag.tag, l, 2, fn()...)
+		record(ctx, DebugLevel, tag, l, 2, fn()...)
 	}
 }
 
 // Debugf logs a formatted message at DebugLevel.
 func Debugf(ctx context.Context, tag *Tag, format string, args ...any) {
 	if l := getLogger(tag); l.GetLevel().Enable(DebugLevel) {
-		record(ctx, DebugLevel, tag.tag, l, 2, Msgf(format, args...))
+		record(ctx, DebugLevel, tag, l, 2, Msgf(format, args...))
+	}
+}
+
+// Debugw logs a message with alternating key-value pairs at DebugLevel.
+func Debugw(ctx context.Context, tag *Tag, msg string, keysAndValues ...any) {
+	if l := getLogger(tag); l.GetLevel().Enable(DebugLevel) {
+		record(ctx, DebugLevel, tag, l, 2, msgWithPairs(msg, keysAndValues)...)
 	}
 }
 
 // Info logs structured fields at InfoLevel.
 func Info(ctx context.Context, tag *Tag, fields ...Field) {
 	if l := getLogger(tag); l.GetLevel().Enable(InfoLevel) {
-		record(ctx, InfoLevel, tag.tag, l, 2, fields...)
+		record(ctx, InfoLevel, tag, l, 2, fields...)
 	}
 }
 
 // Infof logs a formatted message at InfoLevel.
 func Infof(ctx context.Context, tag *Tag, format string, args ...any) {
 	if l := getLogger(tag); l.GetLevel().Enable(InfoLevel) {
-		record(ctx, InfoLevel, tag.tag, l, 2, Msgf(format, args...))
+		record(ctx, InfoLevel, tag, l, 2, Msgf(format, args...))
+	}
+}
+
+// Infow logs a message with alternating key-value pairs at InfoLevel.
+func Infow(ctx context.Context, tag *Tag, msg string, keysAndValues ...any) {
+	if l := getLogger(tag); l.GetLevel().Enable(InfoLevel) {
+		record(ctx, InfoLevel, tag, l, 2, msgWithPairs(msg, keysAndValues)...)
 	}
 }
 
 // Warn logs structured fields at WarnLevel.
 func Warn(ctx context.Context, tag *Tag, fields ...Field) {
 	if l := getLogger(tag); l.GetLevel().Enable(WarnLevel) {
-		record(ctx, WarnLevel, tag.tag, l, 2, fields...)
+		record(ctx, WarnLevel, tag, l, 2, fields...)
 	}
 }
 
 // Warnf logs a formatted message at WarnLevel.
 func Warnf(ctx context.Context, tag *Tag, format string, args ...any) {
 	if l := getLogger(tag); l.GetLevel().Enable(WarnLevel) {
-		record(ctx, WarnLevel, tag.tag, l, 2, Msgf(format, args...))
+		record(ctx, WarnLevel, tag, l, 2, Msgf(format, args...))
+	}
+}
+
+// Warnw logs a message with alternating key-value pairs at WarnLevel.
+func Warnw(ctx context.Context, tag *Tag, msg string, keysAndValues ...any) {
+	if l := getLogger(tag); l.GetLevel().Enable(WarnLevel) {
+		record(ctx, WarnLevel, tag, l, 2, msgWithPairs(msg, keysAndValues)...)
 	}
 }
 
 // Error logs structured fields at ErrorLevel.
 func Error(ctx context.Context, tag *Tag, fields ...Field) {
 	if l := getLogger(tag); l.GetLevel().Enable(ErrorLevel) {
-		record(ctx, ErrorLevel, tag.tag, l, 2, fields...)
+		record(ctx, ErrorLevel, tag, l, 2, fields...)
 	}
 }
 
 // Errorf logs a formatted message at ErrorLevel.
 func Errorf(ctx context.Context, tag *Tag, format string, args ...any) {
 	if l := getLogger(tag); l.GetLevel().Enable(ErrorLevel) {
-		record(ctx, ErrorLevel, tag.tag, l, 2, Msgf(format, args...))
+		record(ctx, ErrorLevel, tag, l, 2, Msgf(format, args...))
+	}
+}
+
+// Errorw logs a message with alternating key-value pairs at ErrorLevel.
+func Errorw(ctx context.Context, tag *Tag, msg string, keysAndValues ...any) {
+	if l := getLogger(tag); l.GetLevel().Enable(ErrorLevel) {
+		record(ctx, ErrorLevel, tag, l, 2, msgWithPairs(msg, keysAndValues)...)
 	}
 }
 
 // Panic logs structured fields at PanicLevel.
 func Panic(ctx context.Context, tag *Tag, fields ...Field) {
 	if l := getLogger(tag); l.GetLevel().Enable(PanicLevel) {
-		record(ctx, PanicLevel, tag.tag, l, 2, fields...)
+		record(ctx, PanicLevel, tag, l, 2, fields...)
+	}
+	if ExitOnFatal {
+		panicAfterFlush(fieldsText(fields))
 	}
 }
 
 // Panicf logs a formatted message at PanicLevel.
 func Panicf(ctx context.Context, tag *Tag, format string, args ...any) {
 	if l := getLogger(tag); l.GetLevel().Enable(PanicLevel) {
-		record(ctx, PanicLevel, tag.tag, l, 2, Msgf(format, args...))
+		record(ctx, PanicLevel, tag, l, 2, Msgf(format, args...))
+	}
+	if ExitOnFatal {
+		panicAfterFlush(fmt.Sprintf(format, args...))
+	}
+}
+
+// Panicw logs a message with alternating key-value pairs at PanicLevel.
+func Panicw(ctx context.Context, tag *Tag, msg string, keysAndValues ...any) {
+	if l := getLogger(tag); l.GetLevel().Enable(PanicLevel) {
+		record(ctx, PanicLevel, tag, l, 2, msgWithPairs(msg, keysAndValues)...)
+	}
+	if ExitOnFatal {
+		panicAfterFlush(msg)
 	}
 }
 
 // Fatal logs structured fields at FatalLevel.
 func Fatal(ctx context.Context, tag *Tag, fields ...Field) {
 	if l := getLogger(tag); l.GetLevel().Enable(FatalLevel) {
-		record(ctx, FatalLevel, tag.tag, l, 2, fields...)
+		record(ctx, FatalLevel, tag, l, 2, fields...)
 	}
+	exitOnFatal()
 }
 
 // Fatalf logs a formatted message at FatalLevel.
 func Fatalf(ctx context.Context, tag *Tag, format string, args ...any) {
 	if l := getLogger(tag); l.GetLevel().Enable(FatalLevel) {
-		record(ctx, FatalLevel, tag.tag, l, 2, Msgf(format, args...))
+		record(ctx, FatalLevel, tag, l, 2, Msgf(format, args...))
+	}
+	exitOnFatal()
+}
+
+// Fatalw logs a message with alternating key-value pairs at FatalLevel.
+func Fatalw(ctx context.Context, tag *Tag, msg string, keysAndValues ...any) {
+	if l := getLogger(tag); l.GetLevel().Enable(FatalLevel) {
+		record(ctx, FatalLevel, tag, l, 2, msgWithPairs(msg, keysAndValues)...)
+	}
+	exitOnFatal()
+}
+
+// exitOnFatal exits the process with code 1 if ExitOnFatal is set.
+// All loggers and appenders are stopped first, so that the events
+// buffered by async loggers, including the fatal one, are flushed.
+func exitOnFatal() {
+	if ExitOnFatal {
+		Destroy()
+		os.Exit(1)
+	}
+}
+
+// panicAfterFlush panics with v once the buffered events, including the
+// panic one, are flushed. Unlike exitOnFatal, nothing is stopped, so that
+// logging goes on as configured if the panic is recovered.
+func panicAfterFlush(v any) {
+	flushLoggers()
+	panic(v)
+}
+
+// fieldsText encodes the fields in text format, e.g. for a panic message.
+func fieldsText(fields []Field) string {
+	var buf bytes.Buffer
+	enc := NewTextEncoder(&buf, "||")
+	enc.AppendEncoderBegin()
+	EncodeFields(enc, fields)
+	enc.AppendEncoderEnd()
+	return buf.String()
+}
+
+// msgWithPairs returns the message field followed by the fields converted
+// from the key-value pairs by FieldsFromPairs, which reports a dangling key
+// with the BadKey key instead of panicking.
+func msgWithPairs(msg string, pairs []any) []Field {
+	fields := make([]Field, 0, 1+(len(pairs)+1)/2)
+	fields = append(fields, Msg(msg))
+	return append(fields, FieldsFromPairs(pairs...)...)
+}
+
+// Log logs structured fields at the given level, e.g. a level computed
+// from a value at the call site. Unlike Panic and Fatal, it neither
+// panics nor exits, whatever the level.
+func Log(ctx context.Context, tag *Tag, level Level, fields ...Field) {
+	if l := getLogger(tag); l.GetLevel().Enable(level) {
+		record(ctx, level, tag, l, 2, fields...)
+	}
+}
+
+// Logf logs a formatted message at the given level, see Log.
+func Logf(ctx context.Context, tag *Tag, level Level, format string, args ...any) {
+	if l := getLogger(tag); l.GetLevel().Enable(level) {
+		record(ctx, level, tag, l, 2, Msgf(format, args...))
 	}
 }
 
 // Record logs a message at the given level for the given tag.
 func Record(ctx context.Context, level Level, tag *Tag, skip int, fields ...Field) {
 	if l := getLogger(tag); l.GetLevel().Enable(level) {
-		record(ctx, level, tag.tag, l, skip, fields...)
+		record(ctx, level, tag, l, skip, fields...)
+	}
+}
+
+// PublishEvent routes a fully-formed event to the logger, e.g. to replay
+// events read back from an archive. Unlike Record, the time, the caller,
+// the goroutine ID and the context string and fields are taken from the
+// event as is, so a zero Time stays zero. Only the sequence number and
+// the logger name are assigned. The event is copied, so the caller keeps
+// ownership of it and of its fields. It is dropped if the logger doesn't
+// enable its level or logging is suppressed.
+func PublishEvent(logger Logger, e *Event) {
+	if suppressed.Load() > 0 || !logger.GetLevel().Enable(e.Level) {
+		return
+	}
+	x := getEvent()
+	buf := x.fieldsBuf
+	*x = *e
+	x.Fields = append(buf[:0], e.Fields...)
+	x.fieldsBuf = x.Fields
+	x.CtxFields = slices.Clone(e.CtxFields)
+	x.RawBytes = bytes.Clone(e.RawBytes)
+	x.flushed = nil
+	x.rendered = false
+	x.Seq = eventSeq.Add(1)
+	x.Logger = logger.GetName()
+	logger.Append(x)
+}
+
+// suppressed counts the active Suppress scopes. Logging is muted
+// as long as it is greater than zero.
+var suppressed atomic.Int32
+
+// Suppress mutes all logging until the returned restore function is called,
+// e.g. during noisy test setup. Nested calls compose: logging resumes only
+// after every scope has been restored. Calling restore more than once has
+// no further effect.
+func Suppress() (restore func()) {
+	suppressed.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { suppressed.Add(-1) })
 	}
 }
 
 // record performs the actual logging logic after level checking.
-func record(ctx context.Context, level Level, tag string, logger Logger, skip int, fields ...Field) {
+func record(ctx context.Context, level Level, tag *Tag, logger Logger, skip int, fields ...Field) {
+	if suppressed.Load() > 0 {
+		return
+	}
+
 	var (
 		file string
 		line int
@@ -249,24 +499,33 @@ func record(ctx context.Context, level Level, tag string, logger Logger, skip in
 		now = TimeNow(ctx)
 	}
 
-	var ctxString string
-	if StringFromContext != nil {
-		ctxString = StringFromContext(ctx)
-	}
-
-	var ctxFields []Field
-	if FieldsFromContext != nil {
-		ctxFields = FieldsFromContext(ctx)
-	}
-
 	e := getEvent()
+	e.Seq = eventSeq.Add(1)
 	e.Level = level
 	e.Time = now
 	e.File = file
 	e.Line = line
-	e.Tag = tag
-	e.Fields = fields
-	e.CtxString = ctxString
-	e.CtxFields = ctxFields
+	e.Tag = tag.tag
+	e.Logger = logger.GetName()
+	if captureGoroutineID.Load() {
+		e.GID = goroutineID()
+	}
+	// The fields are copied into the buffer owned by the event, after the
+	// defaults of the tag, so the variadic slice of the caller doesn't
+	// escape to the heap.
+	e.Fields = e.fieldsBuf[:0]
+	if defaults := tag.defaults.Load(); defaults != nil {
+		e.Fields = append(e.Fields, *defaults...)
+	}
+	e.Fields = append(e.Fields, fields...)
+	e.fieldsBuf = e.Fields
+	// Pooled events have no context values, so they are only
+	// touched when the hooks are configured.
+	if StringFromContext != nil {
+		e.CtxString = StringFromContext(ctx)
+	}
+	if FieldsFromContext != nil {
+		e.CtxFields = FieldsFromContext(ctx)
+	}
 	logger.Append(e)
 }
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"context"
+	"fmt"
+)
+
+// BoundLogger is a facade bound to a Tag and a set of fields, like
+// zap's With. The bound fields are added to every event it emits, after
+// the context fields and before the fields of the call.
+type BoundLogger struct {
+	tag    *Tag
+	fields []Field
+}
+
+// With returns a BoundLogger that logs with the tag and the given fields.
+func With(tag *Tag, fields ...Field) BoundLogger {
+	return BoundLogger{tag: tag, fields: fields}
+}
+
+// With returns a new BoundLogger with the given fields bound after
+// the fields already bound to l.
+func (l BoundLogger) With(fields ...Field) BoundLogger {
+	return BoundLogger{tag: l.tag, fields: l.withFields(fields)}
+}
+
+// Tag returns the tag the BoundLogger is bound to.
+func (l BoundLogger) Tag() *Tag {
+	return l.tag
+}
+
+// Fields returns the fields bound to the BoundLogger.
+func (l BoundLogger) Fields() []Field {
+	return l.fields
+}
+
+// withFields returns the bound fields followed by the given ones.
+// Unless no fields are bound, it allocates, so that loggers sharing
+// the bound fields never write to each other's slices.
+func (l BoundLogger) withFields(fields []Field) []Field {
+	if len(l.fields) == 0 {
+		return fields
+	}
+	s := make([]Field, 0, len(l.fields)+len(fields))
+	s = append(s, l.fields...)
+	return append(s, fields...)
+}
+
+// Enabled reports whether the given level is enabled for the tag.
+func (l BoundLogger) Enabled(ctx context.Context, level Level) bool {
+	return getLogger(l.tag).GetLevel().Enable(level)
+}
+
+// Trace logs a message at TraceLevel using a lazy field generator.
+// The generator function is only invoked if the level is enabled.
+func (l BoundLogger) Trace(ctx context.Context, fn func() []Field) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(TraceLevel) {
+		record(ctx, TraceLevel, l.tag, x, 2, l.withFields(fn())...)
+	}
+}
+
+// Tracef logs a formatted message at TraceLevel.
+func (l BoundLogger) Tracef(ctx context.Context, format string, args ...any) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(TraceLevel) {
+		record(ctx, TraceLevel, l.tag, x, 2, l.withFields([]Field{Msgf(format, args...)})...)
+	}
+}
+
+// Debug logs a message at DebugLevel using a lazy field generator.
+// The generator function is only invoked if the level is enabled.
+func (l BoundLogger) Debug(ctx context.Context, fn func() []Field) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(DebugLevel) {
+		record(ctx, DebugLevel, l.tag, x, 2, l.withFields(fn())...)
+	}
+}
+
+// Debugf logs a formatted message at DebugLevel.
+func (l BoundLogger) Debugf(ctx context.Context, format string, args ...any) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(DebugLevel) {
+		record(ctx, DebugLevel, l.tag, x, 2, l.withFields([]Field{Msgf(format, args...)})...)
+	}
+}
+
+// Info logs structured fields at InfoLevel.
+func (l BoundLogger) Info(ctx context.Context, fields ...Field) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(InfoLevel) {
+		record(ctx, InfoLevel, l.tag, x, 2, l.withFields(fields)...)
+	}
+}
+
+// Infof logs a formatted message at InfoLevel.
+func (l BoundLogger) Infof(ctx context.Context, format string, args ...any) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(InfoLevel) {
+		record(ctx, InfoLevel, l.tag, x, 2, l.withFields([]Field{Msgf(format, args...)})...)
+	}
+}
+
+// Warn logs structured fields at WarnLevel.
+func (l BoundLogger) Warn(ctx context.Context, fields ...Field) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(WarnLevel) {
+		record(ctx, WarnLevel, l.tag, x, 2, l.withFields(fields)...)
+	}
+}
+
+// Warnf logs a formatted message at WarnLevel.
+func (l BoundLogger) Warnf(ctx context.Context, format string, args ...any) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(WarnLevel) {
+		record(ctx, WarnLevel, l.tag, x, 2, l.withFields([]Field{Msgf(format, args...)})...)
+	}
+}
+
+// Error logs structured fields at ErrorLevel.
+func (l BoundLogger) Error(ctx context.Context, fields ...Field) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(ErrorLevel) {
+		record(ctx, ErrorLevel, l.tag, x, 2, l.withFields(fields)...)
+	}
+}
+
+// Errorf logs a formatted message at ErrorLevel.
+func (l BoundLogger) Errorf(ctx context.Context, format string, args ...any) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(ErrorLevel) {
+		record(ctx, ErrorLevel, l.tag, x, 2, l.withFields([]Field{Msgf(format, args...)})...)
+	}
+}
+
+// Panic logs structured fields at PanicLevel.
+func (l BoundLogger) Panic(ctx context.Context, fields ...Field) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(PanicLevel) {
+		record(ctx, PanicLevel, l.tag, x, 2, l.withFields(fields)...)
+	}
+	if ExitOnFatal {
+		panicAfterFlush(fieldsText(fields))
+	}
+}
+
+// Panicf logs a formatted message at PanicLevel.
+func (l BoundLogger) Panicf(ctx context.Context, format string, args ...any) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(PanicLevel) {
+		record(ctx, PanicLevel, l.tag, x, 2, l.withFields([]Field{Msgf(format, args...)})...)
+	}
+	if ExitOnFatal {
+		panicAfterFlush(fmt.Sprintf(format, args...))
+	}
+}
+
+// Fatal logs structured fields at FatalLevel.
+func (l BoundLogger) Fatal(ctx context.Context, fields ...Field) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(FatalLevel) {
+		record(ctx, FatalLevel, l.tag, x, 2, l.withFields(fields)...)
+	}
+	exitOnFatal()
+}
+
+// Fatalf logs a formatted message at FatalLevel.
+func (l BoundLogger) Fatalf(ctx context.Context, format string, args ...any) {
+	if x := getLogger(l.tag); x.GetLevel().Enable(FatalLevel) {
+		record(ctx, FatalLevel, l.tag, x, 2, l.withFields([]Field{Msgf(format, args...)})...)
+	}
+	exitOnFatal()
+}
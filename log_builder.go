@@ -0,0 +1,147 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"maps"
+
+	"github.com/go-spring/stdlib/errutil"
+)
+
+// SyncLoggerBuilder builds a SyncLogger programmatically, without a
+// configuration storage, e.g. for embedded or library use cases.
+//
+//	l, err := log.NewSyncLoggerBuilder().
+//		Level(log.InfoLevel).
+//		Appender(&log.ConsoleAppender{}).
+//		Tags("_com_*").
+//		Build()
+type SyncLoggerBuilder struct {
+	name      string
+	level     LevelRange
+	tags      []string
+	appenders []Appender
+}
+
+// NewSyncLoggerBuilder creates a SyncLoggerBuilder, whose logger
+// handles all levels unless Level is called.
+func NewSyncLoggerBuilder() *SyncLoggerBuilder {
+	return &SyncLoggerBuilder{
+		level: LevelRange{
+			MinLevel: NoneLevel,
+			MaxLevel: MaxLevel,
+		},
+	}
+}
+
+// Name sets the name of the logger.
+func (b *SyncLoggerBuilder) Name(name string) *SyncLoggerBuilder {
+	b.name = name
+	return b
+}
+
+// Level sets the minimum level handled by the logger.
+func (b *SyncLoggerBuilder) Level(l Level) *SyncLoggerBuilder {
+	b.level.MinLevel = l
+	return b
+}
+
+// Appender adds an appender, which must be concurrent-safe.
+func (b *SyncLoggerBuilder) Appender(a Appender) *SyncLoggerBuilder {
+	b.appenders = append(b.appenders, a)
+	return b
+}
+
+// Tags adds the tags handled by the logger, including "xxx_*" patterns.
+func (b *SyncLoggerBuilder) Tags(tags ...string) *SyncLoggerBuilder {
+	b.tags = append(b.tags, tags...)
+	return b
+}
+
+// Build starts the appenders and the logger, and binds the logger to the
+// registered tags for which its tags are the most specific match, in the
+// same way as Refresh. The logger and the appenders are stopped by
+// Destroy, or replaced by the next Refresh.
+func (b *SyncLoggerBuilder) Build() (Logger, error) {
+	tags, err := parseLoggerTags(b.tags)
+	if err != nil {
+		return nil, errutil.Explain(err, "build logger %s error", b.name)
+	}
+
+	l := &SyncLogger{
+		LoggerBase: LoggerBase{
+			Name:  b.name,
+			Tags:  tags,
+			Level: b.level,
+		},
+	}
+	for _, a := range b.appenders {
+		if !a.ConcurrentSafe() {
+			err = errutil.Explain(nil, "appender %s is not concurrent-safe", a.GetName())
+			return nil, errutil.Explain(err, "build logger %s error", b.name)
+		}
+		l.AppenderRefs = append(l.AppenderRefs, &AppenderRef{
+			Appender: a,
+			Level:    LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel},
+		})
+	}
+
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+
+	ensureDefaultStarted()
+	layouts := pluginLayouts(nil, b.appenders)
+	if err = startLayouts(layouts); err != nil {
+		return nil, errutil.Explain(err, "build logger %s error", b.name)
+	}
+	for i, a := range b.appenders {
+		if err = a.Start(); err != nil {
+			stopComponents(nil, b.appenders[:i], layouts)
+			return nil, errutil.Explain(err, "appender %s start error", a.GetName())
+		}
+	}
+	if err = l.Start(); err != nil {
+		stopComponents(nil, b.appenders, layouts)
+		return nil, errutil.Explain(err, "logger %s start error", b.name)
+	}
+
+	// The logger takes over the tags for which it is the most specific,
+	// as if it had been configured along with the current loggers.
+	cTags := maps.Clone(global.tags)
+	if cTags == nil {
+		cTags = make(map[string]Logger)
+	}
+	for _, tag := range tags {
+		cTags[tag] = l
+	}
+	tagMutex.RLock()
+	for tag, t := range tagRegistry {
+		if findLogger(cTags, nil, tag) == l {
+			t.logger.Store(&loggerValue{l})
+		}
+	}
+	tagMutex.RUnlock()
+	global.tags = cTags
+
+	global.loggers = append(global.loggers, l)
+	global.appenders = append(global.appenders, b.appenders...)
+	global.layouts = append(global.layouts, layouts...)
+	if layoutsNeedGoroutineID(layouts) {
+		captureGoroutineID.Store(true)
+	}
+	return l, nil
+}
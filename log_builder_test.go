@@ -0,0 +1,101 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-spring/stdlib/testing/assert"
+)
+
+func TestSyncLoggerBuilder(t *testing.T) {
+
+	t.Run("invalid tag", func(t *testing.T) {
+		_, err := NewSyncLoggerBuilder().Name("x").Tags("_com*").Build()
+		assert.Error(t, err).Matches("build logger x error: tag '_com\\*' is invalid")
+	})
+
+	t.Run("no tag", func(t *testing.T) {
+		_, err := NewSyncLoggerBuilder().Name("x").Build()
+		assert.Error(t, err).Matches("build logger x error: logger must have attribute 'tag'")
+	})
+
+	t.Run("not concurrent-safe", func(t *testing.T) {
+		a := &RollingFileAppender{AppenderBase: AppenderBase{Name: "file"}}
+		_, err := NewSyncLoggerBuilder().Name("x").Appender(a).Tags("_com_*").Build()
+		assert.Error(t, err).Matches("build logger x error: appender file is not concurrent-safe")
+	})
+
+	t.Run("success", func(t *testing.T) {
+		defer Destroy()
+
+		inTag := RegisterTag("_com_request_in")
+		defTag := RegisterTag("_def")
+
+		a := &CaptureAppender{}
+		l, err := NewSyncLoggerBuilder().
+			Name("builder").
+			Level(InfoLevel).
+			Appender(a).
+			Tags("_com_request_*").
+			Build()
+		assert.Error(t, err).Nil()
+		assert.String(t, l.GetName()).Equal("builder")
+
+		ctx := context.Background()
+		Record(ctx, InfoLevel, inTag, 2, Msg("hello"))
+		Record(ctx, DebugLevel, inTag, 2, Msg("ignored"))
+		Record(ctx, InfoLevel, defTag, 2, Msg("other"))
+
+		events := a.Events()
+		assert.Number(t, len(events)).Equal(1)
+		assert.String(t, events[0].Message()).Equal("hello")
+		assert.String(t, events[0].Tag).Equal("_com_request_in")
+	})
+
+	t.Run("most specific", func(t *testing.T) {
+		defer Destroy()
+
+		inTag := RegisterTag("_com_request_in")
+		outTag := RegisterTag("_com_request_out")
+
+		c, err := RefreshConfigWithOptions(map[string]string{
+			"appender.console.type":              "ConsoleAppender",
+			"logger.root.type":                   "Logger",
+			"logger.root.appenderRef.ref":        "console",
+			"logger.myLogger.type":               "Logger",
+			"logger.myLogger.tag":                "_com_request_*",
+			"logger.myLogger.appenderRef[0].ref": "console",
+		}, RefreshOptions{})
+		assert.Error(t, err).Nil()
+		myLogger := c.Loggers["myLogger"]
+
+		// a less specific pattern doesn't take over the configured tags
+		l1, err := NewSyncLoggerBuilder().Name("l1").Appender(&CaptureAppender{}).Tags("_com_*").Build()
+		assert.Error(t, err).Nil()
+		assert.That(t, getLogger(inTag) == myLogger).True()
+		assert.That(t, getLogger(outTag) == myLogger).True()
+		assert.That(t, getLogger(inTag) == l1).False()
+
+		// a more specific one does
+		l2, err := NewSyncLoggerBuilder().Name("l2").Appender(&CaptureAppender{}).Tags("_com_request_in").Build()
+		assert.Error(t, err).Nil()
+		assert.That(t, getLogger(inTag) == l2).True()
+		assert.That(t, getLogger(outTag) == myLogger).True()
+	})
+}
@@ -17,6 +17,8 @@
 package log
 
 import (
+	"bytes"
+	"slices"
 	"sync"
 	"time"
 )
@@ -27,6 +29,10 @@ var eventPool = sync.Pool{
 	},
 }
 
+// fieldsBufCap is the largest fields buffer kept by a pooled event,
+// so that an occasional event with many fields doesn't pin its memory.
+const fieldsBufCap = 64
+
 // Event represents a single log entry. It contains both the
 // log message context (e.g., time, file, line, tag) and
 // structured metadata (fields and context values).
@@ -40,15 +46,70 @@ type Event struct {
 	CtxString string    // String representation extracted from the context (e.g., trace ID)
 	CtxFields []Field   // Additional structured fields extracted from the context (e.g., request ID, user ID)
 	RawBytes  []byte    // Raw data, only used for Write operations, mutually exclusive with other fields
+	Seq       uint64    // Monotonic sequence number in emit order, zero for raw data
+	Logger    string    // Name of the logger the tag was routed to, empty for the default logger
+	GID       uint64    // ID of the logging goroutine, zero unless captured for IncludeGoroutineID
+
+	flushed   chan struct{} // Closed once the event is written, see FlushLevel and AsyncAppender.Sync
+	flushOnly bool          // Not written, only waits for the events before it, see AsyncLogger.Flush
+	rendered  bool          // RawBytes was rendered from the other fields by a logger layout
+	fieldsBuf []Field       // Buffer backing Fields, reused when the event is pooled
+}
+
+// Message returns the value of the last string field with the key "msg"
+// in Fields, or an empty string if there is none.
+func (e *Event) Message() string {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if f := e.Fields[i]; f.Key == MsgKey && f.Type == ValueTypeString {
+			return f.stringValue()
+		}
+	}
+	return ""
+}
+
+// HasMarker reports whether the event carries the marker name, as a
+// field created by Marker either in Fields or in CtxFields.
+func (e *Event) HasMarker(name string) bool {
+	return slices.ContainsFunc(e.Fields, isMarker(name)) ||
+		slices.ContainsFunc(e.CtxFields, isMarker(name))
+}
+
+// isMarker returns a function reporting whether a field is the marker name.
+func isMarker(name string) func(Field) bool {
+	return func(f Field) bool {
+		return isMarkerField(f) && f.stringValue() == name
+	}
+}
+
+// isMarkerField reports whether the field was created by Marker.
+func isMarkerField(f Field) bool {
+	return f.Key == MarkerKey && f.Type == ValueTypeString
+}
+
+// Clone returns a copy of the event that doesn't share memory with it,
+// so that it can be retained after the event is reset and reused. The
+// copy is not taken from the pool.
+func (e *Event) Clone() *Event {
+	x := *e
+	x.Fields = slices.Clone(e.Fields)
+	x.CtxFields = slices.Clone(e.CtxFields)
+	x.RawBytes = bytes.Clone(e.RawBytes)
+	x.flushed = nil
+	x.fieldsBuf = nil
+	return &x
 }
 
 // getEvent retrieves an *Event from the pool.
 // If the pool is empty, a new Event will be created.
 func getEvent() *Event {
+	if DisablePooling {
+		return &Event{}
+	}
 	return eventPool.Get().(*Event)
 }
 
-// Reset clears the fields of the Event and returns it to the pool.
+// Reset clears the fields of the Event and returns it to the pool,
+// unless DisablePooling is set.
 func (e *Event) Reset() {
 	e.Level = NoneLevel
 	e.Time = time.Time{}
@@ -59,5 +120,18 @@ func (e *Event) Reset() {
 	e.CtxString = ""
 	e.CtxFields = nil
 	e.RawBytes = nil
-	eventPool.Put(e)
+	e.Seq = 0
+	e.Logger = ""
+	e.GID = 0
+	e.flushed = nil
+	e.flushOnly = false
+	e.rendered = false
+	if cap(e.fieldsBuf) > fieldsBufCap {
+		e.fieldsBuf = nil
+	} else {
+		clear(e.fieldsBuf) // drop the references held by the fields
+	}
+	if !DisablePooling {
+		eventPool.Put(e)
+	}
 }
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spring/log"
+	"github.com/go-spring/stdlib/testing/assert"
+)
+
+func TestExitOnFatal(t *testing.T) {
+
+	if dir := os.Getenv("TEST_EXIT_ON_FATAL_DIR"); dir != "" {
+		err := log.RefreshConfig(map[string]string{
+			"appender.file.type":              "FileAppender",
+			"appender.file.dir":               dir,
+			"appender.file.file":              "fatal.log",
+			"logger.root.type":                "AsyncLogger",
+			"logger.root.bufferSize":          "100",
+			"logger.root.appenderRef.ref":     "file",
+			"logger.myLogger.type":            "AsyncLogger",
+			"logger.myLogger.tag":             "_com_request_*",
+			"logger.myLogger.bufferSize":      "100",
+			"logger.myLogger.appenderRef.ref": "file",
+		})
+		if err != nil {
+			os.Exit(2)
+		}
+		log.ExitOnFatal = true
+		log.Infof(t.Context(), TagDefault, "before fatal")
+		log.Fatalf(t.Context(), TagDefault, "fatal error")
+		os.Exit(0) // unreachable if ExitOnFatal works
+	}
+
+	t.Run("fatal", func(t *testing.T) {
+		dir := t.TempDir()
+		cmd := exec.Command(os.Args[0], "-test.run=^TestExitOnFatal$")
+		cmd.Env = append(os.Environ(), "TEST_EXIT_ON_FATAL_DIR="+dir)
+		err := cmd.Run()
+		assert.Error(t, err).Matches("exit status 1")
+
+		b, err := os.ReadFile(filepath.Join(dir, "fatal.log"))
+		assert.Error(t, err).Nil()
+		assert.String(t, string(b)).Matches(`\[INFO].*_def\|\|msg=before fatal\n\[FATAL].*_def\|\|msg=fatal error\n$`)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		log.Stdout = bytes.NewBuffer(nil)
+		log.ExitOnFatal = true
+		defer func() {
+			log.Stdout = os.Stdout
+			log.ExitOnFatal = false
+		}()
+
+		assert.Panic(t, func() {
+			log.Panicf(t.Context(), TagDefault, "panic %d", 1)
+		}, "panic 1")
+
+		assert.Panic(t, func() {
+			log.Panic(t.Context(), TagDefault, log.Msg("panic"), log.Int("code", 2))
+		}, "msg=panic\\|\\|code=2")
+	})
+
+	t.Run("panic flushes", func(t *testing.T) {
+		dir := t.TempDir()
+		err := log.RefreshConfig(map[string]string{
+			"appender.file.type":              "FileAppender",
+			"appender.file.dir":               dir,
+			"appender.file.file":              "panic.log",
+			"logger.root.type":                "AsyncLogger",
+			"logger.root.bufferSize":          "100",
+			"logger.root.appenderRef.ref":     "file",
+			"logger.myLogger.type":            "AsyncLogger",
+			"logger.myLogger.tag":             "_com_request_*",
+			"logger.myLogger.bufferSize":      "100",
+			"logger.myLogger.appenderRef.ref": "file",
+		})
+		assert.Error(t, err).Nil()
+		log.ExitOnFatal = true
+		defer func() { log.ExitOnFatal = false }()
+
+		assert.Panic(t, func() {
+			log.With(TagDefault).Panicf(t.Context(), "panic %d", 3)
+		}, "panic 3")
+
+		b, err := os.ReadFile(filepath.Join(dir, "panic.log"))
+		assert.Error(t, err).Nil()
+		assert.String(t, string(b)).Matches(`\[PANIC].*_def\|\|msg=panic 3\n$`)
+
+		// The configuration is kept once the panic is recovered.
+		log.With(TagDefault).Infof(t.Context(), "recovered")
+		log.Destroy()
+
+		b, err = os.ReadFile(filepath.Join(dir, "panic.log"))
+		assert.Error(t, err).Nil()
+		assert.String(t, string(b)).Matches(`msg=panic 3\n\[INFO].*_def\|\|msg=recovered\n$`)
+	})
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-spring/stdlib/errutil"
+)
+
+// Heartbeat periodically emits a "still alive" log event, which is useful
+// for detecting stuck processes and also forces buffered or asynchronous
+// loggers to push events through. It is configured under the "heartbeat"
+// section and is disabled when Interval is zero.
+type Heartbeat struct {
+	Interval time.Duration `PluginAttribute:"interval,default=0s"`
+	Tag      string        `PluginAttribute:"tag,default=_app_def"`
+	Level    Level         `PluginAttribute:"level,default=info"`
+
+	tag  *Tag
+	stop chan struct{}
+	wait chan struct{}
+}
+
+// Start launches the background goroutine that emits heartbeat events.
+// It is a no-op when Interval is zero.
+func (h *Heartbeat) Start() error {
+	if h.Interval <= 0 {
+		return nil
+	}
+	if !isValidTag(h.Tag) {
+		return errutil.Explain(nil, "invalid heartbeat tag %q", h.Tag)
+	}
+	h.tag = RegisterTag(h.Tag)
+	h.stop = make(chan struct{})
+	h.wait = make(chan struct{})
+
+	go func() {
+		defer close(h.wait)
+		ticker := time.NewTicker(h.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+				Record(context.Background(), h.Level, h.tag, 2, Msg("heartbeat"))
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the background goroutine and waits for it to exit.
+func (h *Heartbeat) Stop() {
+	if h.stop == nil {
+		return
+	}
+	close(h.stop)
+	<-h.wait
+	h.stop = nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/stdlib/testing/assert"
+)
+
+func TestHeartbeat(t *testing.T) {
+
+	t.Run("disabled", func(t *testing.T) {
+		h := &Heartbeat{}
+		err := h.Start()
+		assert.Error(t, err).Nil()
+		h.Stop()
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		h := &Heartbeat{Interval: time.Second, Tag: "*"}
+		err := h.Start()
+		assert.Error(t, err).Matches(`invalid heartbeat tag "\*"`)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		dir := t.TempDir()
+		err := RefreshConfig(map[string]string{
+			"appender.file.type":          "FileAppender",
+			"appender.file.dir":           dir,
+			"appender.file.file":          "heartbeat.log",
+			"logger.root.type":            "Logger",
+			"logger.root.level":           "info",
+			"logger.root.appenderRef.ref": "file",
+			"logger.myLogger.type":        "DiscardLogger",
+			"logger.myLogger.tag":         "_com_*",
+			"heartbeat.interval":          "10ms",
+			"heartbeat.tag":               "_app_def",
+			"heartbeat.level":             "info",
+		})
+		assert.Error(t, err).Nil()
+
+		time.Sleep(100 * time.Millisecond)
+		Destroy()
+
+		b, err := os.ReadFile(filepath.Join(dir, "heartbeat.log"))
+		assert.Error(t, err).Nil()
+		n := strings.Count(string(b), "_app_def||msg=heartbeat")
+		assert.That(t, n > 0).True()
+
+		time.Sleep(50 * time.Millisecond)
+		b, err = os.ReadFile(filepath.Join(dir, "heartbeat.log"))
+		assert.Error(t, err).Nil()
+		assert.That(t, strings.Count(string(b), "_app_def||msg=heartbeat")).Equal(n)
+	})
+}
@@ -17,16 +17,16 @@
 package log
 
 import (
+	"cmp"
+	"encoding/binary"
 	"fmt"
+	"iter"
+	"slices"
 	"strings"
 
 	"github.com/go-spring/stdlib/errutil"
 )
 
-func init() {
-	RegisterConverter(ParseLevelRange)
-}
-
 var (
 	NoneLevel  = RegisterLevel(0, "NONE")    // No logging
 	TraceLevel = RegisterLevel(100, "TRACE") // Very detailed logging, typically used for debugging
@@ -41,6 +41,10 @@ var (
 
 var levelRegistry = map[string]Level{}
 
+// canonicalLevels maps each level code to the first name registered with
+// it. The other names with the same code are aliases.
+var canonicalLevels = map[int32]string{}
+
 // Level represents a logging severity level. Each level
 // has a numeric code (for comparison) and a string name (for display).
 type Level struct {
@@ -65,15 +69,37 @@ func (l Level) UpperName() string {
 	return l.upperName
 }
 
+// Less reports whether l is less severe than other.
+func (l Level) Less(other Level) bool {
+	return l.code < other.code
+}
+
+// AtLeast reports whether l is at least as severe as other.
+func (l Level) AtLeast(other Level) bool {
+	return l.code >= other.code
+}
+
+// SortLevels sorts levels in ascending order of severity.
+// Levels with the same code (aliases) keep their relative order.
+func SortLevels(levels []Level) {
+	slices.SortStableFunc(levels, func(a, b Level) int {
+		return cmp.Compare(a.code, b.code)
+	})
+}
+
 // RegisterLevel defines a new logging Level with the given code and name.
 // The name is normalized to uppercase and stored in a global registry for lookup.
 //
 // It must be called during initialization only and is not safe for concurrent use.
-// It panics if the same name is registered with a different code.
+// It panics if the same name is registered with a different code, or if the
+// name is "ALL", which is reserved for the full level range.
 //
 // Multiple names may share the same code (aliases). Such levels are considered
 // equivalent in comparisons (by code), but remain distinct values.
 func RegisterLevel(code int32, name string) Level {
+	if strings.EqualFold(name, "all") {
+		panic("log: level name ALL is reserved")
+	}
 	if l, ok := levelRegistry[strings.ToUpper(name)]; ok {
 		if l.code == code {
 			return l
@@ -86,38 +112,184 @@ func RegisterLevel(code int32, name string) Level {
 		upperName: strings.ToUpper(name),
 	}
 	levelRegistry[l.upperName] = l
+	if _, ok := canonicalLevels[code]; !ok {
+		canonicalLevels[code] = l.upperName
+	}
 	return l
 }
 
-// LevelRange represents a range of log levels [MinLevel, MaxLevel).
+// ParseLevel parses a level name into a registered Level.
+// The comparison is case-insensitive. Returns an error for unknown levels.
+func ParseLevel(s string) (Level, error) {
+	s = strings.TrimSpace(s)
+	l, ok := levelRegistry[strings.ToUpper(s)]
+	if !ok {
+		return Level{}, errutil.Explain(nil, "invalid log level: %q", s)
+	}
+	return l, nil
+}
+
+// ParseLevelStrict is like ParseLevel, but also reports whether s is an
+// alias, i.e. a name registered with the code of an earlier level, so
+// that config linters can flag deprecated level names.
+func ParseLevelStrict(s string) (_ Level, alias bool, _ error) {
+	l, err := ParseLevel(s)
+	if err != nil {
+		return Level{}, false, err
+	}
+	return l, canonicalLevels[l.code] != l.upperName, nil
+}
+
+// LevelRange represents a range of log levels [MinLevel, MaxLevel),
+// or an explicit set of levels within that range, see ParseLevelRange.
 type LevelRange struct {
 	MinLevel Level
 	MaxLevel Level
+
+	levels levelSet // explicit set of levels, empty for the whole range
+}
+
+// levelSet is a non-empty set of level codes, sorted and encoded in a
+// string so that LevelRange stays comparable. Aliases share a code, so
+// they are the same member of the set.
+type levelSet string
+
+// newLevelSet returns the set of the codes of levels.
+func newLevelSet(levels []Level) levelSet {
+	codes := make([]int32, len(levels))
+	for i, l := range levels {
+		codes[i] = l.code
+	}
+	slices.Sort(codes)
+	codes = slices.Compact(codes)
+	b := make([]byte, 0, 4*len(codes))
+	for _, code := range codes {
+		b = binary.BigEndian.AppendUint32(b, uint32(code))
+	}
+	return levelSet(b)
+}
+
+// codes iterates over the codes of the set in ascending order.
+func (s levelSet) codes() iter.Seq[int32] {
+	return func(yield func(int32) bool) {
+		for i := 0; i < len(s); i += 4 {
+			if !yield(int32(binary.BigEndian.Uint32([]byte(s[i : i+4])))) {
+				return
+			}
+		}
+	}
+}
+
+// contains reports whether the code of l is in the set.
+func (s levelSet) contains(l Level) bool {
+	for code := range s.codes() {
+		if code == l.code {
+			return true
+		}
+	}
+	return false
+}
+
+// list returns the levels of the set in ascending order, using the first
+// name registered with each code.
+func (s levelSet) list() []Level {
+	var levels []Level
+	for code := range s.codes() {
+		levels = append(levels, levelRegistry[canonicalLevels[code]])
+	}
+	return levels
 }
 
 // Enable returns true if the given Level 'l' falls within the LevelRange.
 // The check is inclusive of MinLevel and exclusive of MaxLevel.
+// For a set of levels, the code of 'l' must be one of theirs.
 func (c LevelRange) Enable(l Level) bool {
-	return l.code >= c.MinLevel.code && l.code < c.MaxLevel.code
+	if c.levels != "" {
+		return c.levels.contains(l)
+	}
+	return l.AtLeast(c.MinLevel) && l.Less(c.MaxLevel)
+}
+
+// isZero reports whether the LevelRange is unset, i.e. enables nothing.
+func (c LevelRange) isZero() bool {
+	return c == LevelRange{}
+}
+
+// isEmpty reports whether the LevelRange enables no level at all.
+func (c LevelRange) isEmpty() bool {
+	if c.levels != "" {
+		return false
+	}
+	return !c.MinLevel.Less(c.MaxLevel)
+}
+
+// intersect returns the levels enabled by both c and o. An empty result
+// is a range whose MinLevel equals its MaxLevel.
+func (c LevelRange) intersect(o LevelRange) LevelRange {
+	minLevel := c.MinLevel
+	if minLevel.Less(o.MinLevel) {
+		minLevel = o.MinLevel
+	}
+	if c.levels == "" && o.levels == "" {
+		maxLevel := c.MaxLevel
+		if o.MaxLevel.Less(maxLevel) {
+			maxLevel = o.MaxLevel
+		}
+		if maxLevel.Less(minLevel) {
+			maxLevel = minLevel
+		}
+		return LevelRange{MinLevel: minLevel, MaxLevel: maxLevel}
+	}
+	set, other := c, o
+	if set.levels == "" {
+		set, other = o, c
+	}
+	var levels []Level
+	for _, l := range set.levels.list() {
+		if other.Enable(l) {
+			levels = append(levels, l)
+		}
+	}
+	if len(levels) == 0 {
+		return LevelRange{MinLevel: minLevel, MaxLevel: minLevel}
+	}
+	return newLevelSetRange(levels)
 }
 
 // ParseLevelRange parses a string into a LevelRange.
 //
 // Supported formats:
 //
-//	""           → [NONE, MAX)
-//	"INFO"       → [INFO, MAX)
-//	"INFO~ERROR" → [INFO, ERROR)
+//	""                → [NONE, MAX)
+//	"ALL"             → [NONE, MAX)
+//	"INFO"            → [INFO, MAX)
+//	"INFO~ERROR"      → [INFO, ERROR)
+//	"INFO..ERROR"     → [INFO, ERROR]
+//	"INFO-ERROR"      → [INFO, ERROR]
+//	"INFO,WARN,ERROR" → {INFO, WARN, ERROR}
 //
+// A string containing "," is parsed as a set, otherwise one containing ".."
+// or "-" as an inclusive range, otherwise as a "~" half-open range or a
+// single level. Forms cannot be mixed, e.g. "INFO,WARN..ERROR" is invalid.
 // The comparison is case-insensitive. Returns an error for unknown levels.
 func ParseLevelRange(s string) (LevelRange, error) {
-	if s = strings.TrimSpace(s); s == "" {
+	if s = strings.TrimSpace(s); s == "" || strings.EqualFold(s, "all") {
 		return LevelRange{
 			MinLevel: NoneLevel,
 			MaxLevel: MaxLevel,
 		}, nil
 	}
 
+	if strings.Contains(s, ",") {
+		return parseLevelSet(s)
+	}
+	if before, after, ok := strings.Cut(s, ".."); ok {
+		return parseInclusiveLevelRange(s, before, after)
+	}
+	if before, after, ok := strings.Cut(s, "-"); ok {
+		return parseInclusiveLevelRange(s, before, after)
+	}
+
 	var (
 		ok       bool
 		minLevel = NoneLevel
@@ -143,9 +315,109 @@ func ParseLevelRange(s string) (LevelRange, error) {
 			}
 		}
 	}
-	if maxLevel.code <= minLevel.code {
+	if !minLevel.Less(maxLevel) {
 		return LevelRange{}, errutil.Explain(nil, "invalid log level: %q", s)
 	}
 
 	return LevelRange{MinLevel: minLevel, MaxLevel: maxLevel}, nil
 }
+
+// MarshalText implements encoding.TextMarshaler, in a form accepted by
+// ParseLevelRange that gives the same range back:
+//
+//	[NONE, MAX)       → "all"
+//	[INFO, MAX)       → "info"
+//	[INFO, ERROR)     → "info~error"
+//	{INFO, ERROR}     → "info,error"
+//
+// The half-open form is used for bounded ranges, because it doesn't
+// depend on which levels are registered between the bounds. It returns
+// an error for a range that enables no level.
+func (c LevelRange) MarshalText() ([]byte, error) {
+	if c.isEmpty() {
+		return nil, errutil.Explain(nil, "empty level range")
+	}
+	if c.levels != "" {
+		var names []string
+		for _, l := range c.levels.list() {
+			names = append(names, l.lowerName)
+		}
+		return []byte(strings.Join(names, ",")), nil
+	}
+	if c.MaxLevel == MaxLevel {
+		if c.MinLevel == NoneLevel {
+			return []byte("all"), nil
+		}
+		return []byte(c.MinLevel.lowerName), nil
+	}
+	return []byte(c.MinLevel.lowerName + "~" + c.MaxLevel.lowerName), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseLevelRange.
+func (c *LevelRange) UnmarshalText(text []byte) error {
+	r, err := ParseLevelRange(string(text))
+	if err != nil {
+		return err
+	}
+	*c = r
+	return nil
+}
+
+// parseInclusiveLevelRange parses the bounds of an inclusive range. The
+// upper bound is converted to the next registered level, so that the range
+// stays half-open as LevelRange expects.
+func parseInclusiveLevelRange(s, before, after string) (LevelRange, error) {
+	minLevel, err1 := ParseLevel(before)
+	lastLevel, err2 := ParseLevel(after)
+	if err1 != nil || err2 != nil {
+		return LevelRange{}, errutil.Explain(nil, "invalid log level: %q", s)
+	}
+	maxLevel, ok := nextLevel(lastLevel)
+	if !ok || lastLevel.Less(minLevel) {
+		return LevelRange{}, errutil.Explain(nil, "invalid log level: %q", s)
+	}
+	return LevelRange{MinLevel: minLevel, MaxLevel: maxLevel}, nil
+}
+
+// parseLevelSet parses a comma-separated set of levels.
+func parseLevelSet(s string) (LevelRange, error) {
+	var levels []Level
+	for str := range strings.SplitSeq(s, ",") {
+		l, err := ParseLevel(str)
+		if err != nil {
+			return LevelRange{}, err
+		}
+		if l.code == MaxLevel.code {
+			return LevelRange{}, errutil.Explain(nil, "invalid log level: %q", s)
+		}
+		levels = append(levels, l)
+	}
+	return newLevelSetRange(levels), nil
+}
+
+// newLevelSetRange returns the LevelRange of a non-empty set of levels,
+// bounded by the lowest and the next level above the highest.
+func newLevelSetRange(levels []Level) LevelRange {
+	set := newLevelSet(levels)
+	levels = set.list()
+	maxLevel, _ := nextLevel(levels[len(levels)-1])
+	return LevelRange{
+		MinLevel: levels[0],
+		MaxLevel: maxLevel,
+		levels:   set,
+	}
+}
+
+// nextLevel returns the registered level with the lowest code above l.
+func nextLevel(l Level) (Level, bool) {
+	var (
+		next  Level
+		found bool
+	)
+	for _, x := range levelRegistry {
+		if l.Less(x) && (!found || x.Less(next)) {
+			next, found = x, true
+		}
+	}
+	return next, found
+}
@@ -17,9 +17,12 @@
 package log
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 
 	"github.com/go-spring/stdlib/errutil"
+	"github.com/go-spring/stdlib/flatten"
 	"github.com/go-spring/stdlib/testing/assert"
 )
 
@@ -88,3 +91,296 @@ func TestParseLevelRange(t *testing.T) {
 	assert.Number(t, ErrorLevel.Code()).LessThan(PanicLevel.Code())
 	assert.Number(t, PanicLevel.Code()).LessThan(FatalLevel.Code())
 }
+
+func TestLevelCompare(t *testing.T) {
+	levels := []Level{
+		NoneLevel, TraceLevel, DebugLevel, InfoLevel, WarnLevel,
+		ErrorLevel, PanicLevel, FatalLevel, MaxLevel,
+	}
+	for i, a := range levels {
+		for j, b := range levels {
+			assert.That(t, a.Less(b)).Equal(i < j)
+			assert.That(t, a.AtLeast(b)).Equal(i >= j)
+		}
+	}
+
+	alias := RegisterLevel(InfoLevel.Code(), "information")
+	assert.That(t, alias.Less(InfoLevel)).False()
+	assert.That(t, alias.AtLeast(InfoLevel)).True()
+	delete(levelRegistry, alias.UpperName())
+}
+
+func TestParseLevelStrict(t *testing.T) {
+	l, alias, err := ParseLevelStrict(" Info ")
+	assert.Error(t, err).Nil()
+	assert.That(t, l).Equal(InfoLevel)
+	assert.That(t, alias).False()
+
+	information := RegisterLevel(InfoLevel.Code(), "information")
+	defer delete(levelRegistry, information.UpperName())
+
+	l, alias, err = ParseLevelStrict("information")
+	assert.Error(t, err).Nil()
+	assert.That(t, l).Equal(information)
+	assert.That(t, alias).True()
+
+	// registering the canonical name again doesn't make it an alias
+	assert.That(t, RegisterLevel(InfoLevel.Code(), "INFO")).Equal(InfoLevel)
+	l, alias, err = ParseLevelStrict("info")
+	assert.Error(t, err).Nil()
+	assert.That(t, l).Equal(InfoLevel)
+	assert.That(t, alias).False()
+
+	_, alias, err = ParseLevelStrict("verbose")
+	assert.Error(t, err).Matches(`invalid log level: "verbose"`)
+	assert.That(t, alias).False()
+}
+
+func TestSortLevels(t *testing.T) {
+	levels := []Level{
+		MaxLevel, InfoLevel, NoneLevel, FatalLevel, TraceLevel,
+		ErrorLevel, DebugLevel, PanicLevel, WarnLevel,
+	}
+	SortLevels(levels)
+	assert.That(t, levels).Equal([]Level{
+		NoneLevel, TraceLevel, DebugLevel, InfoLevel, WarnLevel,
+		ErrorLevel, PanicLevel, FatalLevel, MaxLevel,
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel(" Warn ")
+	assert.Error(t, err).Nil()
+	assert.That(t, l).Equal(WarnLevel)
+
+	_, err = ParseLevel("unknown")
+	assert.Error(t, err).Matches(`invalid log level: "unknown"`)
+}
+
+func TestParseLevelRangeForms(t *testing.T) {
+	enabled := func(r LevelRange) []string {
+		var names []string
+		for _, l := range []Level{TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel} {
+			if r.Enable(l) {
+				names = append(names, l.LowerName())
+			}
+		}
+		return names
+	}
+
+	tests := []struct {
+		str     string
+		want    []string
+		wantErr string
+	}{
+		{str: "info", want: []string{"info", "warn", "error", "panic", "fatal"}},
+		{str: "info~error", want: []string{"info", "warn"}},
+		{str: "info..error", want: []string{"info", "warn", "error"}},
+		{str: " INFO - ERROR ", want: []string{"info", "warn", "error"}},
+		{str: "warn..warn", want: []string{"warn"}},
+		{str: "error..fatal", want: []string{"error", "panic", "fatal"}},
+		{str: "error,info,warn", want: []string{"info", "warn", "error"}},
+		{str: "info,info", want: []string{"info"}},
+		{str: "error..info", wantErr: `invalid log level: "error..info"`},
+		{str: "info..", wantErr: `invalid log level: "info.."`},
+		{str: "info..max", wantErr: `invalid log level: "info..max"`},
+		{str: "info..unknown", wantErr: `invalid log level: "info..unknown"`},
+		{str: "info,", wantErr: `invalid log level: ""`},
+		{str: "info,max", wantErr: `invalid log level: "info,max"`},
+		{str: "info,warn..error", wantErr: `invalid log level: "warn..error"`},
+		{str: "info..warn~error", wantErr: `invalid log level: "info..warn~error"`},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevelRange(tt.str)
+		if tt.wantErr != "" {
+			assert.Error(t, err).Matches(tt.wantErr)
+			continue
+		}
+		assert.Error(t, err).Nil()
+		assert.That(t, enabled(got)).Equal(tt.want)
+	}
+
+	t.Run("inject", func(t *testing.T) {
+		s := flatten.NewPropertiesStorage(flatten.NewProperties(nil))
+		s.Set("ref.ref", "console")
+		s.Set("ref.level", "info,error")
+		v, err := newPlugin(reflect.TypeFor[AppenderRef](), "ref", s)
+		assert.Error(t, err).Nil()
+		r := v.Interface().(*AppenderRef)
+		assert.That(t, enabled(r.Level)).Equal([]string{"info", "error"})
+	})
+}
+
+func TestLevelRangeText(t *testing.T) {
+	for _, tt := range []struct {
+		str  string
+		want string
+	}{
+		{str: "", want: "all"},
+		{str: "ALL", want: "all"},
+		{str: "none", want: "all"},
+		{str: "info", want: "info"},
+		{str: "Info~Error", want: "info~error"},
+		{str: "info..error", want: "info~panic"},
+		{str: "warn..warn", want: "warn~error"},
+		{str: "error,info,warn", want: "info,warn,error"},
+		{str: "fatal,trace", want: "trace,fatal"},
+	} {
+		r, err := ParseLevelRange(tt.str)
+		assert.Error(t, err).Nil()
+		b, err := r.MarshalText()
+		assert.Error(t, err).Nil()
+		assert.String(t, string(b)).Equal(tt.want)
+
+		// the text gives the same range back
+		var got LevelRange
+		err = got.UnmarshalText(b)
+		assert.Error(t, err).Nil()
+		assert.That(t, got).Equal(r)
+	}
+
+	// usable by standard libraries
+	var v struct {
+		Level LevelRange `json:"level"`
+	}
+	err := json.Unmarshal([]byte(`{"level":"debug..warn"}`), &v)
+	assert.Error(t, err).Nil()
+	b, err := json.Marshal(v)
+	assert.Error(t, err).Nil()
+	assert.String(t, string(b)).Equal(`{"level":"debug~error"}`)
+
+	err = json.Unmarshal([]byte(`{"level":"verbose"}`), &v)
+	assert.Error(t, err).Matches(`invalid log level: "verbose"`)
+
+	_, err = LevelRange{}.MarshalText()
+	assert.Error(t, err).Matches("empty level range")
+	_, err = LevelRange{MinLevel: InfoLevel, MaxLevel: InfoLevel}.MarshalText()
+	assert.Error(t, err).Matches("empty level range")
+}
+
+func TestLevelRangeAll(t *testing.T) {
+	for _, str := range []string{"", "all", " ALL "} {
+		r, err := ParseLevelRange(str)
+		assert.Error(t, err).Nil()
+		assert.That(t, r).Equal(LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel})
+	}
+
+	_, err := ParseLevelRange("all~error")
+	assert.Error(t, err).Matches(`invalid log level: "all"`)
+
+	assert.Panic(t, func() {
+		RegisterLevel(900, "all")
+	}, "log: level name ALL is reserved")
+
+	t.Run("unset appender ref level", func(t *testing.T) {
+		c := &CaptureAppender{}
+		l := &SyncLogger{
+			LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: InfoLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: []*AppenderRef{{Appender: c}},
+		}
+		for _, level := range []Level{DebugLevel, InfoLevel, WarnLevel, FatalLevel} {
+			e := getEvent()
+			e.Level = level
+			l.Append(e)
+		}
+		assert.Number(t, c.Len()).Equal(3)
+	})
+}
+
+func TestEffectiveRanges(t *testing.T) {
+	parse := func(s string) LevelRange {
+		r, err := ParseLevelRange(s)
+		assert.Error(t, err).Nil()
+		return r
+	}
+	newLogger := func(level string, refLevels ...string) Logger {
+		l := &SyncLogger{LoggerBase: LoggerBase{Level: parse(level)}}
+		for _, s := range refLevels {
+			r := &AppenderRef{Appender: &CaptureAppender{}}
+			if s != "-" {
+				r.Level = parse(s)
+			}
+			l.AppenderRefs = append(l.AppenderRefs, r)
+		}
+		return l
+	}
+
+	t.Run("overlapping", func(t *testing.T) {
+		l := newLogger("debug~error", "info", "trace~warn", "-")
+		assert.That(t, EffectiveRanges(l)).Equal([]LevelRange{
+			{MinLevel: InfoLevel, MaxLevel: ErrorLevel},
+			{MinLevel: DebugLevel, MaxLevel: WarnLevel},
+			{MinLevel: DebugLevel, MaxLevel: ErrorLevel},
+		})
+	})
+
+	t.Run("open-ended", func(t *testing.T) {
+		l := newLogger("warn", "info", "all", "error..panic")
+		assert.That(t, EffectiveRanges(l)).Equal([]LevelRange{
+			{MinLevel: WarnLevel, MaxLevel: MaxLevel},
+			{MinLevel: WarnLevel, MaxLevel: MaxLevel},
+			{MinLevel: ErrorLevel, MaxLevel: FatalLevel},
+		})
+	})
+
+	t.Run("disjoint", func(t *testing.T) {
+		l := newLogger("error", "trace~info")
+		r := EffectiveRanges(l)[0]
+		assert.That(t, r).Equal(LevelRange{MinLevel: ErrorLevel, MaxLevel: ErrorLevel})
+		for _, level := range []Level{TraceLevel, InfoLevel, ErrorLevel, FatalLevel} {
+			assert.That(t, r.Enable(level)).False()
+		}
+	})
+
+	t.Run("level sets", func(t *testing.T) {
+		l := newLogger("info", "debug,warn,error", "trace,debug")
+		r := EffectiveRanges(l)
+		assert.That(t, r[0]).Equal(LevelRange{
+			MinLevel: WarnLevel,
+			MaxLevel: PanicLevel,
+			levels:   newLevelSet([]Level{WarnLevel, ErrorLevel}),
+		})
+		assert.That(t, r[1]).Equal(LevelRange{MinLevel: InfoLevel, MaxLevel: InfoLevel})
+	})
+
+	t.Run("no appender refs", func(t *testing.T) {
+		assert.That(t, EffectiveRanges(&DiscardLogger{})).Nil()
+	})
+}
+
+func TestLevelRangeIsEmpty(t *testing.T) {
+	assert.That(t, LevelRange{}.isEmpty()).True()
+	assert.That(t, LevelRange{MinLevel: InfoLevel, MaxLevel: InfoLevel}.isEmpty()).True()
+	assert.That(t, LevelRange{MinLevel: WarnLevel, MaxLevel: InfoLevel}.isEmpty()).True()
+	assert.That(t, LevelRange{MinLevel: InfoLevel, MaxLevel: WarnLevel}.isEmpty()).False()
+
+	r, err := ParseLevelRange("info,error")
+	assert.Error(t, err).Nil()
+	assert.That(t, r.isEmpty()).False()
+	r2, err := ParseLevelRange("warn")
+	assert.Error(t, err).Nil()
+	assert.That(t, r.intersect(r2).isEmpty()).False()
+	r2, err = ParseLevelRange("warn~error")
+	assert.Error(t, err).Nil()
+	assert.That(t, r.intersect(r2).isEmpty()).True()
+}
+
+func TestLevelRangeAliases(t *testing.T) {
+	information := RegisterLevel(InfoLevel.Code(), "information")
+	defer delete(levelRegistry, information.UpperName())
+
+	r, err := ParseLevelRange("info,information,error")
+	assert.Error(t, err).Nil()
+	assert.That(t, r.Enable(InfoLevel)).True()
+	assert.That(t, r.Enable(information)).True()
+	assert.That(t, r.Enable(WarnLevel)).False()
+
+	// Sets with the same codes are equal, whatever the names used.
+	r2, err := ParseLevelRange("error,information")
+	assert.Error(t, err).Nil()
+	assert.That(t, r == r2).True()
+
+	b, err := r.MarshalText()
+	assert.Error(t, err).Nil()
+	assert.String(t, string(b)).Equal("info,error")
+}
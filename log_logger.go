@@ -16,12 +16,25 @@
 
 package log
 
-import "sync/atomic"
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
 
-// loggerMap stores LoggerWrapper instances keyed by their names.
-// Note: This map is not concurrency-safe. It is expected to be modified
-// only during the initialization phase.
-var loggerMap = map[string]*LoggerWrapper{}
+var (
+	// loggerMutex guards loggerMap, since loggers may be obtained from
+	// init functions of packages that are initialized concurrently.
+	loggerMutex sync.RWMutex
+
+	// loggerMap stores LoggerWrapper instances keyed by their names.
+	loggerMap = map[string]*LoggerWrapper{}
+
+	// refreshed is set once Refresh has bound the loggers, and cleared by
+	// Destroy. New LoggerWrappers cannot be created in the meantime, since
+	// they would not be bound to any logger.
+	refreshed atomic.Bool
+)
 
 // LoggerWrapper wraps a Logger instance and allows atomic replacement
 // of the underlying Logger at runtime. This ensures that concurrent
@@ -40,6 +53,9 @@ func (m *LoggerWrapper) Enable(l Level) bool {
 // Write forwards the given byte slice to the currently active Logger
 // with the specified level.
 func (m *LoggerWrapper) Write(level Level, b []byte) {
+	if suppressed.Load() > 0 {
+		return
+	}
 	e := getEvent()
 	e.Level = level
 	e.RawBytes = b
@@ -48,10 +64,19 @@ func (m *LoggerWrapper) Write(level Level, b []byte) {
 
 // GetLogger retrieves an existing LoggerWrapper by name,
 // or creates a new one if it does not exist yet.
-// This function must be called only during the initialization phase.
+//
+// New loggers must be obtained during the initialization phase, so that
+// Refresh checks that they are configured and binds them. Once Refresh
+// has succeeded, the existing LoggerWrappers can still be retrieved, and
+// are bound to the live loggers, but GetLogger panics for a new name.
 func GetLogger(name string) *LoggerWrapper {
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
 	m, ok := loggerMap[name]
 	if !ok {
+		if refreshed.Load() {
+			panic(fmt.Sprintf("log: logger %s must be obtained before Refresh", name))
+		}
 		m = &LoggerWrapper{name: name}
 		loggerMap[name] = m
 	}
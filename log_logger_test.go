@@ -17,7 +17,11 @@
 package log
 
 import (
+	"bytes"
 	"encoding/json"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/go-spring/stdlib/flatten"
@@ -77,3 +81,85 @@ func TestGetLogger(t *testing.T) {
 	delete(loggerMap, l.name)
 	Destroy()
 }
+
+func TestGetLoggerAfterRefresh(t *testing.T) {
+	l := GetLogger("myLogger")
+	c, err := RefreshConfigWithOptions(map[string]string{
+		"appender.capture.type":           "CaptureAppender",
+		"logger.root.type":                "Logger",
+		"logger.root.appenderRef.ref":     "capture",
+		"logger.myLogger.type":            "Logger",
+		"logger.myLogger.tag":             "_com_request_*",
+		"logger.myLogger.appenderRef.ref": "capture",
+	}, RefreshOptions{})
+	assert.Error(t, err).Nil()
+
+	// the existing wrapper is returned, bound to the live logger
+	m := GetLogger("myLogger")
+	assert.That(t, m == l).True()
+	assert.That(t, m.logger.Load().Logger).Equal(c.Loggers["myLogger"])
+	m.Write(InfoLevel, []byte("late write\n"))
+	events := c.Appenders["capture"].(*CaptureAppender).Events()
+	assert.Number(t, len(events)).Equal(1)
+	assert.String(t, string(events[0].RawBytes)).Equal("late write\n")
+
+	// but a new one cannot be created, since it would not be bound
+	assert.Panic(t, func() {
+		GetLogger("lateLogger")
+	}, "log: logger lateLogger must be obtained before Refresh")
+	_, ok := loggerMap["lateLogger"]
+	assert.That(t, ok).False()
+
+	Destroy()
+	l = GetLogger("lateLogger")
+	delete(loggerMap, l.name)
+}
+
+// startCountLayout is a TextLayout that records how many times it was started.
+type startCountLayout struct {
+	TextLayout
+	started int
+}
+
+func (c *startCountLayout) Start() error {
+	c.started++
+	return nil
+}
+
+func TestDefaultLoggerLazyStart(t *testing.T) {
+	Destroy()
+
+	layout := &startCountLayout{}
+	oldLogger := defaultLogger
+	defaultLogger = &ConsoleLogger{
+		LoggerBase: LoggerBase{
+			Level: LevelRange{MinLevel: InfoLevel, MaxLevel: MaxLevel},
+		},
+		appender: &ConsoleAppender{
+			AppenderBase: AppenderBase{Layout: layout},
+		},
+	}
+	defaultStarted = sync.Once{}
+	buf := bytes.NewBuffer(nil)
+	Stdout = buf
+	defer func() {
+		defaultLogger = oldLogger
+		defaultStarted = sync.Once{}
+		Stdout = os.Stdout
+	}()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			Infof(t.Context(), TagAppDef, "hello %s", "world")
+		})
+	}
+	wg.Wait()
+
+	assert.Number(t, layout.started).Equal(1)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Number(t, len(lines)).Equal(4)
+	for _, line := range lines {
+		assert.String(t, line).Matches(`^\[INFO]\[.*\]\[.*\] _app_def\|\|msg=hello world$`)
+	}
+}
@@ -20,12 +20,14 @@ import (
 	"maps"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/go-spring/log/expr"
 	"github.com/go-spring/stdlib/errutil"
 	"github.com/go-spring/stdlib/flatten"
+	"github.com/go-spring/stdlib/ordered"
 )
 
 // RootLoggerName defines the reserved name for the root logger.
@@ -38,6 +40,9 @@ var global struct {
 	mutex     sync.Mutex
 	loggers   []Logger
 	appenders []Appender
+	layouts   []Layout
+	tags      map[string]Logger // loggers by tag and "xxx_*" pattern
+	heartbeat *Heartbeat
 }
 
 // RefreshConfig loads logging configuration from a flat map.
@@ -52,6 +57,33 @@ func RefreshConfig(m map[string]string) error {
 	return Refresh(flatten.NewPropertiesStorage(p))
 }
 
+// RefreshMerged loads logging configuration merged from multiple sources,
+// e.g. a base configuration file followed by environment specific
+// overrides, and applies the result once. Each source is loaded, then
+// expanded like RefreshConfig.
+//
+// Later sources take precedence over earlier ones:
+//   - Leaf values are overridden, e.g. "logger.root.level".
+//   - Maps are merged, so sources may add loggers or appenders.
+//   - Indexed keys, e.g. "appenderRef[0].ref", are overridden as a whole:
+//     the last source defining the array replaces all of its elements.
+func RefreshMerged(sources ...ConfigSource) error {
+	s := &flatten.LayeredStorage{}
+	for i, src := range sources {
+		m, err := src.Load()
+		if err != nil {
+			return errutil.Explain(err, "load source %d error", i)
+		}
+		m, err = parseExpr(m)
+		if err != nil {
+			return errutil.Explain(err, "parse source %d error", i)
+		}
+		p := flatten.NewProperties(m)
+		s.AddStorage(flatten.StorageAppFile, flatten.NewPropertiesStorage(p), strconv.Itoa(i))
+	}
+	return Refresh(s)
+}
+
 // parseExpr expands inline map expressions embedded in values.
 //
 // A key ending with "!" indicates that its value is a map expression.
@@ -99,6 +131,39 @@ func parseExpr(m map[string]string) (map[string]string, error) {
 	return ret, nil
 }
 
+// RefreshOptions controls how a configuration is applied.
+type RefreshOptions struct {
+	// NoStart only constructs and wires the loggers and appenders. They are
+	// neither started nor installed, so no files are opened and no workers
+	// are launched, and the current configuration stays in effect.
+	NoStart bool
+
+	// IgnoreMissingAppenderRefs drops the appender refs of a logger that
+	// name undefined appenders, reporting each of them via ReportError,
+	// instead of failing the refresh. The logger then starts with the refs
+	// that could be resolved.
+	IgnoreMissingAppenderRefs bool
+}
+
+// Components holds the loggers, appenders and heartbeat built from a
+// configuration, keyed by their configured names.
+type Components struct {
+	Loggers   map[string]Logger
+	Appenders map[string]Appender
+	Heartbeat *Heartbeat
+}
+
+// RefreshConfigWithOptions is like RefreshConfig, but applies the
+// configuration according to opts and returns the built components.
+func RefreshConfigWithOptions(m map[string]string, opts RefreshOptions) (*Components, error) {
+	m, err := parseExpr(m)
+	if err != nil {
+		return nil, err
+	}
+	p := flatten.NewProperties(m)
+	return RefreshWithOptions(flatten.NewPropertiesStorage(p), opts)
+}
+
 // Refresh rebuilds all loggers and appenders from the given configuration storage.
 // It replaces the current runtime configuration atomically.
 //
@@ -112,12 +177,26 @@ func parseExpr(m map[string]string) (map[string]string, error) {
 //
 // Returns an error if any step fails.
 func Refresh(s flatten.Storage) error {
+	_, err := RefreshWithOptions(s, RefreshOptions{})
+	return err
+}
+
+// RefreshWithOptions is like Refresh, but applies the configuration
+// according to opts and returns the built components. With NoStart set,
+// it stops after step 3.
+func RefreshWithOptions(s flatten.Storage, opts RefreshOptions) (*Components, error) {
 
 	global.mutex.Lock()
 	defer global.mutex.Unlock()
 
+	// New loggers cannot be obtained while refreshing.
+	loggerMutex.RLock()
+	defer loggerMutex.RUnlock()
+
 	oldLoggers := global.loggers
 	oldAppenders := global.appenders
+	oldLayouts := global.layouts
+	oldHeartbeat := global.heartbeat
 
 	loggerNames := make(map[string]struct{})
 	appenderNames := make(map[string]struct{})
@@ -128,10 +207,14 @@ func Refresh(s flatten.Storage) error {
 	// 	return errutil.Explain(nil, "appenders section not found")
 	// }
 
+	if err := checkRootLogger(s, loggerNames); err != nil {
+		return nil, err
+	}
+
 	// Check logger definitions
 	for _, l := range loggerMap {
 		if _, ok := loggerNames[l.name]; !ok {
-			return errutil.Explain(nil, "logger %s not found", l.name)
+			return nil, errutil.Explain(nil, "logger %s not found", l.name)
 		}
 	}
 
@@ -159,19 +242,14 @@ func Refresh(s flatten.Storage) error {
 	for name := range appenderNames {
 		v, err := newPluginFromType("appender." + name)
 		if err != nil {
-			return errutil.Explain(err, "create appender %s error", name)
+			return nil, errutil.Explain(err, "create appender %s error", name)
 		}
 		cAppenders[name] = v.Interface().(Appender)
 	}
 
 	// initAppenderRefs resolves and injects referenced appenders.
-	initAppenderRefs := func(v reflect.Value) error {
-		i, ok := v.Interface().(AppenderRefs)
-		if !ok {
-			return nil
-		}
-		syncMode, appenderRefs := i.GetAppenderRefs()
-		for _, r := range appenderRefs {
+	initAppenderRefs := func(name string, v reflect.Value) error {
+		resolve := func(r *AppenderRef, syncMode bool) error {
 			a, ok := cAppenders[r.Ref]
 			if !ok {
 				return errutil.Explain(nil, "appender %s not found", r.Ref)
@@ -181,21 +259,58 @@ func Refresh(s flatten.Storage) error {
 				return errutil.Explain(nil, "appender %s is not concurrent-safe", r.Ref)
 			}
 			r.Appender = a // assign resolved appender
+			return nil
+		}
+		if i, ok := v.Interface().(AppenderRefs); ok {
+			syncMode, appenderRefs := i.GetAppenderRefs()
+			var missing []string
+			for _, r := range appenderRefs {
+				if _, ok := cAppenders[r.Ref]; !ok && opts.IgnoreMissingAppenderRefs {
+					missing = append(missing, r.Ref)
+					continue
+				}
+				if err := resolve(r, syncMode); err != nil {
+					return err
+				}
+			}
+			if len(missing) > 0 {
+				i.SetAppenderRefs(slices.DeleteFunc(slices.Clone(appenderRefs), func(r *AppenderRef) bool {
+					return slices.Contains(missing, r.Ref)
+				}))
+				for _, ref := range missing {
+					internalErrorf(nil, "logger %s: appender %s not found, ref skipped", name, ref)
+				}
+			}
+		}
+		// The overflow appender is invoked in the caller goroutines.
+		if i, ok := v.Interface().(OverflowAppenderRef); ok {
+			if r := i.GetOverflowAppenderRef(); r != nil {
+				if err := resolve(r, true); err != nil {
+					return err
+				}
+			}
 		}
 		return nil
 	}
 
-	cLoggers[RootLoggerName] = cRoot
+	// The default logger is not managed by Refresh, so it is
+	// neither started nor stopped here.
 	for name := range loggerNames {
 
 		v, err := newPluginFromType("logger." + name)
 		if err != nil {
-			return errutil.Explain(err, "create logger %s error", name)
+			return nil, errutil.Explain(err, "create logger %s error", name)
+		}
+		if err = initAppenderRefs(name, v); err != nil {
+			return nil, errutil.Explain(err, "init appender refs for logger %s error", name)
 		}
-		if err = initAppenderRefs(v); err != nil {
-			return errutil.Explain(err, "init appender refs for logger %s error", name)
+		if err = checkLoggerLayout(s, v); err != nil {
+			return nil, errutil.Explain(err, "create logger %s error", name)
 		}
 		logger := v.Interface().(Logger)
+		if err = checkLoggerLevel(logger); err != nil {
+			return nil, errutil.Explain(err, "create logger %s error", name)
+		}
 		cLoggers[name] = logger
 
 		// Special handling for root logger
@@ -204,125 +319,425 @@ func Refresh(s flatten.Storage) error {
 			continue
 		}
 
-		var tags []string
-		for _, tag := range logger.GetTags() {
-			if tag = strings.TrimSpace(tag); tag == "" {
-				continue
-			}
-			// Only suffix wildcard patterns like "xxx_*" are allowed.
-			if strings.Contains(tag, "*") {
-				if !strings.HasSuffix(tag, "_*") {
-					err = errutil.Explain(nil, "tag '%s' is invalid", tag)
-					return errutil.Explain(err, "create logger %s error", name)
-				}
-			}
-			tags = append(tags, tag)
-		}
-		if len(tags) == 0 {
-			err = errutil.Explain(nil, "logger must have attribute 'tag'")
-			return errutil.Explain(err, "create logger %s error", name)
+		tags, err := parseLoggerTags(logger.GetTags())
+		if err != nil {
+			return nil, errutil.Explain(err, "create logger %s error", name)
 		}
 
 		// Register tag → logger mapping
 		for _, strTag := range tags {
 			if l, ok := cTags[strTag]; ok && l != logger {
 				err = errutil.Explain(nil, "tag '%s' already config in logger %s", strTag, l)
-				return errutil.Explain(err, "create logger %s error", name)
+				return nil, errutil.Explain(err, "create logger %s error", name)
 			}
 			cTags[strTag] = logger
 		}
 	}
 
+	// Create the optional heartbeat
+	var heartbeat *Heartbeat
+	if s.Exists("heartbeat") {
+		v, err := newPlugin(reflect.TypeFor[Heartbeat](), "heartbeat", s)
+		if err != nil {
+			return nil, errutil.Explain(err, "create heartbeat error")
+		}
+		heartbeat = v.Interface().(*Heartbeat)
+	}
+
+	c := &Components{
+		Loggers:   cLoggers,
+		Appenders: cAppenders,
+		Heartbeat: heartbeat,
+	}
+	if opts.NoStart {
+		return c, nil
+	}
+
 	var (
 		success    bool
 		sLoggers   []Logger
 		sAppenders []Appender
+		sLayouts   []Layout
 	)
 
 	defer func() {
 		if !success {
-			// Stop temp loggers and appenders
-			for _, l := range sLoggers {
-				l.Stop()
-			}
-			for _, a := range sAppenders {
-				a.Stop()
-			}
+			// Stop temp loggers, appenders and layouts
+			stopComponents(sLoggers, sAppenders, sLayouts)
 		}
 	}()
 
+	// Start the layouts before the appenders and loggers using them
+	ensureDefaultStarted()
+	layouts := pluginLayouts(slices.Collect(maps.Values(cLoggers)), slices.Collect(maps.Values(cAppenders)))
+	if err := startLayouts(layouts); err != nil {
+		return nil, errutil.Explain(err, "layout start error")
+	}
+	sLayouts = layouts
+
 	// Start new appenders and loggers
 	for _, a := range cAppenders {
 		if err := a.Start(); err != nil {
-			return errutil.Explain(err, "appender %s start error", a.GetName())
+			return nil, errutil.Explain(err, "appender %s start error", a.GetName())
 		}
 		sAppenders = append(sAppenders, a)
 	}
 	for _, l := range cLoggers {
 		if err := l.Start(); err != nil {
-			return errutil.Explain(err, "logger %s start error", l.GetName())
+			return nil, errutil.Explain(err, "logger %s start error", l.GetName())
 		}
 		sLoggers = append(sLoggers, l)
 	}
+	// The heartbeat is started before binding so that its tag gets bound too.
+	if heartbeat != nil {
+		if err := heartbeat.Start(); err != nil {
+			return nil, errutil.Explain(err, "heartbeat start error")
+		}
+	}
 	success = true
 
 	// Bind named loggers
 	for _, l := range loggerMap {
 		l.logger.Store(&loggerValue{cLoggers[l.name]})
 	}
+	refreshed.Store(true)
 
-	// findLogger selects the most specific logger for a given tag,
-	// falling back hierarchically using "_*" patterns.
-	findLogger := func(tag string) Logger {
-		for {
-			if l, ok := cTags[tag]; ok {
-				return l
-			}
-			tag, _ = strings.CutSuffix(tag, "_*")
-			i := strings.LastIndex(tag, "_")
-			if i <= 0 {
-				return cRoot
-			}
-			tag = tag[:i] + "_*"
-		}
-	}
+	// Capture goroutine IDs before the events reach the new layouts
+	captureGoroutineID.Store(layoutsNeedGoroutineID(layouts))
 
 	// Bind tag-based loggers
+	tagMutex.RLock()
 	for tag, l := range tagRegistry {
-		l.logger.Store(&loggerValue{findLogger(tag)})
+		l.logger.Store(&loggerValue{findLogger(cTags, cRoot, tag)})
 	}
+	tagMutex.RUnlock()
 
 	global.loggers = slices.Collect(maps.Values(cLoggers))
 	global.appenders = slices.Collect(maps.Values(cAppenders))
+	global.layouts = layouts
+	global.tags = cTags
+	global.heartbeat = heartbeat
+
+	// Stop old heartbeat, loggers and appenders
+	if oldHeartbeat != nil {
+		oldHeartbeat.Stop()
+	}
+	stopComponents(oldLoggers, oldAppenders, oldLayouts)
+
+	return c, nil
+}
+
+// findLogger selects the most specific logger for a given tag from the
+// loggers by tag, falling back hierarchically using "_*" patterns, and
+// finally to root.
+func findLogger(tags map[string]Logger, root Logger, tag string) Logger {
+	for {
+		if l, ok := tags[tag]; ok {
+			return l
+		}
+		tag, _ = strings.CutSuffix(tag, "_*")
+		i := strings.LastIndex(tag, "_")
+		if i <= 0 {
+			return root
+		}
+		tag = tag[:i] + "_*"
+	}
+}
 
-	// Stop old loggers and appenders
-	for _, l := range oldLoggers {
+// stopComponents stops the loggers, then the appenders, then the layouts.
+// Every logger has returned from Stop before the first appender is stopped,
+// and Stop only returns once the logger no longer writes to its appenders,
+// e.g. once an AsyncLogger has drained its buffer. So no event is written
+// to an appender that is already stopped, such as a closed file, nor
+// encoded with a stopped layout.
+func stopComponents(loggers []Logger, appenders []Appender, layouts []Layout) {
+	for _, l := range loggers {
 		l.Stop()
 	}
-	for _, a := range oldAppenders {
+	for _, a := range appenders {
 		a.Stop()
 	}
+	stopLayouts(layouts)
+}
+
+// pluginLayouts returns the layouts held by the loggers and appenders,
+// including those nested in their elements, e.g. the layout of an
+// AppenderRef or of the appender wrapped by an AsyncAppender. Each layout
+// is returned once, and DefaultLayout, which is shared, is left out.
+func pluginLayouts(loggers []Logger, appenders []Appender) []Layout {
+	var layouts []Layout
+	seen := make(map[any]struct{})
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Pointer:
+			if v.IsNil() {
+				return
+			}
+			p := v.Interface()
+			if _, ok := seen[p]; ok {
+				return
+			}
+			seen[p] = struct{}{}
+			if l, ok := p.(Layout); ok && l != DefaultLayout {
+				layouts = append(layouts, l)
+			}
+			walk(v.Elem())
+		case reflect.Struct:
+			for i := range v.NumField() {
+				if v.Type().Field(i).IsExported() {
+					walk(v.Field(i))
+				}
+			}
+		case reflect.Slice:
+			for i := range v.Len() {
+				walk(v.Index(i))
+			}
+		default: // for linter
+		}
+	}
+	for _, l := range loggers {
+		walk(reflect.ValueOf(l))
+	}
+	for _, a := range appenders {
+		walk(reflect.ValueOf(a))
+	}
+	return layouts
+}
 
+// checkRootLogger checks that the root logger, which handles all
+// unmatched tags, doesn't declare tags.
+func checkRootLogger(s flatten.Storage, loggerNames map[string]struct{}) error {
+	if _, ok := loggerNames[RootLoggerName]; ok {
+		if s.Exists("logger." + RootLoggerName + ".tag") {
+			return errutil.Explain(nil, "logger %s must not have attribute 'tag'", RootLoggerName)
+		}
+	}
 	return nil
 }
 
+// checkLoggerLayout checks that a logger with its own layout doesn't
+// reference an appender with an explicitly configured layout, since the
+// appender would write the events rendered by the logger, see LayoutLogger.
+// A ref with its own layout overrides both, so it is not checked.
+func checkLoggerLayout(s flatten.Storage, v reflect.Value) error {
+	l, ok := v.Interface().(LayoutLogger)
+	if !ok || l.GetLayout() == nil {
+		return nil
+	}
+	i, ok := v.Interface().(AppenderRefs)
+	if !ok {
+		return nil
+	}
+	_, appenderRefs := i.GetAppenderRefs()
+	for _, r := range appenderRefs {
+		if r.Layout == nil && s.Exists("appender."+r.Ref+".layout") {
+			return errutil.Explain(nil, "both the logger and appender %s have a layout", r.Ref)
+		}
+	}
+	return nil
+}
+
+// checkLoggerLevel checks that a logger can log at least one level, so
+// that a misconfigured level doesn't silently drop all its events. When
+// the logger has appender refs, at least one of them must receive some
+// level, see EffectiveRanges.
+func checkLoggerLevel(l Logger) error {
+	if l.GetLevel().isEmpty() {
+		return errutil.Explain(nil, "level range enables no level")
+	}
+	ranges := EffectiveRanges(l)
+	if len(ranges) == 0 {
+		return nil
+	}
+	for _, r := range ranges {
+		if !r.isEmpty() {
+			return nil
+		}
+	}
+	return errutil.Explain(nil, "level range doesn't overlap the level of any appender ref")
+}
+
+// parseLoggerTags trims the tags of a logger and checks that there is at
+// least one tag, and that wildcards are only used as a "_*" suffix.
+func parseLoggerTags(loggerTags []string) ([]string, error) {
+	var tags []string
+	for _, tag := range loggerTags {
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+		// Only suffix wildcard patterns like "xxx_*" are allowed.
+		if strings.Contains(tag, "*") {
+			if !strings.HasSuffix(tag, "_*") {
+				return nil, errutil.Explain(nil, "tag '%s' is invalid", tag)
+			}
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) == 0 {
+		return nil, errutil.Explain(nil, "logger must have attribute 'tag'")
+	}
+	return tags, nil
+}
+
+// LoggerNames returns the sorted names of the loggers currently in use.
+func LoggerNames() []string {
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+	var names []string
+	for _, l := range global.loggers {
+		if name := l.GetName(); name != "" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// AppenderNames returns the sorted names of the appenders currently in use.
+func AppenderNames() []string {
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+	var names []string
+	for _, a := range global.appenders {
+		if name := a.GetName(); name != "" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SetLevels atomically replaces the level ranges of running loggers in
+// place, without restarting them or their appenders and without parsing
+// the configuration again. Each key is either the name of a configured
+// logger, or a registered tag, which selects the logger the tag is routed
+// to. A tag routed to the built-in default logger is rejected, and so are
+// two keys that select the same logger. All keys are checked before any
+// level is changed. The levels last until the next Refresh, which restores
+// the configured ones.
+func SetLevels(levels map[string]LevelRange) error {
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+
+	type levelSetter interface {
+		SetLevel(LevelRange)
+	}
+
+	loggers := make(map[string]Logger)
+	for _, l := range global.loggers {
+		loggers[l.GetName()] = l
+	}
+
+	setters := make(map[string]levelSetter)
+	selected := make(map[Logger]string)
+	for _, key := range ordered.MapKeys(levels) {
+		l, ok := loggers[key]
+		if !ok {
+			tagMutex.RLock()
+			t, found := tagRegistry[key]
+			tagMutex.RUnlock()
+			if !found {
+				return errutil.Explain(nil, "logger or tag %s not found", key)
+			}
+			l = t.logger.Load().Logger
+			if l == nil || !slices.Contains(global.loggers, l) {
+				return errutil.Explain(nil, "tag %s is not routed to a configured logger", key)
+			}
+		}
+		if prev, ok := selected[l]; ok {
+			return errutil.Explain(nil, "%s and %s select the same logger", prev, key)
+		}
+		selected[l] = key
+		ls, ok := l.(levelSetter)
+		if !ok {
+			return errutil.Explain(nil, "logger of %s doesn't support setting levels", key)
+		}
+		setters[key] = ls
+	}
+
+	for key, ls := range setters {
+		ls.SetLevel(levels[key])
+	}
+	return nil
+}
+
+// DiscardCounter is implemented by loggers and appenders that may
+// discard events, e.g. AsyncLogger when its buffer is full.
+type DiscardCounter interface {
+	GetDiscardCounter() int64
+}
+
+// TotalDiscarded returns the number of events discarded so far by the
+// loggers and appenders of the current configuration, as a single
+// health metric. The counts of replaced configurations are not included.
+func TotalDiscarded() int64 {
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+	var n int64
+	for _, l := range global.loggers {
+		if c, ok := l.(DiscardCounter); ok {
+			n += c.GetDiscardCounter()
+		}
+	}
+	for _, a := range global.appenders {
+		if c, ok := a.(DiscardCounter); ok {
+			n += c.GetDiscardCounter()
+		}
+	}
+	return n
+}
+
+// flushLoggers waits until the async loggers have written the events
+// buffered so far, and syncs the appenders implementing SyncAppender.
+// Unlike Destroy, the loggers and appenders keep running.
+func flushLoggers() {
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+
+	for _, l := range global.loggers {
+		if c, ok := l.(*AsyncLogger); ok {
+			c.Flush()
+		}
+	}
+	for _, a := range global.appenders {
+		if c, ok := a.(SyncAppender); ok {
+			if err := c.Sync(); err != nil {
+				internalErrorf(err, "sync appender %s error", a.GetName())
+			}
+		}
+	}
+}
+
 // Destroy gracefully shuts down all loggers and appenders,
-// releases resources, and resets global state.
+// releases resources, and resets global state. It is safe to call
+// Destroy concurrently and more than once, e.g. from a signal handler
+// and a deferred call; the calls after the first do nothing.
 func Destroy() {
 	global.mutex.Lock()
 	defer global.mutex.Unlock()
 
+	if global.heartbeat != nil {
+		global.heartbeat.Stop()
+		global.heartbeat = nil
+	}
+
+	tagMutex.RLock()
 	for _, obj := range tagRegistry {
 		obj.reset()
 	}
+	tagMutex.RUnlock()
 
 	// Stop all loggers and appenders
-	for _, l := range global.loggers {
-		l.Stop()
-	}
-	for _, a := range global.appenders {
-		a.Stop()
-	}
+	stopComponents(global.loggers, global.appenders, global.layouts)
 	global.loggers = nil
 	global.appenders = nil
+	global.layouts = nil
+	global.tags = nil
+	captureGoroutineID.Store(false)
+	refreshed.Store(false)
 }
@@ -16,6 +16,17 @@
 
 package log
 
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-spring/stdlib/testing/assert"
+)
+
 //func TestRefreshFile(t *testing.T) {
 //	t.Cleanup(func() {
 //		for _, tag := range tagRegistry {
@@ -243,3 +254,232 @@ package log
 //	})
 //
 //}
+
+func TestDestroyConcurrently(t *testing.T) {
+	err := RefreshConfig(map[string]string{
+		"appender.file.type":          "FileAppender",
+		"appender.file.dir":           t.TempDir(),
+		"appender.file.file":          "app.log",
+		"logger.root.type":            "AsyncLogger",
+		"logger.root.bufferSize":      "100",
+		"logger.root.appenderRef.ref": "file",
+		"logger.myLogger.type":        "RollingFileLogger",
+		"logger.myLogger.tag":         "_com_*",
+		"logger.myLogger.dir":         t.TempDir(),
+		"logger.myLogger.file":        "rolling.log",
+		"logger.myLogger.async":       "true",
+		"logger.myLogger.bufferSize":  "100",
+	})
+	assert.Error(t, err).Nil()
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(Destroy)
+	}
+	wg.Wait()
+	Destroy()
+
+	assert.That(t, LoggerNames()).Nil()
+	assert.That(t, AppenderNames()).Nil()
+
+	t.Run("async logger", func(t *testing.T) {
+		l := &AsyncLogger{BufferSize: 100}
+		l.Stop() // not started
+
+		err := l.Start()
+		assert.Error(t, err).Nil()
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(l.Stop)
+		}
+		wg.Wait()
+		l.Stop()
+	})
+}
+
+func TestSetLevels(t *testing.T) {
+	dir := t.TempDir()
+	err := RefreshConfig(map[string]string{
+		"appender.file.type":              "FileAppender",
+		"appender.file.dir":               dir,
+		"appender.file.file":              "app.log",
+		"logger.root.type":                "Logger",
+		"logger.root.level":               "warn",
+		"logger.root.appenderRef.ref":     "file",
+		"logger.myLogger.type":            "Logger",
+		"logger.myLogger.tag":             "_com_request_*",
+		"logger.myLogger.level":           "error",
+		"logger.myLogger.appenderRef.ref": "file",
+	})
+	assert.Error(t, err).Nil()
+	defer Destroy()
+
+	a := global.appenders[0].(*FileAppender)
+	file := a.file.Load()
+
+	ctx := context.Background()
+	tagRequestIn := RegisterTag("_com_request_in")
+	Record(ctx, InfoLevel, TagAppDef, 1, Msg("suppressed"))
+	Record(ctx, InfoLevel, tagRequestIn, 1, Msg("suppressed"))
+
+	err = SetLevels(map[string]LevelRange{
+		"root":    {MinLevel: InfoLevel, MaxLevel: MaxLevel},
+		"unknown": {MinLevel: InfoLevel, MaxLevel: MaxLevel},
+	})
+	assert.Error(t, err).Matches("logger or tag unknown not found")
+	assert.That(t, Enabled(ctx, TagAppDef, InfoLevel)).False()
+
+	err = SetLevels(map[string]LevelRange{
+		"myLogger":        {MinLevel: InfoLevel, MaxLevel: MaxLevel},
+		"_com_request_in": {MinLevel: DebugLevel, MaxLevel: MaxLevel},
+	})
+	assert.Error(t, err).Matches("_com_request_in and myLogger select the same logger")
+	assert.That(t, Enabled(ctx, tagRequestIn, InfoLevel)).False()
+
+	err = SetLevels(map[string]LevelRange{
+		"root":            {MinLevel: InfoLevel, MaxLevel: MaxLevel},
+		"_com_request_in": {MinLevel: DebugLevel, MaxLevel: MaxLevel},
+	})
+	assert.Error(t, err).Nil()
+	assert.That(t, Enabled(ctx, TagAppDef, InfoLevel)).True()
+	assert.That(t, Enabled(ctx, tagRequestIn, DebugLevel)).True()
+
+	Record(ctx, InfoLevel, TagAppDef, 1, Msg("app"))
+	Record(ctx, DebugLevel, tagRequestIn, 1, Msg("request"))
+
+	// The file is neither reopened nor replaced.
+	assert.That(t, a.file.Load() == file).True()
+	b, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	assert.Error(t, err).Nil()
+	assert.String(t, string(b)).Matches(`(?s)^\[INFO\].*msg=app\n\[DEBUG\].*msg=request\n$`)
+
+	// once destroyed, the tags fall back to the default logger, whose
+	// level would outlive the next Refresh
+	Destroy()
+	err = SetLevels(map[string]LevelRange{
+		"_com_request_in": {MinLevel: DebugLevel, MaxLevel: MaxLevel},
+	})
+	assert.Error(t, err).Matches("tag _com_request_in is not routed to a configured logger")
+}
+
+func TestDestroyDrainsLoggersFirst(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		errs  []error
+	)
+	reportError := ReportError
+	ReportError = func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		errs = append(errs, err)
+	}
+	defer func() { ReportError = reportError }()
+
+	dir := t.TempDir()
+	err := RefreshConfig(map[string]string{
+		"appender.file.type":              "FileAppender",
+		"appender.file.dir":               dir,
+		"appender.file.file":              "app.log",
+		"logger.root.type":                "AsyncLogger",
+		"logger.root.level":               "info",
+		"logger.root.bufferSize":          "10000",
+		"logger.root.appenderRef.ref":     "file",
+		"logger.myLogger.type":            "Logger",
+		"logger.myLogger.tag":             "_com_request_*",
+		"logger.myLogger.appenderRef.ref": "file",
+	})
+	assert.Error(t, err).Nil()
+
+	// fill the buffer so that the worker is still writing when Destroy starts
+	const n = 5000
+	for i := range n {
+		Infof(t.Context(), TagAppDef, "event %d", i)
+	}
+	Destroy()
+
+	// nothing was written to the closed file, and nothing was lost
+	assert.That(t, errs).Nil()
+	b, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	assert.Error(t, err).Nil()
+	assert.Number(t, bytes.Count(b, []byte("\n"))).Equal(n)
+}
+
+// lifecycleLayout records how many times it has been started and stopped.
+type lifecycleLayout struct {
+	TextLayout
+	started int
+	stopped int
+}
+
+func (c *lifecycleLayout) Start() error {
+	c.started++
+	return nil
+}
+
+func (c *lifecycleLayout) Stop() {
+	c.stopped++
+}
+
+func init() {
+	RegisterPlugin[lifecycleLayout]("LifecycleLayout")
+}
+
+func TestRefreshLayoutLifecycle(t *testing.T) {
+	m := map[string]string{
+		"appender.console.type":                      "ConsoleAppender",
+		"appender.console.layout.type":               "LifecycleLayout",
+		"appender.plain.type":                        "DiscardAppender",
+		"logger.root.type":                           "Logger",
+		"logger.root.layout.type":                    "LifecycleLayout",
+		"logger.root.appenderRef.ref":                "plain",
+		"logger.myLogger.type":                       "Logger",
+		"logger.myLogger.tag":                        "_com_request_*",
+		"logger.myLogger.appenderRef[0].ref":         "console",
+		"logger.myLogger.appenderRef[1].ref":         "plain",
+		"logger.myLogger.appenderRef[1].layout.type": "LifecycleLayout",
+	}
+
+	layouts := func(c *Components) []*lifecycleLayout {
+		ref := c.Loggers["myLogger"].(*SyncLogger).AppenderRefs[1]
+		return []*lifecycleLayout{
+			c.Appenders["console"].(*ConsoleAppender).Layout.(*lifecycleLayout),
+			c.Loggers["root"].(*SyncLogger).Layout.(*lifecycleLayout),
+			ref.Layout.(*lifecycleLayout),
+		}
+	}
+
+	t.Run("no start", func(t *testing.T) {
+		c, err := RefreshConfigWithOptions(m, RefreshOptions{NoStart: true})
+		assert.Error(t, err).Nil()
+		for _, l := range layouts(c) {
+			assert.Number(t, l.started).Equal(0)
+		}
+	})
+
+	t.Run("start and stop", func(t *testing.T) {
+		c, err := RefreshConfigWithOptions(m, RefreshOptions{})
+		assert.Error(t, err).Nil()
+		for _, l := range layouts(c) {
+			assert.Number(t, l.started).Equal(1)
+			assert.Number(t, l.stopped).Equal(0)
+		}
+		Destroy()
+		for _, l := range layouts(c) {
+			assert.Number(t, l.stopped).Equal(1)
+		}
+	})
+
+	t.Run("start error", func(t *testing.T) {
+		err := RefreshConfig(map[string]string{
+			"appender.console.type":              "ConsoleAppender",
+			"appender.console.layout.type":       "CloudEventsLayout",
+			"appender.console.layout.source":     "",
+			"logger.root.type":                   "Logger",
+			"logger.root.appenderRef.ref":        "console",
+			"logger.myLogger.type":               "Logger",
+			"logger.myLogger.tag":                "_com_request_*",
+			"logger.myLogger.appenderRef[0].ref": "console",
+		})
+		assert.Error(t, err).Matches("layout start error: cloudevents source is empty")
+	})
+}
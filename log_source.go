@@ -0,0 +1,105 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-spring/stdlib/errutil"
+	"github.com/go-spring/stdlib/flatten"
+)
+
+// ConfigSource provides a flat configuration map, like the one accepted
+// by RefreshConfig, e.g. to be merged with others by RefreshMerged.
+type ConfigSource interface {
+	Load() (map[string]string, error)
+}
+
+// MapSource is a ConfigSource for a flat map.
+type MapSource map[string]string
+
+// Load returns the map itself.
+func (s MapSource) Load() (map[string]string, error) {
+	return s, nil
+}
+
+// sourceFunc adapts a function to a ConfigSource.
+type sourceFunc func() (map[string]string, error)
+
+func (f sourceFunc) Load() (map[string]string, error) {
+	return f()
+}
+
+// ReaderSource returns a ConfigSource that reads the configuration from r
+// in the given format, see FileSource. The reader is consumed on Load.
+func ReaderSource(r io.Reader, format string) ConfigSource {
+	return sourceFunc(func() (map[string]string, error) {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, errutil.Explain(err, "read config error")
+		}
+		return parseConfig(b, format)
+	})
+}
+
+// FileSource returns a ConfigSource that reads the configuration from the
+// file, whose format is given by its extension:
+//   - "json": nested objects and arrays, flattened into keys such as
+//     "logger.root.appenderRef[0].ref".
+//   - "properties": one key=value per line, where blank lines and lines
+//     starting with '#' or '!' are ignored.
+func FileSource(path string) ConfigSource {
+	return sourceFunc(func() (map[string]string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errutil.Explain(err, "read config error")
+		}
+		return parseConfig(b, strings.TrimPrefix(filepath.Ext(path), "."))
+	})
+}
+
+// parseConfig parses the configuration in the given format into a flat map.
+func parseConfig(b []byte, format string) (map[string]string, error) {
+	switch format {
+	case "json":
+		var m map[string]any
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, errutil.Explain(err, "parse json error")
+		}
+		return flatten.Flatten(m), nil
+	case "properties":
+		m := make(map[string]string)
+		for i, line := range strings.Split(string(b), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || line[0] == '#' || line[0] == '!' {
+				continue
+			}
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, errutil.Explain(nil, "invalid properties line %d: %q", i+1, line)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		return m, nil
+	default:
+		return nil, errutil.Explain(nil, "unsupported config format %q", format)
+	}
+}
@@ -19,15 +19,20 @@ package log
 import (
 	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/go-spring/stdlib/ordered"
 )
 
-// tagRegistry stores Tag instances keyed by their string names.
-// Note: This registry is not concurrency-safe and is intended to be
-// used only during the initialization phase.
-var tagRegistry = map[string]*Tag{}
+var (
+	// tagMutex guards tagRegistry, since tags may be registered from
+	// init functions of packages that are initialized concurrently.
+	tagMutex sync.RWMutex
+
+	// tagRegistry stores Tag instances keyed by their string names.
+	tagRegistry = map[string]*Tag{}
+)
 
 // loggerValue wraps a Logger instance.
 type loggerValue struct {
@@ -38,8 +43,9 @@ type loggerValue struct {
 // subsystem, business domain, RPC interaction, and so on.
 // Each Tag maintains a reference to a Logger instance.
 type Tag struct {
-	tag    string
-	logger atomic.Pointer[loggerValue]
+	tag      string
+	logger   atomic.Pointer[loggerValue]
+	defaults atomic.Pointer[[]Field]
 }
 
 // reset resets the logger associated with the tag.
@@ -47,8 +53,25 @@ func (t *Tag) reset() {
 	t.logger.Store(&loggerValue{})
 }
 
+// WithDefaults sets the default fields of the tag, which are added to every
+// event logged with it, before the bound fields and the fields of the call.
+// It replaces the previous defaults, and no fields remove them. Since tags
+// are shared, the defaults apply to all the users of the tag. It returns
+// the tag, so that it can be chained with RegisterTag.
+func (t *Tag) WithDefaults(fields ...Field) *Tag {
+	if len(fields) == 0 {
+		t.defaults.Store(nil)
+		return t
+	}
+	fields = slices.Clone(fields)
+	t.defaults.Store(&fields)
+	return t
+}
+
 // GetAllTags returns the names of all registered tags.
 func GetAllTags() []string {
+	tagMutex.RLock()
+	defer tagMutex.RUnlock()
 	return ordered.MapKeys(tagRegistry)
 }
 
@@ -91,6 +114,8 @@ func RegisterTag(tag string) *Tag {
 	if !isValidTag(tag) {
 		panic("invalid log tag")
 	}
+	tagMutex.Lock()
+	defer tagMutex.Unlock()
 	m, ok := tagRegistry[tag]
 	if !ok {
 		m = &Tag{tag: tag}
@@ -100,6 +125,31 @@ func RegisterTag(tag string) *Tag {
 	return m
 }
 
+// TagNamespace registers tags under a common prefix, so that libraries
+// sharing the global tag registry don't collide on the same tag names.
+type TagNamespace struct {
+	prefix string
+}
+
+// NewTagNamespace creates a TagNamespace with the given prefix. The prefix
+// becomes the leading segment of every tag registered through it, so it
+// must consist of lowercase letters and digits only.
+func NewTagNamespace(prefix string) *TagNamespace {
+	if prefix == "" || strings.ContainsFunc(prefix, func(c rune) bool {
+		return (c < 'a' || c > 'z') && (c < '0' || c > '9')
+	}) {
+		panic("invalid tag namespace")
+	}
+	return &TagNamespace{prefix: prefix}
+}
+
+// Register retrieves or creates the Tag named "_<prefix>_<tag>". A leading
+// underscore in tag is ignored. The resulting name must still satisfy the
+// tag format rules, which leaves room for at most three segments in tag.
+func (ns *TagNamespace) Register(tag string) *Tag {
+	return RegisterTag("_" + ns.prefix + "_" + strings.TrimPrefix(tag, "_"))
+}
+
 // BuildTag constructs a structured tag string from mainType, subType,
 // and an optional action.
 //
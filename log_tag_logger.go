@@ -0,0 +1,28 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+// TagLogger is a facade bound to a Tag, so that callers do not need to
+// pass the tag on every call. It behaves exactly like the package-level
+// functions called with the same tag, and routes to the same logger.
+// It is a BoundLogger without bound fields.
+type TagLogger = BoundLogger
+
+// Logger returns a TagLogger bound to the tag.
+func (t *Tag) Logger() TagLogger {
+	return BoundLogger{tag: t}
+}
@@ -17,7 +17,9 @@
 package log
 
 import (
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/go-spring/stdlib/testing/assert"
@@ -160,3 +162,64 @@ func TestRegisterTags(t *testing.T) {
 	assert.That(t, tag2).NotNil()
 	assert.String(t, tag2.tag).Equal("_rpc_http")
 }
+
+func TestRegisterTagConcurrently(t *testing.T) {
+	var names []string
+	for i := range 10 {
+		names = append(names, BuildTag("test", "concurrent", strconv.Itoa(i)))
+	}
+	defer func() {
+		for _, name := range names {
+			delete(tagRegistry, name)
+		}
+	}()
+
+	const n = 8
+	var wg sync.WaitGroup
+	results := make([][]*Tag, n)
+	for i := range n {
+		wg.Go(func() {
+			for _, name := range names {
+				results[i] = append(results[i], RegisterTag(name))
+			}
+			_ = GetAllTags()
+		})
+	}
+	wg.Wait()
+
+	for i := 1; i < n; i++ {
+		assert.That(t, results[i]).Equal(results[0])
+	}
+}
+
+func TestTagNamespace(t *testing.T) {
+	defer func() {
+		for _, name := range []string{"_liba_com_request_in", "_libb_com_request_in"} {
+			delete(tagRegistry, name)
+		}
+	}()
+
+	a := NewTagNamespace("liba")
+	b := NewTagNamespace("libb")
+
+	tagA := a.Register("_com_request_in")
+	tagB := b.Register("com_request_in")
+	assert.String(t, tagA.tag).Equal("_liba_com_request_in")
+	assert.String(t, tagB.tag).Equal("_libb_com_request_in")
+	assert.That(t, tagA != tagB).True()
+	assert.That(t, isValidTag(tagA.tag)).True()
+	assert.That(t, isValidTag(tagB.tag)).True()
+
+	// the same namespace returns the same tag
+	assert.That(t, a.Register("com_request_in")).Equal(tagA)
+
+	assert.Panic(t, func() {
+		NewTagNamespace("")
+	}, "invalid tag namespace")
+	assert.Panic(t, func() {
+		NewTagNamespace("lib_a")
+	}, "invalid tag namespace")
+	assert.Panic(t, func() {
+		a.Register("a_b_c_d")
+	}, "invalid log tag")
+}
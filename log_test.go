@@ -252,3 +252,674 @@ this message is written directly
 	expectLog = strings.ReplaceAll(expectLog, "<<file>>", currFile)
 	assert.String(t, string(b)).Equal(strings.TrimLeft(expectLog, "\n"))
 }
+
+func TestEnabled(t *testing.T) {
+	ctx := t.Context()
+
+	// default logger is enabled from INFO
+	assert.That(t, log.Enabled(ctx, TagDefault, log.DebugLevel)).False()
+	assert.That(t, log.Enabled(ctx, TagDefault, log.InfoLevel)).True()
+
+	err := log.RefreshConfig(readConfig())
+	assert.Error(t, err).Nil()
+	defer log.Destroy()
+
+	// root logger is enabled from WARN
+	assert.That(t, log.Enabled(ctx, TagDefault, log.InfoLevel)).False()
+	assert.That(t, log.Enabled(ctx, TagDefault, log.WarnLevel)).True()
+
+	// myLogger is enabled from TRACE
+	assert.That(t, log.Enabled(ctx, TagRequestOut, log.NoneLevel)).False()
+	assert.That(t, log.Enabled(ctx, TagRequestOut, log.TraceLevel)).True()
+	assert.That(t, log.Enabled(ctx, TagRequestIn, log.DebugLevel)).True()
+}
+
+func TestSetDefaultLogger(t *testing.T) {
+	ctx := t.Context()
+
+	logBuf := bytes.NewBuffer(nil)
+	log.Stdout = logBuf
+	defer func() {
+		log.Stdout = os.Stdout
+	}()
+
+	log.TimeNow = func(ctx context.Context) time.Time {
+		return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	}
+	log.FieldsFromContext = nil
+	defer func() {
+		log.TimeNow = nil
+	}()
+
+	err := log.SetDefaultLogger(nil)
+	assert.Error(t, err).Matches("default logger is nil")
+
+	err = log.SetDefaultLogger(&log.AsyncLogger{BufferSize: 10})
+	assert.Error(t, err).Matches("start default logger error: bufferSize is too small")
+
+	err = log.SetDefaultLogger(&log.ConsoleLogger{
+		LoggerBase: log.LoggerBase{
+			Level: log.LevelRange{MinLevel: log.WarnLevel, MaxLevel: log.MaxLevel},
+		},
+		Layout: &log.JSONLayout{},
+	})
+	assert.Error(t, err).Nil()
+	defer func() {
+		err = log.SetDefaultLogger(&log.ConsoleLogger{
+			LoggerBase: log.LoggerBase{
+				Level: log.LevelRange{MinLevel: log.InfoLevel, MaxLevel: log.MaxLevel},
+			},
+			Layout: &log.TextLayout{
+				BaseLayout: log.BaseLayout{FileLineMaxLength: 48},
+			},
+		})
+		assert.Error(t, err).Nil()
+	}()
+
+	log.Info(ctx, TagDefault, log.Msg("not print"))
+	log.Warn(ctx, TagDefault, log.Msg("hello world"))
+	assert.String(t, logBuf.String()).Matches(`^{"level":"warn","time":"2025-06-01T00:00:00.000",.*"tag":"_def","msg":"hello world"}\n$`)
+
+	err = log.RefreshConfig(readConfig())
+	assert.Error(t, err).Nil()
+	err = log.SetDefaultLogger(&log.DiscardLogger{})
+	assert.Error(t, err).Matches("default logger must be set before refresh")
+	log.Destroy()
+}
+
+func TestTagLogger(t *testing.T) {
+	ctx := t.Context()
+
+	logBuf := bytes.NewBuffer(nil)
+	log.Stdout = logBuf
+	log.FieldsFromContext = nil
+	defer func() {
+		log.Stdout = os.Stdout
+	}()
+
+	l := TagDefault.Logger()
+	assert.That(t, l.Tag()).Equal(TagDefault)
+
+	// default logger is enabled from INFO
+	assert.That(t, l.Enabled(ctx, log.DebugLevel)).False()
+	assert.That(t, l.Enabled(ctx, log.InfoLevel)).True()
+
+	l.Debugf(ctx, "not print")
+	l.Infof(ctx, "hello %s", "world")
+	l.Warn(ctx, log.Msg("hello world"), log.Int("code", 1))
+	assert.String(t, logBuf.String()).Matches(
+		`^\[INFO]\[.*]\[.*log_test.go:\d+] _def\|\|msg=hello world\n` +
+			`\[WARN]\[.*]\[.*log_test.go:\d+] _def\|\|msg=hello world\|\|code=1\n$`)
+
+	err := log.RefreshConfig(readConfig())
+	assert.Error(t, err).Nil()
+	defer log.Destroy()
+
+	// routes to the same logger as the free functions
+	for _, tag := range []*log.Tag{TagDefault, TagRequestIn, TagRequestOut} {
+		for _, level := range []log.Level{log.TraceLevel, log.InfoLevel, log.WarnLevel} {
+			expect := log.Enabled(ctx, tag, level)
+			assert.That(t, tag.Logger().Enabled(ctx, level)).Equal(expect)
+		}
+	}
+}
+
+func TestRefreshMerged(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("parse error", func(t *testing.T) {
+		err := log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+			"logger.root!": "{",
+		})
+		assert.Error(t, err).Matches("parse source 1 error: parseExpr error")
+	})
+
+	t.Run("override", func(t *testing.T) {
+		override := flatten.NewProperties(nil)
+		override.Set("logger.root.level", "info")
+		override.Set("logger.myLogger.appenderRef[0].ref", "file")
+		override.Set("appender.discard.type", "DiscardAppender")
+
+		err := log.RefreshMerged(log.MapSource(readConfig()), log.MapSource(override.Data()))
+		assert.Error(t, err).Nil()
+		defer log.Destroy()
+
+		// root logger is bumped from WARN to INFO
+		assert.That(t, log.Enabled(ctx, TagDefault, log.DebugLevel)).False()
+		assert.That(t, log.Enabled(ctx, TagDefault, log.InfoLevel)).True()
+
+		// myLogger is kept as it is
+		assert.That(t, log.Enabled(ctx, TagRequestIn, log.TraceLevel)).True()
+	})
+
+	t.Run("array override", func(t *testing.T) {
+		logBuf := bytes.NewBuffer(nil)
+		log.Stdout = logBuf
+		stdout, err := os.CreateTemp(t.TempDir(), "stdout")
+		assert.Error(t, err).Nil()
+		osStdout := os.Stdout
+		os.Stdout = stdout // SampleAppender writes to os.Stdout
+		defer func() {
+			os.Stdout = osStdout
+			log.Stdout = os.Stdout
+		}()
+
+		// myLogger refers to file and sample in the base configuration
+		err = log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+			"logger.myLogger.appenderRef[0].ref": "console",
+		})
+		assert.Error(t, err).Nil()
+
+		log.Infof(ctx, TagRequestIn, "merged")
+		log.Destroy()
+
+		assert.String(t, logBuf.String()).Matches(`^\[INFO].* _com_request_in\|\|.*msg=merged\n$`)
+		b, err := os.ReadFile(stdout.Name())
+		assert.Error(t, err).Nil()
+		assert.String(t, string(b)).Equal("")
+
+		err = log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+			"logger.myLogger.appenderRef[0].ref": "console",
+			"logger.myLogger.appenderRef[1].ref": "not-exist",
+		})
+		assert.Error(t, err).Matches("appender not-exist not found")
+	})
+
+	t.Run("sources", func(t *testing.T) {
+		dir := t.TempDir()
+		base := dir + "/log.json"
+		err := os.WriteFile(base, []byte(`{
+		  "appender": {"console": {"type": "ConsoleAppender", "layout": {"type": "TextLayout"}}},
+		  "logger": {
+		    "root": {"type": "Logger", "level": "warn", "appenderRef": {"ref": "console"}},
+		    "myLogger": {
+		      "type": "Logger",
+		      "level": "info",
+		      "tag": "_com_request_*",
+		      "appenderRef": [{"ref": "console"}]
+		    }
+		  }
+		}`), 0o644)
+		assert.Error(t, err).Nil()
+
+		override := log.ReaderSource(strings.NewReader(`
+			# bump the root logger
+			logger.root.level = debug
+		`), "properties")
+
+		err = log.RefreshMerged(log.FileSource(base), override)
+		assert.Error(t, err).Nil()
+		assert.That(t, log.Enabled(ctx, TagDefault, log.TraceLevel)).False()
+		assert.That(t, log.Enabled(ctx, TagDefault, log.DebugLevel)).True()
+		assert.That(t, log.Enabled(ctx, TagRequestIn, log.DebugLevel)).False()
+		log.Destroy()
+
+		err = log.RefreshMerged(log.FileSource(dir + "/not-exist.json"))
+		assert.Error(t, err).Matches("load source 0 error: read config error")
+
+		err = log.RefreshMerged(log.FileSource(base), log.ReaderSource(strings.NewReader("level"), "properties"))
+		assert.Error(t, err).Matches(`load source 1 error: invalid properties line 1: "level"`)
+
+		err = log.RefreshMerged(log.ReaderSource(strings.NewReader("{}"), "yaml"))
+		assert.Error(t, err).Matches(`load source 0 error: unsupported config format "yaml"`)
+	})
+}
+
+func TestSuppress(t *testing.T) {
+	ctx := t.Context()
+
+	logBuf := bytes.NewBuffer(nil)
+	log.Stdout = logBuf
+	log.FieldsFromContext = nil
+	defer func() {
+		log.Stdout = os.Stdout
+	}()
+
+	restore1 := log.Suppress()
+	log.Infof(ctx, TagDefault, "muted 1")
+
+	restore2 := log.Suppress()
+	log.Infof(ctx, TagDefault, "muted 2")
+	restore2()
+	restore2() // no effect
+	log.Infof(ctx, TagDefault, "muted 3")
+
+	restore1()
+	log.Infof(ctx, TagDefault, "resumed")
+
+	assert.String(t, logBuf.String()).Matches(`^\[INFO].* _def\|\|msg=resumed\n$`)
+}
+
+func TestSugared(t *testing.T) {
+	ctx := t.Context()
+
+	logBuf := bytes.NewBuffer(nil)
+	log.Stdout = logBuf
+	log.FieldsFromContext = nil
+	defer func() {
+		log.Stdout = os.Stdout
+	}()
+
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	log.Debugw(ctx, TagDefault, "not print", "k", "v")
+	log.Infow(ctx, TagDefault, "even", "id", 1, "ok", true, "user", user{Name: "tom"}, "ratio", 0.5)
+	log.Warnw(ctx, TagDefault, "odd", "id", 2, "dangling")
+	log.Errorw(ctx, TagDefault, "no pairs")
+
+	lines := strings.Split(strings.TrimSuffix(logBuf.String(), "\n"), "\n")
+	assert.Number(t, len(lines)).Equal(3)
+	assert.String(t, lines[0]).Matches(`^\[INFO].* _def\|\|msg=even\|\|id=1\|\|ok=true\|\|user={"name":"tom"}\|\|ratio=0.5$`)
+	assert.String(t, lines[1]).Matches(`^\[WARN].* _def\|\|msg=odd\|\|id=2\|\|!BADKEY=dangling$`)
+	assert.String(t, lines[2]).Matches(`^\[ERROR].* _def\|\|msg=no pairs$`)
+}
+
+func TestRefreshLoggerNames(t *testing.T) {
+
+	t.Run("root with tags", func(t *testing.T) {
+		err := log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+			"logger.root.tag": "_com_*",
+		})
+		assert.Error(t, err).Matches("logger root must not have attribute 'tag'")
+	})
+}
+
+func TestLoggerNames(t *testing.T) {
+	assert.That(t, log.LoggerNames()).Nil()
+	assert.That(t, log.AppenderNames()).Nil()
+
+	err := log.RefreshConfig(readConfig())
+	assert.Error(t, err).Nil()
+
+	assert.That(t, log.LoggerNames()).Equal([]string{"myLogger", "root"})
+	assert.That(t, log.AppenderNames()).Equal([]string{"console", "file", "sample"})
+
+	log.Destroy()
+	assert.That(t, log.LoggerNames()).Nil()
+	assert.That(t, log.AppenderNames()).Nil()
+}
+
+func TestRefreshLoggerLayout(t *testing.T) {
+	err := log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+		"logger.myLogger.layout.type": "JSONLayout",
+	})
+	assert.Error(t, err).Matches("create logger myLogger error: both the logger and appender file have a layout")
+
+	err = log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+		"logger.root.layout.type": "JSONLayout",
+	})
+	assert.Error(t, err).Matches("create logger root error: both the logger and appender console have a layout")
+
+	err = log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+		"appender.plain.type":            "ConsoleAppender",
+		"logger.root.appenderRef[0].ref": "plain",
+		"logger.root.layout.type":        "JSONLayout",
+	})
+	assert.Error(t, err).Nil()
+	log.Destroy()
+}
+
+func TestRefreshNoStart(t *testing.T) {
+	m := readConfig()
+	m["appender.file.dir"] = t.TempDir()
+
+	n := runtime.NumGoroutine()
+	c, err := log.RefreshConfigWithOptions(m, log.RefreshOptions{NoStart: true})
+	assert.Error(t, err).Nil()
+	assert.Number(t, runtime.NumGoroutine()).Equal(n)
+
+	_, err = os.Stat(m["appender.file.dir"] + "/log.txt")
+	assert.That(t, os.IsNotExist(err)).True()
+
+	assert.Number(t, len(c.Loggers)).Equal(2)
+	assert.Number(t, len(c.Appenders)).Equal(3)
+	assert.That(t, c.Loggers["myLogger"].GetName()).Equal("myLogger")
+	assert.That(t, c.Heartbeat).Nil()
+
+	// Nothing is installed.
+	assert.That(t, log.LoggerNames()).Nil()
+	assert.That(t, log.AppenderNames()).Nil()
+}
+
+func TestRefreshAppenderRefLayout(t *testing.T) {
+	m := readConfig()
+	for k, v := range map[string]string{
+		"appender.json.type":                         "CaptureAppender",
+		"appender.plain.type":                        "CaptureAppender",
+		"logger.myLogger.layout.type":                "TextLayout",
+		"logger.myLogger.appenderRef[0].ref":         "json",
+		"logger.myLogger.appenderRef[0].layout.type": "JSONLayout",
+		"logger.myLogger.appenderRef[1].ref":         "plain",
+	} {
+		m[k] = v
+	}
+	c, err := log.RefreshConfigWithOptions(m, log.RefreshOptions{})
+	assert.Error(t, err).Nil()
+	log.Info(t.Context(), TagRequestIn, log.Msg("hello"))
+	log.Destroy()
+
+	// the ref with a layout renders the event again, the other one
+	// writes the event rendered by the logger
+	rendered := func(name string) string {
+		events := c.Appenders[name].(*log.CaptureAppender).Events()
+		assert.Number(t, len(events)).Equal(1)
+		return string(events[0].RawBytes)
+	}
+	assert.String(t, rendered("json")).Matches(`^\{"level":"info",.*"tag":"_com_request_in","msg":"hello"\}\n$`)
+	assert.String(t, rendered("plain")).Matches(`^\[INFO\].* _com_request_in\|\|msg=hello\n$`)
+}
+
+// blockingWriter blocks writes until release is closed.
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestTotalDiscarded(t *testing.T) {
+	w := blockingWriter{release: make(chan struct{})}
+	log.Stdout = w
+	defer func() { log.Stdout = os.Stdout }()
+
+	err := log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+		"logger.myLogger.bufferSize":         "100",
+		"logger.myLogger.appenderRef[0].ref": "console",
+	})
+	assert.Error(t, err).Nil()
+	assert.Number(t, log.TotalDiscarded()).Equal(int64(0))
+
+	// The worker blocks on the first event, so the buffer overflows.
+	for range 1000 {
+		log.Info(t.Context(), TagRequestIn, log.Msg("hello"))
+	}
+	assert.Number(t, log.TotalDiscarded()).GreaterThan(int64(0))
+
+	close(w.release)
+	log.Destroy()
+	assert.Number(t, log.TotalDiscarded()).Equal(int64(0))
+}
+
+func TestBoundLogger(t *testing.T) {
+	ctx := t.Context()
+
+	logBuf := bytes.NewBuffer(nil)
+	log.Stdout = logBuf
+	log.FieldsFromContext = func(ctx context.Context) []log.Field {
+		return []log.Field{log.String("traceId", "abc")}
+	}
+	defer func() {
+		log.Stdout = os.Stdout
+		log.FieldsFromContext = nil
+	}()
+
+	l := log.With(TagDefault, log.String("component", "db"))
+	assert.That(t, l.Tag()).Equal(TagDefault)
+	assert.That(t, l.Fields()).Equal([]log.Field{log.String("component", "db")})
+
+	// child loggers do not share their bound fields
+	l1 := l.With(log.Int("shard", 1))
+	l2 := l.With(log.Int("shard", 2))
+
+	l.Debugf(ctx, "not print")
+	l.Infof(ctx, "hello %s", "world")
+	l1.Warn(ctx, log.Msg("slow query"), log.Int("ms", 300))
+	l2.Error(ctx, log.Msg("failed"))
+	assert.String(t, logBuf.String()).Matches(
+		`^\[INFO]\[.*]\[.*log_test.go:\d+] _def\|\|traceId=abc\|\|component=db\|\|msg=hello world\n` +
+			`\[WARN]\[.*]\[.*log_test.go:\d+] _def\|\|traceId=abc\|\|component=db\|\|shard=1\|\|msg=slow query\|\|ms=300\n` +
+			`\[ERROR]\[.*]\[.*log_test.go:\d+] _def\|\|traceId=abc\|\|component=db\|\|shard=2\|\|msg=failed\n$`)
+}
+
+func TestRefreshLoggerLevel(t *testing.T) {
+	err := log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+		"logger.myLogger.level":                "error",
+		"logger.myLogger.appenderRef[0].ref":   "file",
+		"logger.myLogger.appenderRef[0].level": "debug~warn",
+		"logger.myLogger.appenderRef[1].ref":   "sample",
+		"logger.myLogger.appenderRef[1].level": "trace,info",
+	})
+	assert.Error(t, err).Matches("create logger myLogger error: level range doesn't overlap the level of any appender ref")
+
+	err = log.RefreshMerged(log.MapSource(readConfig()), log.MapSource{
+		"logger.myLogger.level":                "error",
+		"logger.myLogger.appenderRef[0].ref":   "file",
+		"logger.myLogger.appenderRef[0].level": "debug~warn",
+		"logger.myLogger.appenderRef[1].ref":   "sample",
+	})
+	assert.Error(t, err).Nil()
+	log.Destroy()
+}
+
+func TestPublishEvent(t *testing.T) {
+	a := &log.CaptureAppender{}
+	l := &log.SyncLogger{
+		LoggerBase: log.LoggerBase{
+			Name:  "replay",
+			Level: log.LevelRange{MinLevel: log.InfoLevel, MaxLevel: log.MaxLevel},
+		},
+		AppenderRefs: []*log.AppenderRef{{Appender: a}},
+	}
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := &log.Event{
+		Level:     log.WarnLevel,
+		Time:      ts,
+		File:      "archive.go",
+		Line:      42,
+		Tag:       "_com_request_in",
+		Fields:    []log.Field{log.Msg("replayed")},
+		CtxString: "trace_id=abc",
+	}
+	log.PublishEvent(l, e)
+	log.PublishEvent(l, &log.Event{Level: log.DebugLevel, Time: ts})
+
+	// the caller's event is left untouched
+	assert.That(t, e.Time).Equal(ts)
+	assert.String(t, e.Message()).Equal("replayed")
+	assert.Number(t, e.Seq).Equal(uint64(0))
+
+	events := a.Events()
+	assert.Number(t, len(events)).Equal(1)
+	assert.That(t, events[0].Time).Equal(ts)
+	assert.String(t, events[0].File).Equal("archive.go")
+	assert.String(t, events[0].Logger).Equal("replay")
+	assert.Number(t, events[0].Seq).GreaterThan(uint64(0))
+
+	var buf bytes.Buffer
+	layout := &log.TextLayout{}
+	layout.EncodeTo(events[0], &buf)
+	assert.String(t, buf.String()).Matches(
+		`^\[WARN]\[2020-01-02T03:04:05[^]]*]\[archive.go:42] _com_request_in\|\|trace_id=abc\|\|msg=replayed\n$`)
+}
+
+var _ log.Filter = (*DropFilter)(nil)
+
+// DropFilter rejects the events whose message contains Text.
+type DropFilter struct {
+	Text string `PluginAttribute:"text"`
+}
+
+func (f *DropFilter) Accept(e *log.Event) bool {
+	return !strings.Contains(e.Message(), f.Text)
+}
+
+func init() {
+	log.RegisterPlugin[DropFilter]("DropFilter")
+}
+
+func TestLoggerFilters(t *testing.T) {
+	c, err := log.RefreshConfigWithOptions(map[string]string{
+		"appender.capture.type":           "CaptureAppender",
+		"logger.root.type":                "Logger",
+		"logger.root.appenderRef.ref":     "capture",
+		"logger.root.filter[0].type":      "DropFilter",
+		"logger.root.filter[0].text":      "health",
+		"logger.root.filter[1].type":      "DropFilter",
+		"logger.root.filter[1].text":      "ping",
+		"logger.myLogger.type":            "AsyncLogger",
+		"logger.myLogger.tag":             "_com_request_*",
+		"logger.myLogger.appenderRef.ref": "capture",
+		"logger.myLogger.filter.type":     "DropFilter",
+		"logger.myLogger.filter.text":     "secret",
+	}, log.RefreshOptions{})
+	assert.Error(t, err).Nil()
+
+	ctx := t.Context()
+	log.Infof(ctx, log.TagAppDef, "health check")
+	log.Infof(ctx, log.TagAppDef, "ping")
+	log.Infof(ctx, log.TagAppDef, "user login")
+	log.Infof(ctx, TagRequestIn, "secret request")
+	log.Infof(ctx, TagRequestIn, "health request")
+	log.Destroy()
+
+	var msgs []string
+	for _, e := range c.Appenders["capture"].(*log.CaptureAppender).Events() {
+		msgs = append(msgs, e.Message())
+	}
+	assert.That(t, msgs).Equal([]string{"user login", "health request"})
+
+	_, err = log.RefreshConfigWithOptions(map[string]string{
+		"appender.console.type":           "ConsoleAppender",
+		"logger.root.type":                "Logger",
+		"logger.root.appenderRef.ref":     "console",
+		"logger.root.filter.type":         "TextLayout",
+		"logger.myLogger.type":            "Logger",
+		"logger.myLogger.tag":             "_com_request_*",
+		"logger.myLogger.appenderRef.ref": "console",
+	}, log.RefreshOptions{NoStart: true})
+	assert.Error(t, err).Matches("plugin TextLayout is not a log.Filter")
+}
+
+func TestRefreshIgnoreMissingAppenderRefs(t *testing.T) {
+	m := map[string]string{
+		"appender.console.type":           "ConsoleAppender",
+		"logger.root.type":                "Logger",
+		"logger.root.appenderRef[0].ref":  "console",
+		"logger.root.appenderRef[1].ref":  "missing",
+		"logger.myLogger.type":            "AsyncLogger",
+		"logger.myLogger.tag":             "_com_request_*",
+		"logger.myLogger.appenderRef.ref": "console",
+	}
+
+	_, err := log.RefreshConfigWithOptions(m, log.RefreshOptions{NoStart: true})
+	assert.Error(t, err).Matches("appender missing not found")
+
+	var errs []string
+	reportError := log.ReportError
+	log.ReportError = func(err error) { errs = append(errs, err.Error()) }
+	defer func() { log.ReportError = reportError }()
+
+	c, err := log.RefreshConfigWithOptions(m, log.RefreshOptions{
+		NoStart:                   true,
+		IgnoreMissingAppenderRefs: true,
+	})
+	assert.Error(t, err).Nil()
+
+	refs := c.Loggers["root"].(*log.SyncLogger).AppenderRefs
+	assert.Number(t, len(refs)).Equal(1)
+	assert.String(t, refs[0].Ref).Equal("console")
+	assert.That(t, refs[0].Appender).Equal(c.Appenders["console"])
+
+	assert.Number(t, len(errs)).Equal(1)
+	assert.String(t, errs[0]).Matches("logger root: appender missing not found, ref skipped")
+
+	// the logger starts and logs to the remaining appender
+	buf := bytes.NewBuffer(nil)
+	log.Stdout = buf
+	defer func() { log.Stdout = os.Stdout }()
+
+	_, err = log.RefreshConfigWithOptions(m, log.RefreshOptions{IgnoreMissingAppenderRefs: true})
+	assert.Error(t, err).Nil()
+	log.Errorf(t.Context(), TagDefault, "hello")
+	log.Destroy()
+	assert.String(t, buf.String()).Matches(`^\[ERROR\].*msg=hello\n$`)
+	assert.Number(t, len(errs)).Equal(2)
+}
+
+func TestLogComputedLevel(t *testing.T) {
+	defer log.Destroy()
+
+	a := &log.CaptureAppender{}
+	_, err := log.NewSyncLoggerBuilder().
+		Level(log.WarnLevel).
+		Appender(a).
+		Tags("_com_request_*").
+		Build()
+	assert.Error(t, err).Nil()
+
+	levelOf := func(elapsed time.Duration) log.Level {
+		if elapsed >= 100*time.Millisecond {
+			return log.WarnLevel
+		}
+		return log.DebugLevel
+	}
+
+	ctx := t.Context()
+	for _, elapsed := range []time.Duration{
+		99 * time.Millisecond,
+		100 * time.Millisecond,
+		101 * time.Millisecond,
+	} {
+		log.Logf(ctx, TagRequestIn, levelOf(elapsed), "elapsed %s", elapsed)
+	}
+	log.Log(ctx, TagRequestIn, log.ErrorLevel, log.Msg("failed"))
+	log.Log(ctx, TagRequestIn, log.InfoLevel, log.Msg("ignored"))
+
+	var msgs []string
+	for _, e := range a.Events() {
+		assert.That(t, strings.HasSuffix(e.File, "log_test.go")).True()
+		msgs = append(msgs, e.Level.UpperName()+" "+e.Message())
+	}
+	assert.That(t, msgs).Equal([]string{
+		"WARN elapsed 100ms",
+		"WARN elapsed 101ms",
+		"ERROR failed",
+	})
+}
+
+func TestTagDefaults(t *testing.T) {
+	defer log.Destroy()
+	defer TagRequestIn.WithDefaults()
+
+	a := &log.CaptureAppender{}
+	_, err := log.NewSyncLoggerBuilder().
+		Level(log.InfoLevel).
+		Appender(a).
+		Tags("_com_request_*").
+		Build()
+	assert.Error(t, err).Nil()
+
+	tag := TagRequestIn.WithDefaults(log.String("domain", "payment"))
+	assert.That(t, tag).Equal(TagRequestIn)
+
+	ctx := t.Context()
+	log.Info(ctx, TagRequestIn, log.Msg("in"))
+	log.Infof(ctx, TagRequestOut, "out")
+	log.With(TagRequestIn, log.Int("user", 1)).Infof(ctx, "bound")
+	TagRequestIn.Logger().Info(ctx, log.Msg("tag logger"))
+
+	TagRequestIn.WithDefaults()
+	log.Info(ctx, TagRequestIn, log.Msg("cleared"))
+
+	var lines []string
+	for _, e := range a.Events() {
+		buf := bytes.NewBuffer(nil)
+		enc := log.NewJSONEncoder(buf)
+		enc.AppendEncoderBegin()
+		log.EncodeFields(enc, e.Fields)
+		enc.AppendEncoderEnd()
+		lines = append(lines, e.Tag+" "+buf.String())
+	}
+	assert.That(t, lines).Equal([]string{
+		`_com_request_in {"domain":"payment","msg":"in"}`,
+		`_com_request_out {"msg":"out"}`,
+		`_com_request_in {"domain":"payment","user":1,"msg":"bound"}`,
+		`_com_request_in {"domain":"payment","msg":"tag logger"}`,
+		`_com_request_in {"msg":"cleared"}`,
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -43,7 +44,38 @@ func RegisterConverter[T any](fn Converter[T]) {
 }
 
 func init() {
+	registerBuiltinConverters()
+}
+
+// registerBuiltinConverters registers the converters of the types used by
+// the built-in plugins.
+func registerBuiltinConverters() {
 	RegisterConverter(time.ParseDuration)
+	RegisterConverter(ParseLevel)
+	RegisterConverter(ParseLevelRange)
+	RegisterConverter(ParseBufferFullPolicy)
+	RegisterConverter(ParseRatio)
+	RegisterConverter(ParseTimePrecision)
+}
+
+// ListConverters returns the types that have a registered converter,
+// sorted by their names.
+func ListConverters() []reflect.Type {
+	types := make([]reflect.Type, 0, len(typeConverters))
+	for t := range typeConverters {
+		types = append(types, t)
+	}
+	slices.SortFunc(types, func(a, b reflect.Type) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return types
+}
+
+// ResetConverters removes all custom converters and restores the built-in
+// ones. It is meant for tests that register their own converters.
+func ResetConverters() {
+	typeConverters = map[reflect.Type]any{}
+	registerBuiltinConverters()
 }
 
 // Lifecycle is an optional interface for plugin lifecycle hooks.
@@ -195,7 +227,7 @@ func injectSingleAttribute(fv reflect.Value, ft reflect.StructField, prefix stri
 	if err != nil {
 		return err
 	}
-	v, err := convertAttributeValue(ft.Type, val)
+	v, err := convertAttributeValue(ft.Type, val, tag.Get("strict") == "true")
 	if err != nil {
 		return err
 	}
@@ -210,9 +242,10 @@ func injectArrayAttribute(fv reflect.Value, ft reflect.StructField, prefix strin
 		return err
 	}
 	elemType := ft.Type.Elem()
+	strict := tag.Get("strict") == "true"
 	slice := reflect.MakeSlice(ft.Type, len(values), len(values))
 	for i, str := range values {
-		elemVal, err := convertAttributeValue(elemType, str)
+		elemVal, err := convertAttributeValue(elemType, str, strict)
 		if err != nil {
 			return errutil.Stack(err, "inject %s[%d] error", ft.Name, i)
 		}
@@ -262,7 +295,9 @@ func getArrayValues(s flatten.Storage, prefix string, tag PluginTag) ([]string,
 }
 
 // convertAttributeValue converts a string value to the specified type.
-func convertAttributeValue(t reflect.Type, val string) (reflect.Value, error) {
+// Booleans are parsed leniently unless strict is set, in which case only
+// the forms accepted by strconv.ParseBool are allowed.
+func convertAttributeValue(t reflect.Type, val string, strict bool) (reflect.Value, error) {
 
 	// Try to use a registered type converter
 	if fn := typeConverters[t]; fn != nil {
@@ -296,7 +331,7 @@ func convertAttributeValue(t reflect.Type, val string) (reflect.Value, error) {
 		}
 		v.SetFloat(f)
 	case reflect.Bool:
-		b, err := strconv.ParseBool(val)
+		b, err := parseBool(val, strict)
 		if err != nil {
 			return reflect.Value{}, errutil.Explain(err, "parse %q to %s error", val, t.String())
 		}
@@ -309,7 +344,24 @@ func convertAttributeValue(t reflect.Type, val string) (reflect.Value, error) {
 	return v, nil
 }
 
+// parseBool parses a boolean value. Besides the forms accepted by
+// strconv.ParseBool, it accepts yes/no, on/off and enabled/disabled
+// case-insensitively unless strict is set.
+func parseBool(val string, strict bool) (bool, error) {
+	if !strict {
+		switch strings.ToLower(val) {
+		case "yes", "on", "enabled":
+			return true, nil
+		case "no", "off", "disabled":
+			return false, nil
+		}
+	}
+	return strconv.ParseBool(val)
+}
+
 // resolveProperty resolves a property reference in a string value.
+// Besides ${key}, the forms ${key:-default} and ${key:?message} are
+// supported for optional and required properties.
 func resolveProperty(p flatten.Storage, s string) (string, error) {
 	// If there is no property reference, return the original string.
 	start := strings.Index(s, "${")
@@ -342,12 +394,38 @@ func resolveProperty(p flatten.Storage, s string) (string, error) {
 	}
 
 	key := s[start+2 : end]
+
+	// ${key:-default} falls back to the default, and ${key:?message}
+	// fails with the message when the property is missing.
+	var (
+		fallback string
+		message  string
+		mode     byte
+	)
+	if i := strings.Index(key, ":"); i >= 0 && i+1 < len(key) {
+		if c := key[i+1]; c == '-' || c == '?' {
+			if c == '-' {
+				fallback = key[i+2:]
+			} else {
+				message = key[i+2:]
+			}
+			mode, key = c, key[:i]
+		}
+	}
+
 	val, ok := p.Value(key)
 	if !ok {
 		if p.Exists(key) {
 			return "", errutil.Explain(nil, "property reference %q is not a simple value", s[start:end+1])
 		}
-		return "", errutil.Explain(nil, "property reference %q does not exist", s[start:end+1])
+		switch mode {
+		case '-':
+			val = fallback
+		case '?':
+			return "", errutil.Explain(nil, "property %q is required: %s", key, message)
+		default:
+			return "", errutil.Explain(nil, "property reference %q does not exist", s[start:end+1])
+		}
 	}
 
 	resolved, err := resolveProperty(p, val)
@@ -379,7 +457,7 @@ func injectElement(fv reflect.Value, ft reflect.StructField, prefix string, tag
 	switch fv.Kind() {
 	case reflect.Slice:
 		return injectArrayElement(fv, ft, elemKey, nullable, PluginTag(tag), s)
-	case reflect.Interface, reflect.Pointer:
+	case reflect.Interface, reflect.Pointer, reflect.Struct:
 		return injectSingleElement(fv, ft, elemKey, nullable, PluginTag(tag), s)
 	default:
 		return errutil.Explain(nil, "unsupported inject type %s", ft.Type.String())
@@ -392,6 +470,10 @@ func injectSingleElement(fv reflect.Value, ft reflect.StructField, prefix string
 	plugin, ok := s.Value(prefix + ".type")
 	if !ok {
 		plugin, ok = tag.Lookup("default")
+		if !ok {
+			// Concrete struct elements can be created without a type.
+			ok = ft.Type.Kind() != reflect.Interface && s.Exists(prefix)
+		}
 		if !ok {
 			if nullable {
 				return nil
@@ -482,21 +564,40 @@ func createPlugin(t reflect.Type, prefix string, plugin string, s flatten.Storag
 		if !ok {
 			return reflect.Value{}, errutil.Explain(nil, "plugin %s not found", plugin)
 		}
+		// The interface of the element is the category of the plugin.
+		if !reflect.PointerTo(p.Class).Implements(t) {
+			return reflect.Value{}, errutil.Explain(nil, "plugin %s is not a %s", plugin, t.String())
+		}
 		return newPlugin(p.Class, prefix, s)
 	case reflect.Pointer:
 		elemType := t.Elem()
 		if elemType.Kind() != reflect.Struct {
 			return reflect.Value{}, errutil.Explain(nil, "point field must point to a struct")
 		}
-		p, ok := pluginRegistry[plugin]
-		if !ok {
-			if plugin != "" {
-				return reflect.Value{}, errutil.Explain(nil, "plugin %s not found", plugin)
-			}
-			p = &Plugin{Class: elemType}
+		return newStructPlugin(elemType, prefix, plugin, s)
+	case reflect.Struct:
+		v, err := newStructPlugin(t, prefix, plugin, s)
+		if err != nil {
+			return reflect.Value{}, err
 		}
-		return newPlugin(p.Class, prefix, s)
+		return v.Elem(), nil
 	default:
 		return reflect.Value{}, errutil.Explain(nil, "unsupported inject type %s", t.String())
 	}
 }
+
+// newStructPlugin creates a plugin instance of the concrete struct type t.
+// The plugin name is optional, but if given it must be registered with t.
+func newStructPlugin(t reflect.Type, prefix string, plugin string, s flatten.Storage) (reflect.Value, error) {
+	p, ok := pluginRegistry[plugin]
+	if !ok {
+		if plugin != "" {
+			return reflect.Value{}, errutil.Explain(nil, "plugin %s not found", plugin)
+		}
+		p = &Plugin{Class: t}
+	}
+	if p.Class != t {
+		return reflect.Value{}, errutil.Explain(nil, "plugin %s is not of type %s", plugin, t.String())
+	}
+	return newPlugin(p.Class, prefix, s)
+}
@@ -21,9 +21,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
@@ -48,6 +50,8 @@ func init() {
 	RegisterPlugin[ConsoleAppender]("ConsoleAppender")
 	RegisterPlugin[FileAppender]("FileAppender")
 	RegisterPlugin[RollingFileAppender]("RollingFileAppender")
+	RegisterPlugin[CaptureAppender]("CaptureAppender")
+	RegisterPlugin[AsyncAppender]("AsyncAppender")
 
 	bufferCap = 10 * 1024 // 10KB
 	if s, ok := os.LookupEnv("GS_LOGGER_BUFFER_CAP"); ok {
@@ -87,9 +91,37 @@ func ParseHumanizeBytes(s string) (int, error) {
 	return int(f), nil
 }
 
+// HumanizeBytes formats a byte count with the largest binary unit that
+// keeps the number at least 1, with at most one decimal, e.g. 512B, 1KB
+// or 1.5MB. Units go up to EB.
+func HumanizeBytes(n int64) string {
+	const units = "KMGTPE"
+	sign, u := "", uint64(n)
+	if n < 0 {
+		sign, u = "-", -u
+	}
+	if u < 1024 {
+		return sign + strconv.FormatUint(u, 10) + "B"
+	}
+	i, div := 0, uint64(1024)
+	for u/div >= 1024 && i < len(units)-1 {
+		div *= 1024
+		i++
+	}
+	s := strconv.FormatFloat(float64(u)/float64(div), 'f', 1, 64)
+	if s == "1024.0" && i < len(units)-1 { // rounded up to the next unit
+		s, i = "1.0", i+1
+	}
+	s = strings.TrimSuffix(s, ".0")
+	return sign + s + units[i:i+1] + "B"
+}
+
 // getBuffer retrieves a *bytes.Buffer from the pool.
 // If the pool is empty, it allocates a new buffer.
 func getBuffer() *bytes.Buffer {
+	if DisablePooling {
+		return bytes.NewBuffer(nil)
+	}
 	if v := bufferPool.Get(); v != nil {
 		return v.(*bytes.Buffer)
 	}
@@ -100,7 +132,7 @@ func getBuffer() *bytes.Buffer {
 // Buffers with capacity larger than bufferCap are discarded
 // to prevent retaining excessively large memory.
 func putBuffer(buf *bytes.Buffer) {
-	if buf.Cap() <= bufferCap {
+	if !DisablePooling && buf.Cap() <= bufferCap {
 		buf.Reset()
 		bufferPool.Put(buf)
 	}
@@ -108,22 +140,75 @@ func putBuffer(buf *bytes.Buffer) {
 
 // WriteEvent writes a log event to the given io.Writer using the specified Layout.
 // If e.RawBytes is not nil, it writes the raw bytes directly.
-// Otherwise, the event is encoded using the layout into a temporary buffer.
+// Otherwise, the event is encoded using the layout into a temporary buffer,
+// or using DefaultLayout if layout is nil.
 // Any write errors are reported via ReportError.
-func WriteEvent(w io.Writer, e *Event, layout Layout) {
+//
+// It returns the number of bytes written, so that appenders can account
+// for the size of the events without encoding them again. The count is
+// taken before the buffer returns to the pool, so it stays valid.
+func WriteEvent(w io.Writer, e *Event, layout Layout) int {
 	if e.RawBytes != nil {
-		if _, err := w.Write(e.RawBytes); err != nil {
-			ReportError(err)
+		n, err := w.Write(e.RawBytes)
+		if err != nil {
+			internalErrorf(err, "write event error")
 		}
-		return
+		return n
+	}
+	if layout == nil {
+		layout = DefaultLayout
 	}
 
 	buf := getBuffer()
 	defer putBuffer(buf)
 	layout.EncodeTo(e, buf)
-	if _, err := w.Write(buf.Bytes()); err != nil {
-		ReportError(err)
+	n, err := w.Write(buf.Bytes())
+	if err != nil {
+		internalErrorf(err, "write event error")
+	}
+	return n
+}
+
+// Render returns the bytes that WriteEvent would write for the event, for
+// integrations that send events over their own transport without being an
+// Appender. The event is encoded into a pooled buffer, and the result is a
+// copy of it, so the caller owns the returned slice and may keep or modify
+// it. The event itself is not reset, its ownership stays with the caller.
+func Render(layout Layout, e *Event) []byte {
+	if e.RawBytes != nil {
+		return bytes.Clone(e.RawBytes)
+	}
+	if layout == nil {
+		layout = DefaultLayout
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	layout.EncodeTo(e, buf)
+	return bytes.Clone(buf.Bytes())
+}
+
+// WriteEvents writes log events to the given io.Writer using the specified
+// Layout, like WriteEvent, but coalesces them into a single write.
+// It returns the total number of bytes written.
+func WriteEvents(w io.Writer, events []*Event, layout Layout) int {
+	if layout == nil {
+		layout = DefaultLayout
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	for _, e := range events {
+		if e.RawBytes != nil {
+			buf.Write(e.RawBytes)
+			continue
+		}
+		layout.EncodeTo(e, buf)
+	}
+	n, err := w.Write(buf.Bytes())
+	if err != nil {
+		internalErrorf(err, "write event error")
 	}
+	return n
 }
 
 // Appender defines components responsible for writing log events.
@@ -137,6 +222,15 @@ type Appender interface {
 	ConcurrentSafe() bool // Returns true if the appender is concurrent-safe
 }
 
+// BatchAppender is implemented by appenders that write a batch of events
+// more efficiently than one by one, e.g. with a single write. AsyncLogger
+// forwards its batches to such appenders, see AsyncLogger.MaxBatchSize.
+//
+// AppendBatch MUST NOT modify or retain references to the Events.
+type BatchAppender interface {
+	AppendBatch(events []*Event)
+}
+
 // AppenderBase provides common configuration fields for all appenders.
 type AppenderBase struct {
 	Name   string `PluginAttribute:"name"`
@@ -151,8 +245,26 @@ var (
 	_ Appender = (*ConsoleAppender)(nil)
 	_ Appender = (*FileAppender)(nil)
 	_ Appender = (*RollingFileAppender)(nil)
+	_ Appender = (*CaptureAppender)(nil)
+	_ Appender = (*ChannelAppender)(nil)
+	_ Appender = (*AsyncAppender)(nil)
+
+	_ BatchAppender = (*ConsoleAppender)(nil)
+	_ BatchAppender = (*FileAppender)(nil)
+
+	_ SyncAppender = (*FileAppender)(nil)
+	_ SyncAppender = (*RollingFileAppender)(nil)
+	_ SyncAppender = (*AsyncAppender)(nil)
 )
 
+// SyncAppender is implemented by appenders that can commit the events
+// written so far to stable storage, e.g. file appenders with fsync.
+// Loggers call Sync for events at or above their FlushLevel.
+type SyncAppender interface {
+	Appender
+	Sync() error
+}
+
 // DiscardAppender ignores all log events (no-op).
 type DiscardAppender struct {
 	AppenderBase
@@ -176,43 +288,412 @@ func (c *ConsoleAppender) Append(e *Event) {
 	WriteEvent(Stdout, e, c.Layout)
 }
 
+// AppendBatch formats the events and writes them to standard output at once.
+func (c *ConsoleAppender) AppendBatch(events []*Event) {
+	WriteEvents(Stdout, events, c.Layout)
+}
+
 func (c *ConsoleAppender) ConcurrentSafe() bool { return true }
 
 // FileAppender writes formatted log events to a file in append mode.
+// If Truncate is set, the existing content of the file is discarded on Start.
+// If LinePrefix is set, it is written at the start of each line, including
+// the lines of raw data.
 type FileAppender struct {
 	AppenderBase
 
-	FileDir  string `PluginAttribute:"dir,default=./logs"`
-	FileName string `PluginAttribute:"file"`
+	FileDir    string `PluginAttribute:"dir,default=./logs"`
+	FileName   string `PluginAttribute:"file"`
+	Truncate   bool   `PluginAttribute:"truncate,default=false"`
+	LinePrefix string `PluginAttribute:"linePrefix,default="`
 
-	file *File
+	file atomic.Pointer[File] // nil before Start and after Stop
 }
 
 // Start opens the log file for appending.
 func (c *FileAppender) Start() error {
 	filePath := filepath.Join(c.FileDir, c.FileName)
-	f, err := OpenFile(filePath)
+	open := OpenFile
+	if c.Truncate {
+		open = OpenTruncFile
+	}
+	f, err := open(filePath)
 	if err != nil {
 		return err
 	}
-	c.file = f
+	c.file.Store(f)
 	return nil
 }
 
 // Stop flushes and closes the file.
 func (c *FileAppender) Stop() {
-	if c.file != nil {
-		CloseFile(c.file)
+	if f := c.file.Swap(nil); f != nil {
+		CloseFile(f)
 	}
 }
 
 // Append formats the log event and writes it to the file.
+// Events appended after Stop are dropped and reported via ReportError.
 func (c *FileAppender) Append(e *Event) {
-	WriteEvent(c.file, e, c.Layout)
+	if f := c.openedFile(); f != nil {
+		WriteEvent(prefixLines(f, c.LinePrefix), e, c.Layout)
+	}
+}
+
+// AppendBatch formats the events and writes them to the file at once.
+func (c *FileAppender) AppendBatch(events []*Event) {
+	if f := c.openedFile(); f != nil {
+		WriteEvents(prefixLines(f, c.LinePrefix), events, c.Layout)
+	}
+}
+
+// Sync commits the written events to disk. It does nothing if the
+// appender is not started.
+func (c *FileAppender) Sync() error {
+	if f := c.file.Load(); f != nil {
+		return f.Sync()
+	}
+	return nil
+}
+
+// openedFile returns the file, or reports an error if it is not opened.
+func (c *FileAppender) openedFile() *File {
+	f := c.file.Load()
+	if f == nil {
+		internalErrorf(nil, "file appender %s is not started", c.Name)
+	}
+	return f
 }
 
 func (c *FileAppender) ConcurrentSafe() bool { return true }
 
+// prefixWriter writes a prefix at the start of each line. Each Write is
+// expected to start a new line, as the writes of the appenders do.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+}
+
+// prefixLines returns a writer that prefixes each line written to w,
+// or w itself if the prefix is empty.
+func prefixLines(w io.Writer, prefix string) io.Writer {
+	if prefix == "" {
+		return w
+	}
+	return prefixWriter{w: w, prefix: prefix}
+}
+
+// Write writes p with the prefix inserted before each line in a single
+// write to the underlying writer.
+func (w prefixWriter) Write(p []byte) (int, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	for line := range bytes.Lines(p) {
+		buf.WriteString(w.prefix)
+		buf.Write(line)
+	}
+	if _, err := w.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// CaptureAppender keeps copies of the log events it receives in memory,
+// so that tests can assert on what has been logged.
+type CaptureAppender struct {
+	AppenderBase
+
+	mutex  sync.Mutex
+	events []*Event
+}
+
+func (c *CaptureAppender) Start() error         { return nil }
+func (c *CaptureAppender) Stop()                {}
+func (c *CaptureAppender) ConcurrentSafe() bool { return true }
+
+// Append stores a copy of the event, since the event itself is reused.
+func (c *CaptureAppender) Append(e *Event) {
+	x := e.Clone()
+	c.mutex.Lock()
+	c.events = append(c.events, x)
+	c.mutex.Unlock()
+}
+
+// Events returns the captured events in the order they were appended.
+func (c *CaptureAppender) Events() []*Event {
+	return c.filter(func(e *Event) bool { return true })
+}
+
+// Len returns the number of captured events.
+func (c *CaptureAppender) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.events)
+}
+
+// Clear removes all captured events.
+func (c *CaptureAppender) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.events = nil
+}
+
+// FilterLevel returns the captured events with the given level.
+func (c *CaptureAppender) FilterLevel(l Level) []*Event {
+	return c.filter(func(e *Event) bool { return e.Level.Code() == l.Code() })
+}
+
+// FilterTag returns the captured events with the given tag.
+func (c *CaptureAppender) FilterTag(tag string) []*Event {
+	return c.filter(func(e *Event) bool { return e.Tag == tag })
+}
+
+// FilterMessage returns the captured events whose message contains substr.
+func (c *CaptureAppender) FilterMessage(substr string) []*Event {
+	return c.filter(func(e *Event) bool { return strings.Contains(e.Message(), substr) })
+}
+
+// filter returns the captured events matching fn.
+func (c *CaptureAppender) filter(fn func(e *Event) bool) []*Event {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var ret []*Event
+	for _, e := range c.events {
+		if fn(e) {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// ChannelAppender sends the log events to a channel, e.g. to feed an
+// in-process pipeline. Since events are pooled and reused once appended,
+// the channel receives clones, see Event.Clone. When the channel is full,
+// Append blocks or discards the event according to Policy.
+type ChannelAppender struct {
+	AppenderBase
+
+	ch     chan<- *Event
+	Policy BufferFullPolicy
+
+	discardCounter atomic.Int64 // Count of discarded events
+}
+
+// NewChannelAppender creates a ChannelAppender sending to ch. Only the
+// block and discard policies are supported, since the oldest event can't
+// be removed from a send-only channel.
+func NewChannelAppender(ch chan<- *Event, policy BufferFullPolicy) *ChannelAppender {
+	return &ChannelAppender{ch: ch, Policy: policy}
+}
+
+// Start checks the channel and the policy.
+func (c *ChannelAppender) Start() error {
+	if c.ch == nil {
+		return errutil.Explain(nil, "channel is nil")
+	}
+	if c.Policy != BufferFullPolicyBlock && c.Policy != BufferFullPolicyDiscard {
+		return errutil.Explain(nil, "BufferFullPolicy %d is not supported", c.Policy)
+	}
+	return nil
+}
+
+func (c *ChannelAppender) Stop()                {}
+func (c *ChannelAppender) ConcurrentSafe() bool { return true }
+
+// GetDiscardCounter returns the total number of discarded events.
+func (c *ChannelAppender) GetDiscardCounter() int64 {
+	return c.discardCounter.Load()
+}
+
+// Append sends a clone of the event to the channel.
+func (c *ChannelAppender) Append(e *Event) {
+	x := e.Clone()
+	if c.Policy == BufferFullPolicyBlock {
+		c.ch <- x
+		return
+	}
+	select {
+	case c.ch <- x:
+	default:
+		c.discardCounter.Add(1)
+	}
+}
+
+// AsyncAppender writes log events to the wrapped appender in a background
+// goroutine, with its own buffer and BufferFullPolicy, so that e.g. a sync
+// logger can write to the console synchronously and to a file
+// asynchronously. Since events are pooled and reused once appended, the
+// buffer holds clones, see Event.Clone. The wrapped appender is started
+// and stopped with the AsyncAppender and is only called by its worker, so
+// it needs not be concurrent-safe. Its own layout is used.
+type AsyncAppender struct {
+	AppenderBase
+	Appender     Appender         `PluginElement:"appender"`
+	BufferSize   int              `PluginAttribute:"bufferSize,default=10000"`
+	OnBufferFull BufferFullPolicy `PluginAttribute:"onBufferFull,default=discard"`
+
+	buf      chan *Event   // Channel buffering events
+	wait     chan struct{} // Closed once the worker has exited
+	stop     *Event        // Signal event to stop the worker
+	stopOnce sync.Once     // Makes Stop idempotent
+
+	mutex   sync.RWMutex // Guards the sends to buf against Stop
+	stopped bool         // Set by Stop, after which events are dropped
+
+	syncMutex sync.Mutex // Serializes Sync calls
+	syncErr   error      // Error of the last sync, set by the worker
+
+	discardCounter atomic.Int64 // Count of discarded events
+}
+
+// Start starts the wrapped appender and the worker goroutine.
+func (c *AsyncAppender) Start() error {
+	if c.Appender == nil {
+		return errutil.Explain(nil, "appender is nil")
+	}
+	if c.BufferSize < 100 {
+		return errutil.Explain(nil, "bufferSize is too small")
+	}
+	if err := c.Appender.Start(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.buf = make(chan *Event, c.BufferSize)
+	c.wait = make(chan struct{})
+	c.stop = &Event{}
+	c.stopOnce = sync.Once{}
+	c.stopped = false
+	c.mutex.Unlock()
+
+	go func() {
+		for e := range c.buf {
+			if e == c.stop {
+				c.releaseMarkers()
+				break
+			}
+			if e.flushed != nil { // sync marker, see Sync
+				c.syncErr = nil
+				if a, ok := c.Appender.(SyncAppender); ok {
+					c.syncErr = a.Sync()
+				}
+				close(e.flushed)
+				continue
+			}
+			c.Appender.Append(e)
+		}
+		close(c.wait)
+	}()
+	return nil
+}
+
+// releaseMarkers closes the channels of the sync markers queued behind
+// the stop signal, so that no Sync waits for them forever.
+func (c *AsyncAppender) releaseMarkers() {
+	for {
+		select {
+		case e := <-c.buf:
+			if e.flushed != nil {
+				close(e.flushed)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Stop writes the buffered events, then stops the wrapped appender.
+// Events appended and syncs requested after Stop are dropped.
+// Concurrent and repeated calls wait for the first one to finish.
+func (c *AsyncAppender) Stop() {
+	c.stopOnce.Do(func() {
+		// Wait for the pending sends, so that none follows the stop signal.
+		c.mutex.Lock()
+		c.stopped = true
+		c.mutex.Unlock()
+		if c.buf == nil { // not started
+			return
+		}
+		c.buf <- c.stop
+		<-c.wait
+		close(c.buf)
+		c.Appender.Stop()
+	})
+}
+
+func (c *AsyncAppender) ConcurrentSafe() bool { return true }
+
+// Sync waits until the events buffered before the call are written, then
+// syncs the wrapped appender if it implements SyncAppender. The wrapped
+// appender is synced by the worker, so it still needs not be
+// concurrent-safe.
+func (c *AsyncAppender) Sync() error {
+	c.syncMutex.Lock()
+	defer c.syncMutex.Unlock()
+	c.mutex.RLock()
+	if c.buf == nil || c.stopped { // not started, or stopped
+		c.mutex.RUnlock()
+		return nil
+	}
+	flushed := make(chan struct{})
+	c.buf <- &Event{flushed: flushed}
+	c.mutex.RUnlock()
+	<-flushed
+	return c.syncErr
+}
+
+// GetDiscardCounter returns the total number of discarded events.
+func (c *AsyncAppender) GetDiscardCounter() int64 {
+	return c.discardCounter.Load()
+}
+
+// Append enqueues a clone of the event. Behavior on full buffer depends
+// on OnBufferFull, see AsyncLogger. Events appended before Start or
+// after Stop are discarded.
+func (c *AsyncAppender) Append(e *Event) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.buf == nil || c.stopped {
+		c.discardCounter.Add(1)
+		return
+	}
+	x := e.Clone()
+
+	select {
+	case c.buf <- x:
+		return
+	default:
+	}
+
+	switch c.OnBufferFull {
+	case BufferFullPolicyDropOldest:
+		for {
+			select {
+			case y := <-c.buf: // Remove one element to make space
+				// Sync markers and the stop signal must reach the worker,
+				// so they are queued again and the new event is dropped.
+				if y == c.stop || y.flushed != nil {
+					c.buf <- y
+					c.discardCounter.Add(1)
+					return
+				}
+				c.discardCounter.Add(1)
+			default: // for linter
+			}
+			select {
+			case c.buf <- x:
+				return
+			default: // for linter
+			}
+		}
+	case BufferFullPolicyBlock:
+		c.buf <- x // Block until space is available
+	case BufferFullPolicyDiscard:
+		c.discardCounter.Add(1)
+	default: // for linter
+	}
+}
+
 // RollingFileAppender writes log events to files that rotate at fixed time intervals.
 // It is safe for concurrent use only when Lock is true.
 // If Lock is false, callers must ensure serialized access (e.g., via an async logger).
@@ -225,23 +706,61 @@ type RollingFileAppender struct {
 	MaxAge   time.Duration `PluginAttribute:"maxAge,default=168h"`
 	SyncLock bool          `PluginAttribute:"syncLock,default=false"`
 
+	// LinePrefix is written at the start of each line, see FileAppender.
+	LinePrefix string `PluginAttribute:"linePrefix,default="`
+
+	// MaxBackups is the maximum number of old files to keep besides the
+	// current one, the oldest are removed first. Zero means no limit.
+	MaxBackups int `PluginAttribute:"maxBackups,default=0"`
+
+	// FilePattern is the time layout of the file name suffix. It is applied
+	// to the start of the rotation interval, and must be able to distinguish
+	// adjacent intervals, e.g. "2006010215" is enough for hourly rotation.
+	FilePattern string `PluginAttribute:"filePattern,default=20060102150405"`
+
+	// RotateOnStart opens a new file on Start even if the file of the
+	// current interval already exists, e.g. from a previous run. The new
+	// file gets a sequence suffix, e.g. "app.log.20250601120000.1".
+	RotateOnStart bool `PluginAttribute:"rotateOnStart,default=false"`
+
 	writer *RollingFileWriter
 	mutex  sync.Mutex
 }
 
 // Start opens the initial log file and prepares for rotation.
 func (c *RollingFileAppender) Start() error {
+	if c.FilePattern == "" {
+		c.FilePattern = DefaultFilePattern
+	}
+	if c.Interval > 0 {
+		t := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+		if t.Format(c.FilePattern) == t.Add(c.Interval).Format(c.FilePattern) {
+			return errutil.Explain(nil, "filePattern %q cannot distinguish intervals of %s", c.FilePattern, c.Interval)
+		}
+	}
 	c.writer = &RollingFileWriter{
-		fileDir:  c.FileDir,
-		fileName: c.FileName,
-		interval: c.Interval,
-		maxAge:   c.MaxAge,
+		fileDir:     c.FileDir,
+		fileName:    c.FileName,
+		filePattern: c.FilePattern,
+		interval:    c.Interval,
+		maxAge:      c.MaxAge,
+		maxBackups:  c.MaxBackups,
+		freshFile:   c.RotateOnStart,
+	}
+	if c.RotateOnStart {
+		if _, err := c.writer.Rotate(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// Stop flushes and closes the current file.
+// Stop flushes and closes the current file. It does nothing if Start
+// failed before creating the writer.
 func (c *RollingFileAppender) Stop() {
+	if c.writer == nil {
+		return
+	}
 	c.writer.Close()
 }
 
@@ -259,11 +778,19 @@ func (c *RollingFileAppender) Append(e *Event) {
 		file, err = c.writer.Rotate()
 	}
 	if err != nil {
-		ReportError(err)
+		internalErrorf(err, "rotate file %s error", c.FileName)
 	}
 	if file != nil {
-		WriteEvent(file, e, c.Layout)
+		WriteEvent(prefixLines(file, c.LinePrefix), e, c.Layout)
+	}
+}
+
+// Sync commits the events written to the current file to disk.
+func (c *RollingFileAppender) Sync() error {
+	if c.writer == nil { // not started
+		return nil
 	}
+	return c.writer.Sync()
 }
 
 func (c *RollingFileAppender) ConcurrentSafe() bool { return c.SyncLock }
@@ -271,29 +798,70 @@ func (c *RollingFileAppender) ConcurrentSafe() bool { return c.SyncLock }
 // RollingFileWriter is the low-level sequential writer.
 // It is NOT safe for concurrent use;
 // synchronization is the responsibility of the caller/appender.
+// Only the cleanup of old files runs in the background, and it is
+// serialized with the creation of new files by mutex.
 type RollingFileWriter struct {
-	fileDir  string
-	fileName string
-	interval time.Duration
-	currFile *File
-	currTime int64
-	maxAge   time.Duration
+	fileDir     string
+	fileName    string
+	filePattern string
+	interval    time.Duration
+	currFile    *File
+	currTime    int64
+	maxAge      time.Duration
+	maxBackups  int
+	freshFile   bool          // whether the first file must not exist yet
+	closeDelay  time.Duration // delay before closing old files, 5m if zero
+
+	mutex   sync.Mutex     // guards file creation, currFile and cleanup
+	pending sync.WaitGroup // delayed closes in flight
+
+	// Cleanup requests are coalesced and served by a single worker, so that
+	// rapid rotations cause at most one directory scan at a time.
+	cleanMutex  sync.Mutex    // guards cleanSignal and closed, but not scans
+	cleanSignal chan struct{} // pending cleanup request
+	cleanDone   chan struct{} // closed when the cleanup worker exits
+	cleanups    int           // number of cleanup scans, guarded by mutex
+	closed      bool          // whether Close has been called
 }
 
+// DefaultFilePattern is the default time layout of rolling file name suffixes.
+const DefaultFilePattern = "20060102150405"
+
 // Rotate creates a new log file if the current time exceeds the rotation interval.
 // It returns the active file for writing.
 // The previous file is closed asynchronously after a delay.
 // This method is not concurrency-safe.
 func (w *RollingFileWriter) Rotate() (*File, error) {
-	now := time.Now()
-	newTime := now.Truncate(w.interval).Unix()
+	return w.rotate(time.Now())
+}
+
+// rotate is Rotate at the given time. The file name suffix is derived from
+// the start of the interval, so it is aligned and distinct for each interval.
+func (w *RollingFileWriter) rotate(now time.Time) (*File, error) {
+	slot := now.Truncate(w.interval)
+	newTime := slot.Unix()
 	if newTime <= w.currTime {
 		return w.currFile, nil
 	}
 
-	formatTime := now.Format("20060102150405")
-	fileName := w.fileName + "." + formatTime
+	fileName := w.fileName + "." + slot.Format(w.pattern())
 	filePath := filepath.Join(w.fileDir, fileName)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.freshFile && w.currFile == nil {
+		for i := 1; ; i++ {
+			_, err := os.Stat(filePath)
+			if os.IsNotExist(err) {
+				break
+			}
+			if err != nil {
+				return w.currFile, err
+			}
+			filePath = filepath.Join(w.fileDir, fileName+"."+strconv.Itoa(i))
+		}
+	}
 	file, err := OpenFile(filePath)
 	if err != nil {
 		return w.currFile, err
@@ -301,11 +869,18 @@ func (w *RollingFileWriter) Rotate() (*File, error) {
 
 	if w.currFile != nil {
 		oldFile := w.currFile
+		closeDelay := w.closeDelay
+		if closeDelay <= 0 {
+			closeDelay = 5 * time.Minute
+		}
+		w.startCleanupWorker()
+		w.pending.Add(1)
 		go func() {
+			defer w.pending.Done()
 			// Delay closing old file. Some logs may be lost.
-			time.Sleep(5 * time.Minute)
+			time.Sleep(closeDelay)
 			CloseFile(oldFile)
-			w.clearExpiredFiles()
+			w.requestCleanup()
 		}()
 	}
 
@@ -314,27 +889,151 @@ func (w *RollingFileWriter) Rotate() (*File, error) {
 	return w.currFile, nil
 }
 
-// clearExpiredFiles deletes log files matching the configured filename prefix
-// that are older than MaxAge. Errors during deletion are ignored.
+// pattern returns the time layout of file name suffixes.
+func (w *RollingFileWriter) pattern() string {
+	if w.filePattern == "" {
+		return DefaultFilePattern
+	}
+	return w.filePattern
+}
+
+// startCleanupWorker starts the cleanup worker if it is not running yet.
+func (w *RollingFileWriter) startCleanupWorker() {
+	w.cleanMutex.Lock()
+	defer w.cleanMutex.Unlock()
+	if w.cleanSignal == nil && !w.closed {
+		w.cleanSignal = make(chan struct{}, 1)
+		w.cleanDone = make(chan struct{})
+		go w.cleanupWorker()
+	}
+}
+
+// requestCleanup asks the cleanup worker for a scan. Requests made while
+// one is already pending are merged into it.
+func (w *RollingFileWriter) requestCleanup() {
+	w.cleanMutex.Lock()
+	defer w.cleanMutex.Unlock()
+	if w.closed || w.cleanSignal == nil {
+		return
+	}
+	select {
+	case w.cleanSignal <- struct{}{}:
+	default:
+	}
+}
+
+// cleanupWorker serves cleanup requests one at a time until Close.
+func (w *RollingFileWriter) cleanupWorker() {
+	defer close(w.cleanDone)
+	for range w.cleanSignal {
+		w.clearExpiredFiles()
+	}
+}
+
+// clearExpiredFiles deletes old log files of this writer that are older
+// than MaxAge, and the oldest ones beyond MaxBackups. The directory is
+// listed while holding the mutex, so that files created by a concurrent
+// rotation are counted and the current file is never deleted.
+// Errors during deletion are reported via ReportError.
 func (w *RollingFileWriter) clearExpiredFiles() {
-	expiration := time.Now().Add(-w.maxAge)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.cleanups++
+
+	type backup struct {
+		name    string
+		modTime time.Time
+	}
+
+	var backups []backup
 	entries, _ := os.ReadDir(w.fileDir)
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasPrefix(entry.Name(), w.fileName+".") {
+		if entry.IsDir() || !w.isRollingFile(entry.Name()) {
+			continue
+		}
+		// The name of the current file is absolute, see OpenFile, while
+		// fileDir may be relative, so only the base names are compared.
+		if w.currFile != nil && entry.Name() == filepath.Base(w.currFile.Name()) {
 			continue
 		}
 		info, err := entry.Info()
 		if err != nil {
 			continue
 		}
-		if info.ModTime().Before(expiration) {
-			_ = os.Remove(filepath.Join(w.fileDir, entry.Name()))
+		backups = append(backups, backup{entry.Name(), info.ModTime()})
+	}
+
+	// newest first, so that the backups to keep come first
+	slices.SortFunc(backups, func(a, b backup) int {
+		if c := b.modTime.Compare(a.modTime); c != 0 {
+			return c
+		}
+		return strings.Compare(b.name, a.name)
+	})
+
+	expiration := time.Now().Add(-w.maxAge)
+	for i, b := range backups {
+		if (w.maxBackups > 0 && i >= w.maxBackups) || b.modTime.Before(expiration) {
+			if err := os.Remove(filepath.Join(w.fileDir, b.name)); err != nil && !os.IsNotExist(err) {
+				internalErrorf(err, "remove file %s error", b.name)
+			}
 		}
 	}
 }
 
-// Close closes the current file.
+// isRollingFile reports whether name is a file of this writer, i.e. the
+// file name followed by a time suffix and an optional sequence suffix.
+// Files of other writers sharing the prefix, e.g. "app.log.wf.*", are not.
+func (w *RollingFileWriter) isRollingFile(name string) bool {
+	suffix, ok := strings.CutPrefix(name, w.fileName+".")
+	if !ok {
+		return false
+	}
+	if _, err := time.Parse(w.pattern(), suffix); err == nil {
+		return true
+	}
+	i := strings.LastIndex(suffix, ".")
+	if i < 0 {
+		return false
+	}
+	if _, err := strconv.Atoi(suffix[i+1:]); err != nil {
+		return false
+	}
+	_, err := time.Parse(w.pattern(), suffix[:i])
+	return err == nil
+}
+
+// Sync commits the content of the current file to stable storage.
+func (w *RollingFileWriter) Sync() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.currFile != nil {
+		return w.currFile.Sync()
+	}
+	return nil
+}
+
+// Close closes the current file, and stops the cleanup worker once the
+// pending cleanup, if any, is done.
 func (w *RollingFileWriter) Close() {
+	w.cleanMutex.Lock()
+	if w.closed {
+		w.cleanMutex.Unlock()
+		return
+	}
+	w.closed = true
+	if w.cleanSignal != nil {
+		close(w.cleanSignal)
+	}
+	done := w.cleanDone
+	w.cleanMutex.Unlock()
+
+	if done != nil {
+		<-done
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	if w.currFile != nil {
 		CloseFile(w.currFile)
 	}
@@ -17,7 +17,15 @@
 package log
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -46,7 +54,7 @@ func TestConsoleAppender(t *testing.T) {
 		a := &ConsoleAppender{
 			AppenderBase: AppenderBase{
 				Layout: &TextLayout{
-					BaseLayout{
+					BaseLayout: BaseLayout{
 						FileLineMaxLength: 48,
 					},
 				},
@@ -70,6 +78,28 @@ func TestConsoleAppender(t *testing.T) {
 		assert.String(t, string(b)).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello world\n")
 	})
 
+	t.Run("nil layout", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		Stdout = buf
+		defer func() {
+			Stdout = os.Stdout
+		}()
+
+		a := &ConsoleAppender{}
+		e := &Event{
+			Level:  InfoLevel,
+			File:   "file.go",
+			Line:   100,
+			Tag:    "_def",
+			Fields: []Field{Msg("hello world")},
+		}
+		a.Append(e)
+		a.AppendBatch([]*Event{e})
+
+		const line = "[INFO][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello world\n"
+		assert.String(t, buf.String()).Equal(line + line)
+	})
+
 	//t.Run("write directly", func(t *testing.T) {
 	//	file, err := os.CreateTemp(os.TempDir(), "")
 	//	assert.Error(t, err).Nil()
@@ -97,7 +127,7 @@ func TestFileAppender(t *testing.T) {
 		a := &FileAppender{
 			AppenderBase: AppenderBase{
 				Layout: &TextLayout{
-					BaseLayout{
+					BaseLayout: BaseLayout{
 						FileLineMaxLength: 48,
 					},
 				},
@@ -108,6 +138,21 @@ func TestFileAppender(t *testing.T) {
 		assert.Error(t, err).Matches("open /not-exist-dir/file.log: no such file or directory")
 	})
 
+	t.Run("nil layout", func(t *testing.T) {
+		a := &FileAppender{
+			FileDir:  t.TempDir(),
+			FileName: "file.log",
+		}
+		err := a.Start()
+		assert.Error(t, err).Nil()
+		a.Append(&Event{Level: InfoLevel, Tag: "_def"})
+		a.Stop()
+
+		b, err := os.ReadFile(filepath.Join(a.FileDir, a.FileName))
+		assert.Error(t, err).Nil()
+		assert.String(t, string(b)).Equal("[INFO][0001-01-01T00:00:00.000][:0] _def||\n")
+	})
+
 	t.Run("success", func(t *testing.T) {
 		file, err := os.CreateTemp(os.TempDir(), "")
 		assert.Error(t, err).Nil()
@@ -117,7 +162,7 @@ func TestFileAppender(t *testing.T) {
 		a := &FileAppender{
 			AppenderBase: AppenderBase{
 				Layout: &TextLayout{
-					BaseLayout{
+					BaseLayout: BaseLayout{
 						FileLineMaxLength: 48,
 					},
 				},
@@ -139,11 +184,65 @@ func TestFileAppender(t *testing.T) {
 
 		a.Stop()
 
-		b, err := os.ReadFile(a.file.Name())
+		b, err := os.ReadFile(file.Name())
 		assert.Error(t, err).Nil()
 		assert.String(t, string(b)).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello world\n")
 	})
 
+	t.Run("write after stop", func(t *testing.T) {
+		var errs []error
+		reportError := ReportError
+		ReportError = func(err error) { errs = append(errs, err) }
+		defer func() { ReportError = reportError }()
+
+		a := &FileAppender{
+			AppenderBase: AppenderBase{Name: "file"},
+			FileDir:      t.TempDir(),
+			FileName:     "file.log",
+		}
+		err := a.Start()
+		assert.Error(t, err).Nil()
+		a.Append(&Event{Tag: "_def", Fields: []Field{Msg("before")}})
+		a.Stop()
+		a.Stop()
+
+		a.Append(&Event{Tag: "_def", Fields: []Field{Msg("after")}})
+		a.AppendBatch([]*Event{{Tag: "_def", Fields: []Field{Msg("after")}}})
+		assert.Number(t, len(errs)).Equal(2)
+		assert.Error(t, errs[0]).Matches("file appender file is not started")
+
+		b, err := os.ReadFile(filepath.Join(a.FileDir, a.FileName))
+		assert.Error(t, err).Nil()
+		assert.String(t, string(b)).Equal("[][0001-01-01T00:00:00.000][:0] _def||msg=before\n")
+	})
+
+	t.Run("line prefix", func(t *testing.T) {
+		a := &FileAppender{
+			FileDir:    t.TempDir(),
+			FileName:   "file.log",
+			LinePrefix: "local0: ",
+		}
+		err := a.Start()
+		assert.Error(t, err).Nil()
+		a.Append(&Event{Level: InfoLevel, Tag: "_def", Fields: []Field{Msg("hello")}})
+		a.Append(&Event{RawBytes: []byte("raw 1\nraw 2\n")})
+		a.AppendBatch([]*Event{
+			{Level: WarnLevel, Tag: "_def"},
+			{RawBytes: []byte("raw 3\n")},
+		})
+		a.Stop()
+
+		b, err := os.ReadFile(filepath.Join(a.FileDir, a.FileName))
+		assert.Error(t, err).Nil()
+		assert.That(t, bytes.HasPrefix(b, []byte("\xef\xbb\xbf"))).False()
+		assert.String(t, string(b)).Equal("" +
+			"local0: [INFO][0001-01-01T00:00:00.000][:0] _def||msg=hello\n" +
+			"local0: raw 1\n" +
+			"local0: raw 2\n" +
+			"local0: [WARN][0001-01-01T00:00:00.000][:0] _def||\n" +
+			"local0: raw 3\n")
+	})
+
 	//t.Run("write directly", func(t *testing.T) {
 	//	file, err := os.CreateTemp(os.TempDir(), "")
 	//	assert.Error(t, err).Nil()
@@ -181,3 +280,732 @@ func TestFileAppender(t *testing.T) {
 		a.Stop()
 	})
 }
+
+func TestRollingFileWriter(t *testing.T) {
+
+	t.Run("pattern error", func(t *testing.T) {
+		a := &RollingFileAppender{
+			FileDir:     t.TempDir(),
+			FileName:    "app.log",
+			Interval:    30 * time.Minute,
+			FilePattern: "2006010215",
+		}
+		err := a.Start()
+		assert.Error(t, err).Matches(`filePattern "2006010215" cannot distinguish intervals of 30m0s`)
+		a.Stop() // doesn't panic
+	})
+
+	for _, interval := range []time.Duration{10 * time.Minute, 30 * time.Minute} {
+		t.Run(interval.String(), func(t *testing.T) {
+			dir := t.TempDir()
+			w := &RollingFileWriter{
+				fileDir:  dir,
+				fileName: "app.log",
+				interval: interval,
+			}
+			defer w.Close()
+
+			start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+			var names []string
+			for i := range 3 {
+				// any time inside the slot yields the slot start
+				now := start.Add(time.Duration(i)*interval + interval/2)
+				f, err := w.rotate(now)
+				assert.Error(t, err).Nil()
+				names = append(names, filepath.Base(f.Name()))
+			}
+
+			for i := range 3 {
+				slot := start.Add(time.Duration(i) * interval)
+				assert.String(t, names[i]).Equal("app.log." + slot.Format(DefaultFilePattern))
+			}
+			assert.That(t, names[0] != names[1] && names[1] != names[2]).True()
+		})
+	}
+}
+
+func TestCaptureAppender(t *testing.T) {
+	a := &CaptureAppender{}
+	err := a.Start()
+	assert.Error(t, err).Nil()
+	defer a.Stop()
+
+	l := &SyncLogger{
+		LoggerBase: LoggerBase{
+			Level: LevelRange{MinLevel: InfoLevel, MaxLevel: MaxLevel},
+		},
+		AppenderRefs: []*AppenderRef{
+			{Appender: a, Level: LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}},
+		},
+	}
+
+	// The logger resets the events after appending them.
+	l.Append(&Event{Level: InfoLevel, Tag: "_def", Fields: []Field{Msg("user login")}})
+	l.Append(&Event{Level: WarnLevel, Tag: "_def", Fields: []Field{Msg("slow query"), Int("ms", 800)}})
+	l.Append(&Event{Level: ErrorLevel, Tag: "_com_request_in", Fields: []Field{Msg("user not found")}})
+	l.Append(&Event{Level: DebugLevel, Tag: "_def", Fields: []Field{Msg("not captured")}})
+
+	assert.Number(t, a.Len()).Equal(3)
+	assert.Number(t, len(a.Events())).Equal(3)
+
+	events := a.FilterLevel(WarnLevel)
+	assert.Number(t, len(events)).Equal(1)
+	assert.String(t, events[0].Message()).Equal("slow query")
+	assert.That(t, events[0].Fields[1]).Equal(Int("ms", 800))
+
+	events = a.FilterTag("_com_request_in")
+	assert.Number(t, len(events)).Equal(1)
+	assert.That(t, events[0].Level).Equal(ErrorLevel)
+
+	events = a.FilterMessage("user")
+	assert.Number(t, len(events)).Equal(2)
+	assert.String(t, events[0].Message()).Equal("user login")
+	assert.String(t, events[1].Message()).Equal("user not found")
+
+	assert.Number(t, len(a.FilterMessage("timeout"))).Equal(0)
+
+	a.Clear()
+	assert.Number(t, a.Len()).Equal(0)
+}
+
+func TestReopenFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := &FileAppender{FileDir: dir, FileName: "file.log"}
+	err := a.Start()
+	assert.Error(t, err).Nil()
+	defer a.Stop()
+
+	a.Append(&Event{RawBytes: []byte("line 1\n")})
+
+	// logrotate moves the file away, the appender still writes to it
+	err = os.Rename(filepath.Join(dir, "file.log"), filepath.Join(dir, "file.log.1"))
+	assert.Error(t, err).Nil()
+	a.Append(&Event{RawBytes: []byte("line 2\n")})
+
+	// files left open by other tests may fail to reopen
+	err = ReopenFiles()
+	assert.That(t, err == nil || !strings.Contains(err.Error(), dir)).True()
+	a.Append(&Event{RawBytes: []byte("line 3\n")})
+
+	b, err := os.ReadFile(filepath.Join(dir, "file.log.1"))
+	assert.Error(t, err).Nil()
+	assert.String(t, string(b)).Equal("line 1\nline 2\n")
+	b, err = os.ReadFile(filepath.Join(dir, "file.log"))
+	assert.Error(t, err).Nil()
+	assert.String(t, string(b)).Equal("line 3\n")
+
+	// a file that cannot be reopened keeps being written
+	err = os.RemoveAll(dir)
+	assert.Error(t, err).Nil()
+	err = ReopenFiles()
+	assert.Error(t, err).Matches("reopen file " + dir + "/file.log error")
+	a.Append(&Event{RawBytes: []byte("line 4\n")})
+}
+
+func TestFileAppenderTruncate(t *testing.T) {
+	for _, truncate := range []bool{false, true} {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "file.log"), []byte("old line\n"), 0644)
+		assert.Error(t, err).Nil()
+
+		a := &FileAppender{
+			FileDir:  dir,
+			FileName: "file.log",
+			Truncate: truncate,
+		}
+		err = a.Start()
+		assert.Error(t, err).Nil()
+		a.Append(&Event{RawBytes: []byte("new line\n")})
+		a.Stop()
+
+		b, err := os.ReadFile(filepath.Join(dir, "file.log"))
+		assert.Error(t, err).Nil()
+		if truncate {
+			assert.String(t, string(b)).Equal("new line\n")
+		} else {
+			assert.String(t, string(b)).Equal("old line\nnew line\n")
+		}
+	}
+}
+
+func TestRollingFileAppenderRotateOnStart(t *testing.T) {
+	dir := t.TempDir()
+	for range 3 {
+		a := &RollingFileAppender{
+			FileDir:       dir,
+			FileName:      "app.log",
+			Interval:      24 * time.Hour,
+			RotateOnStart: true,
+		}
+		assert.Error(t, a.Sync()).Nil() // not started
+		err := a.Start()
+		assert.Error(t, err).Nil()
+		a.Append(&Event{RawBytes: []byte("hello\n")})
+		assert.Error(t, a.Sync()).Nil()
+		a.Stop()
+	}
+
+	entries, err := os.ReadDir(dir)
+	assert.Error(t, err).Nil()
+	assert.Number(t, len(entries)).Equal(3)
+
+	name := entries[0].Name()
+	assert.String(t, entries[1].Name()).Equal(name + ".1")
+	assert.String(t, entries[2].Name()).Equal(name + ".2")
+	for _, entry := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		assert.Error(t, err).Nil()
+		assert.String(t, string(b)).Equal("hello\n")
+	}
+
+	// the file dir is a regular file, so probing the file fails with
+	// an error other than not-exist, which must not loop forever
+	notDir := filepath.Join(dir, entries[0].Name())
+	a := &RollingFileAppender{
+		FileDir:       notDir,
+		FileName:      "app.log",
+		Interval:      24 * time.Hour,
+		RotateOnStart: true,
+	}
+	err = a.Start()
+	assert.Error(t, err).Matches("not a directory")
+}
+
+func TestRollingFileAppenderLinePrefix(t *testing.T) {
+	dir := t.TempDir()
+	a := &RollingFileAppender{
+		FileDir:    dir,
+		FileName:   "app.log",
+		Interval:   24 * time.Hour,
+		LinePrefix: "local0: ",
+	}
+	err := a.Start()
+	assert.Error(t, err).Nil()
+	a.Append(&Event{Level: InfoLevel, Tag: "_def", Fields: []Field{Msg("hello")}})
+	a.Append(&Event{RawBytes: []byte("raw 1\nraw 2")})
+	a.Stop()
+
+	entries, err := os.ReadDir(dir)
+	assert.Error(t, err).Nil()
+	assert.Number(t, len(entries)).Equal(1)
+	b, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	assert.Error(t, err).Nil()
+	assert.String(t, string(b)).Equal("" +
+		"local0: [INFO][0001-01-01T00:00:00.000][:0] _def||msg=hello\n" +
+		"local0: raw 1\n" +
+		"local0: raw 2")
+}
+
+func TestRollingFileWriterMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+
+	// files of other writers sharing the prefix are kept
+	other := filepath.Join(dir, "app.log.wf.20250601120000")
+	err := os.WriteFile(other, nil, 0644)
+	assert.Error(t, err).Nil()
+
+	const maxBackups = 3
+	w := &RollingFileWriter{
+		fileDir:    dir,
+		fileName:   "app.log",
+		interval:   time.Minute,
+		maxAge:     24 * time.Hour,
+		maxBackups: maxBackups,
+		closeDelay: time.Millisecond,
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex // the appender lock
+		slot  int
+	)
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for range 8 {
+		wg.Go(func() {
+			for range 20 {
+				mutex.Lock()
+				slot++
+				f, err := w.rotate(start.Add(time.Duration(slot) * time.Minute))
+				assert.Error(t, err).Nil()
+				_, err = f.Write([]byte("hello\n"))
+				assert.Error(t, err).Nil()
+				_, err = os.Stat(f.Name())
+				assert.Error(t, err).Nil()
+				mutex.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	w.pending.Wait()
+	w.Close()
+
+	var names []string
+	entries, err := os.ReadDir(dir)
+	assert.Error(t, err).Nil()
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "app.log.2025") {
+			names = append(names, e.Name())
+		}
+	}
+	assert.Number(t, len(names)).Equal(maxBackups + 1)
+	assert.That(t, names[maxBackups]).Equal(filepath.Base(w.currFile.Name()))
+
+	_, err = os.Stat(other)
+	assert.Error(t, err).Nil()
+}
+
+func TestRollingFileWriterRelativeDir(t *testing.T) {
+	t.Chdir(t.TempDir())
+	err := os.Mkdir("logs", 0755)
+	assert.Error(t, err).Nil()
+
+	w := &RollingFileWriter{
+		fileDir:    "./logs",
+		fileName:   "app.log",
+		interval:   time.Minute,
+		maxAge:     24 * time.Hour,
+		maxBackups: 1,
+		closeDelay: time.Millisecond,
+	}
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		_, err = w.rotate(start.Add(time.Duration(i) * time.Minute))
+		assert.Error(t, err).Nil()
+	}
+	w.pending.Wait()
+	w.Close()
+
+	// the current file is not counted as a backup
+	entries, err := os.ReadDir("logs")
+	assert.Error(t, err).Nil()
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.That(t, names).Equal([]string{"app.log.20250601120200", "app.log.20250601120300"})
+	assert.String(t, w.currFile.Name()).Equal(filepath.Join(filepath.Dir(w.currFile.Name()), "app.log.20250601120300"))
+}
+
+func TestReportError(t *testing.T) {
+	var errs []error
+	reportError := ReportError
+	ReportError = func(err error) { errs = append(errs, err) }
+	defer func() { ReportError = reportError }()
+
+	// a regular file where the log directory should be
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	err := os.WriteFile(blocker, nil, 0644)
+	assert.Error(t, err).Nil()
+
+	a := &RollingFileAppender{
+		FileDir:  blocker,
+		FileName: "app.log",
+		Interval: time.Hour,
+	}
+	err = a.Start()
+	assert.Error(t, err).Nil()
+	defer a.Stop()
+
+	a.Append(&Event{Tag: "_def", Fields: []Field{Msg("hello")}})
+	assert.Number(t, len(errs)).Equal(1)
+	assert.Error(t, errs[0]).Matches("rotate file app.log error: .*blocker")
+}
+
+func TestChannelAppender(t *testing.T) {
+
+	t.Run("unsupported policy", func(t *testing.T) {
+		a := NewChannelAppender(make(chan *Event, 1), BufferFullPolicyDropOldest)
+		err := a.Start()
+		assert.Error(t, err).Matches("BufferFullPolicy 2 is not supported")
+
+		err = NewChannelAppender(nil, BufferFullPolicyBlock).Start()
+		assert.Error(t, err).Matches("channel is nil")
+	})
+
+	t.Run("cloned events", func(t *testing.T) {
+		ch := make(chan *Event, 2)
+		a := NewChannelAppender(ch, BufferFullPolicyBlock)
+		err := a.Start()
+		assert.Error(t, err).Nil()
+		defer a.Stop()
+
+		e := getEvent()
+		e.Level = InfoLevel
+		e.Tag = "_def"
+		e.Fields = []Field{Msg("hello")}
+		a.Append(e)
+		e.Reset()
+
+		x := <-ch
+		assert.That(t, x != e).True()
+		assert.That(t, x.Level).Equal(InfoLevel)
+		assert.String(t, x.Tag).Equal("_def")
+		assert.String(t, x.Message()).Equal("hello")
+	})
+
+	t.Run("discard when full", func(t *testing.T) {
+		ch := make(chan *Event, 1)
+		a := NewChannelAppender(ch, BufferFullPolicyDiscard)
+		err := a.Start()
+		assert.Error(t, err).Nil()
+
+		a.Append(&Event{Fields: []Field{Msg("first")}})
+		a.Append(&Event{Fields: []Field{Msg("second")}})
+		assert.Number(t, a.GetDiscardCounter()).Equal(int64(1))
+		assert.String(t, (<-ch).Message()).Equal("first")
+	})
+
+	t.Run("block when full", func(t *testing.T) {
+		ch := make(chan *Event, 1)
+		a := NewChannelAppender(ch, BufferFullPolicyBlock)
+		err := a.Start()
+		assert.Error(t, err).Nil()
+
+		a.Append(&Event{Fields: []Field{Msg("first")}})
+		done := make(chan struct{})
+		go func() {
+			a.Append(&Event{Fields: []Field{Msg("second")}})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("append should block while the channel is full")
+		case <-time.After(50 * time.Millisecond):
+		}
+		assert.String(t, (<-ch).Message()).Equal("first")
+		<-done
+		assert.String(t, (<-ch).Message()).Equal("second")
+		assert.Number(t, a.GetDiscardCounter()).Equal(int64(0))
+	})
+}
+
+func TestRollingFileWriterCleanupWorker(t *testing.T) {
+
+	t.Run("coalesced requests", func(t *testing.T) {
+		w := &RollingFileWriter{fileDir: t.TempDir(), fileName: "app.log"}
+		w.startCleanupWorker()
+
+		// requests made during a scan are merged into one more scan
+		w.mutex.Lock()
+		for range 100 {
+			w.requestCleanup()
+		}
+		w.mutex.Unlock()
+		w.Close()
+		w.Close()
+
+		assert.Number(t, w.cleanups).GreaterThan(0)
+		assert.Number(t, w.cleanups).LessThan(3)
+
+		// requests after Close are ignored
+		w.requestCleanup()
+	})
+
+	t.Run("rotations", func(t *testing.T) {
+		const rotations = 100
+		w := &RollingFileWriter{
+			fileDir:    t.TempDir(),
+			fileName:   "app.log",
+			interval:   time.Minute,
+			maxAge:     24 * time.Hour,
+			maxBackups: 2,
+			closeDelay: time.Millisecond,
+		}
+
+		start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		for i := range rotations {
+			_, err := w.rotate(start.Add(time.Duration(i) * time.Minute))
+			assert.Error(t, err).Nil()
+		}
+		w.pending.Wait()
+		w.Close()
+
+		assert.Number(t, w.cleanups).GreaterThan(0)
+		assert.Number(t, w.cleanups).LessThan(rotations)
+
+		entries, err := os.ReadDir(w.fileDir)
+		assert.Error(t, err).Nil()
+		assert.Number(t, len(entries)).Equal(3)
+	})
+}
+
+func TestWriteEventSize(t *testing.T) {
+	events := []*Event{
+		{Level: InfoLevel, File: "file.go", Line: 100, Tag: "_def", Fields: []Field{Msg(strings.Repeat("x", 4096))}},
+		{Level: WarnLevel, File: "file.go", Line: 101, Tag: "_def", Fields: []Field{Msg("short"), Int("code", 1)}},
+		{RawBytes: []byte("raw line\n")},
+	}
+	for _, layout := range []Layout{&TextLayout{}, &JSONLayout{}} {
+		buf := bytes.NewBuffer(nil)
+		total := 0
+		for _, e := range events {
+			before := buf.Len()
+			n := WriteEvent(buf, e, layout)
+			assert.Number(t, n).Equal(buf.Len() - before)
+			total += n
+		}
+		assert.Number(t, total).Equal(buf.Len())
+
+		// a small event written after a large one with a pooled buffer
+		// reports its own size
+		buf.Reset()
+		n := WriteEvent(buf, events[1], layout)
+		assert.Number(t, n).Equal(buf.Len())
+		assert.Number(t, n).LessThan(200)
+
+		buf.Reset()
+		n = WriteEvents(buf, events, layout)
+		assert.Number(t, n).Equal(buf.Len())
+		assert.Number(t, n).Equal(total)
+	}
+}
+
+// gatedAppender captures the events once the gate is open.
+type gatedAppender struct {
+	CaptureAppender
+	gate    chan struct{}
+	stopped atomic.Bool
+}
+
+func (c *gatedAppender) Append(e *Event) {
+	<-c.gate
+	c.CaptureAppender.Append(e)
+}
+
+func (c *gatedAppender) Stop() { c.stopped.Store(true) }
+
+// syncedAppender counts the syncs of a gatedAppender.
+type syncedAppender struct {
+	gatedAppender
+	syncs atomic.Int32
+	err   error
+}
+
+func (c *syncedAppender) Sync() error {
+	c.syncs.Add(1)
+	return c.err
+}
+
+func TestAsyncAppender(t *testing.T) {
+
+	t.Run("drain on stop", func(t *testing.T) {
+		wrapped := &gatedAppender{gate: make(chan struct{})}
+		a := &AsyncAppender{Appender: wrapped, BufferSize: 100, OnBufferFull: BufferFullPolicyBlock}
+		err := a.Start()
+		assert.Error(t, err).Nil()
+
+		// Append doesn't wait for the wrapped appender, and the
+		// events survive being reset by the logger
+		for i := range 10 {
+			e := getEvent()
+			e.Level = InfoLevel
+			e.Fields = []Field{Msgf("event %d", i)}
+			a.Append(e)
+			e.Reset()
+		}
+		assert.Number(t, wrapped.Len()).Equal(0)
+
+		close(wrapped.gate)
+		a.Stop()
+		a.Stop()
+		assert.That(t, wrapped.stopped.Load()).True()
+		events := wrapped.Events()
+		assert.Number(t, len(events)).Equal(10)
+		for i, e := range events {
+			assert.String(t, e.Message()).Equal(fmt.Sprintf("event %d", i))
+		}
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		wrapped := &gatedAppender{gate: make(chan struct{})}
+		a := &AsyncAppender{Appender: wrapped, BufferSize: 100, OnBufferFull: BufferFullPolicyDiscard}
+		err := a.Start()
+		assert.Error(t, err).Nil()
+		for range 150 {
+			a.Append(&Event{Level: InfoLevel})
+		}
+		// the worker holds one event, the buffer the next 100
+		assert.Number(t, a.GetDiscardCounter()).GreaterThan(int64(48))
+		close(wrapped.gate)
+		a.Stop()
+		assert.Number(t, int64(wrapped.Len())+a.GetDiscardCounter()).Equal(int64(150))
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		wrapped := &syncedAppender{gatedAppender: gatedAppender{gate: make(chan struct{})}}
+		a := &AsyncAppender{Appender: wrapped, BufferSize: 100, OnBufferFull: BufferFullPolicyDropOldest}
+		err := a.Start()
+		assert.Error(t, err).Nil()
+		assert.Error(t, (&AsyncAppender{}).Sync()).Nil() // not started
+
+		// the worker holds the first event, the buffer the next 99
+		a.Append(&Event{Level: InfoLevel})
+		for len(a.buf) > 0 {
+			runtime.Gosched()
+		}
+		for range 99 {
+			a.Append(&Event{Level: InfoLevel})
+		}
+
+		// the sync marker fills the buffer, and is never dropped
+		done := make(chan error)
+		go func() { done <- a.Sync() }()
+		for len(a.buf) < 100 {
+			runtime.Gosched()
+		}
+		for range 200 {
+			a.Append(&Event{Level: InfoLevel})
+		}
+
+		wrapped.err = errors.New("sync error")
+		close(wrapped.gate)
+		assert.Error(t, <-done).Matches("sync error")
+		assert.Number(t, wrapped.syncs.Load()).Equal(int32(1))
+		a.Stop()
+		assert.Number(t, int64(wrapped.Len())+a.GetDiscardCounter()).Equal(int64(300))
+	})
+
+	t.Run("after stop", func(t *testing.T) {
+		wrapped := &syncedAppender{gatedAppender: gatedAppender{gate: make(chan struct{})}}
+		close(wrapped.gate)
+		a := &AsyncAppender{Appender: wrapped, BufferSize: 100, OnBufferFull: BufferFullPolicyBlock}
+		err := a.Start()
+		assert.Error(t, err).Nil()
+
+		// appends and syncs racing with Stop neither panic nor block
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				for range 100 {
+					a.Append(&Event{Level: InfoLevel})
+					_ = a.Sync()
+				}
+			})
+		}
+		a.Stop()
+		wg.Wait()
+		assert.Number(t, int64(wrapped.Len())+a.GetDiscardCounter()).Equal(int64(400))
+
+		n := a.GetDiscardCounter()
+		a.Append(&Event{Level: InfoLevel})
+		assert.Number(t, a.GetDiscardCounter()).Equal(n + 1)
+		syncs := wrapped.syncs.Load()
+		assert.Error(t, a.Sync()).Nil()
+		assert.Number(t, wrapped.syncs.Load()).Equal(syncs)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		err := (&AsyncAppender{BufferSize: 100}).Start()
+		assert.Error(t, err).Matches("appender is nil")
+		err = (&AsyncAppender{Appender: &DiscardAppender{}, BufferSize: 10}).Start()
+		assert.Error(t, err).Matches("bufferSize is too small")
+		(&AsyncAppender{}).Stop() // not started
+	})
+
+	t.Run("config", func(t *testing.T) {
+		dir := t.TempDir()
+		err := RefreshConfig(map[string]string{
+			"appender.console.type":              "ConsoleAppender",
+			"appender.file.type":                 "AsyncAppender",
+			"appender.file.bufferSize":           "1000",
+			"appender.file.appender.type":        "FileAppender",
+			"appender.file.appender.dir":         dir,
+			"appender.file.appender.file":        "app.log",
+			"appender.file.appender.layout":      "TextLayout{}",
+			"logger.root.type":                   "Logger",
+			"logger.root.level":                  "info",
+			"logger.root.appenderRef[0].ref":     "file",
+			"logger.myLogger.type":               "Logger",
+			"logger.myLogger.tag":                "_com_request_*",
+			"logger.myLogger.appenderRef[0].ref": "console",
+		})
+		assert.Error(t, err).Nil()
+		for i := range 100 {
+			Infof(t.Context(), TagAppDef, "event %d", i)
+		}
+		Destroy()
+
+		b, err := os.ReadFile(filepath.Join(dir, "app.log"))
+		assert.Error(t, err).Nil()
+		assert.Number(t, bytes.Count(b, []byte("\n"))).Equal(100)
+	})
+}
+
+// retainAppender keeps the events without copying them, which is a bug.
+type retainAppender struct {
+	DiscardAppender
+	events []*Event
+}
+
+func (c *retainAppender) Append(e *Event) { c.events = append(c.events, e) }
+
+func TestDisablePooling(t *testing.T) {
+	DisablePooling = true
+	defer func() { DisablePooling = false }()
+
+	seen := make(map[*Event]bool)
+	bufs := make(map[*bytes.Buffer]bool)
+	for range 100 {
+		e := getEvent()
+		assert.That(t, seen[e]).False()
+		seen[e] = true
+		e.Reset()
+
+		buf := getBuffer()
+		assert.That(t, bufs[buf]).False()
+		bufs[buf] = true
+		putBuffer(buf)
+	}
+
+	// a retained event is reset, but never reused by another event
+	a := &retainAppender{}
+	l := &SyncLogger{
+		LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}},
+		AppenderRefs: []*AppenderRef{{Appender: a}},
+	}
+	for _, msg := range []string{"first", "second"} {
+		e := getEvent()
+		e.Level = InfoLevel
+		e.Fields = []Field{Msg(msg)}
+		l.Append(e)
+	}
+	assert.Number(t, len(a.events)).Equal(2)
+	assert.That(t, a.events[0] != a.events[1]).True()
+	assert.String(t, a.events[0].Message()).Equal("")
+	assert.That(t, a.events[0].Level).Equal(NoneLevel)
+}
+
+func TestRender(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	Stdout = buf
+	defer func() {
+		Stdout = os.Stdout
+	}()
+
+	e := &Event{
+		Level:  InfoLevel,
+		File:   "file.go",
+		Line:   100,
+		Tag:    "_def",
+		Fields: []Field{Msg("hello world")},
+	}
+	for _, layout := range []Layout{nil, &TextLayout{}, &JSONLayout{}} {
+		buf.Reset()
+		(&ConsoleAppender{AppenderBase: AppenderBase{Layout: layout}}).Append(e)
+		b := Render(layout, e)
+		assert.String(t, string(b)).Equal(buf.String())
+	}
+
+	// the result doesn't share memory with the pooled buffer
+	b1 := Render(nil, e)
+	want := string(b1)
+	_ = Render(nil, &Event{Level: WarnLevel, Fields: []Field{Msg("other")}})
+	assert.String(t, string(b1)).Equal(want)
+
+	raw := &Event{RawBytes: []byte("raw data\n")}
+	b := Render(&JSONLayout{}, raw)
+	assert.String(t, string(b)).Equal("raw data\n")
+	b[0] = 'R'
+	assert.String(t, string(raw.RawBytes)).Equal("raw data\n")
+}
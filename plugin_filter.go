@@ -0,0 +1,103 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package log
+
+import (
+	"slices"
+	"strings"
+)
+
+func init() {
+	RegisterPlugin[LevelFilter]("LevelFilter")
+	RegisterPlugin[TagFilter]("TagFilter")
+	RegisterPlugin[MarkerFilter]("MarkerFilter")
+}
+
+var (
+	_ Filter = (*LevelFilter)(nil)
+	_ Filter = (*TagFilter)(nil)
+	_ Filter = (*MarkerFilter)(nil)
+)
+
+// Filter decides whether a logger accepts an event, beyond its level
+// range, e.g. to drop noisy events by message or field. Filters are
+// plugins like layouts and appenders: an implementation is registered
+// with RegisterPlugin and configured as a "filter" element of a logger,
+// e.g. logger.root.filter[0].type=MyFilter, see LoggerBase.Filters.
+//
+// Accept is called in the logging goroutines, before the event is
+// buffered by an async logger, so it must be concurrent-safe and cheap.
+type Filter interface {
+	Accept(e *Event) bool
+}
+
+// acceptFilters reports whether all the filters accept the event.
+func acceptFilters(filters []Filter, e *Event) bool {
+	for _, f := range filters {
+		if !f.Accept(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// LevelFilter accepts the events whose level is in Level, e.g. to keep
+// only some levels of a logger that handles a wider range. Unlike the
+// level of the logger, it is not changed by SetLevel.
+type LevelFilter struct {
+	Level LevelRange `PluginAttribute:"level"`
+}
+
+func (f *LevelFilter) Accept(e *Event) bool {
+	return f.Level.Enable(e.Level)
+}
+
+// TagFilter accepts the events whose tag matches one of Tags, either
+// exactly or by a "xxx_*" pattern. With Deny, it rejects them instead.
+type TagFilter struct {
+	Tags []string `PluginAttribute:"tag"`
+	Deny bool     `PluginAttribute:"deny,default=false"`
+}
+
+func (f *TagFilter) Accept(e *Event) bool {
+	return matchLoggerTags(f.Tags, e.Tag) != f.Deny
+}
+
+// matchLoggerTags reports whether the tag matches any of the logger tags,
+// either exactly or by a "xxx_*" pattern.
+func matchLoggerTags(loggerTags []string, tag string) bool {
+	for _, s := range loggerTags {
+		if prefix, ok := strings.CutSuffix(s, "*"); ok {
+			if strings.HasPrefix(tag, prefix) {
+				return true
+			}
+		} else if s == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkerFilter accepts the events that carry one of Markers, see Marker.
+// With Deny, it rejects them instead.
+type MarkerFilter struct {
+	Markers []string `PluginAttribute:"marker"`
+	Deny    bool     `PluginAttribute:"deny,default=false"`
+}
+
+func (f *MarkerFilter) Accept(e *Event) bool {
+	return slices.ContainsFunc(f.Markers, e.HasMarker) != f.Deny
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package log
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-spring/stdlib/flatten"
+	"github.com/go-spring/stdlib/testing/assert"
+)
+
+func TestLevelFilter(t *testing.T) {
+	f := &LevelFilter{Level: LevelRange{MinLevel: InfoLevel, MaxLevel: ErrorLevel}}
+	assert.That(t, f.Accept(&Event{Level: DebugLevel})).False()
+	assert.That(t, f.Accept(&Event{Level: InfoLevel})).True()
+	assert.That(t, f.Accept(&Event{Level: WarnLevel})).True()
+	assert.That(t, f.Accept(&Event{Level: ErrorLevel})).False()
+}
+
+func TestTagFilter(t *testing.T) {
+	f := &TagFilter{Tags: []string{"_def", "_com_request_*"}}
+	assert.That(t, f.Accept(&Event{Tag: "_def"})).True()
+	assert.That(t, f.Accept(&Event{Tag: "_com_request_in"})).True()
+	assert.That(t, f.Accept(&Event{Tag: "_com_db_query"})).False()
+
+	f.Deny = true
+	assert.That(t, f.Accept(&Event{Tag: "_def"})).False()
+	assert.That(t, f.Accept(&Event{Tag: "_com_request_in"})).False()
+	assert.That(t, f.Accept(&Event{Tag: "_com_db_query"})).True()
+}
+
+func TestMarkerFilter(t *testing.T) {
+	audit := &Event{Fields: []Field{Msg("login"), Marker("AUDIT")}}
+	security := &Event{CtxFields: []Field{Marker("SECURITY")}}
+	plain := &Event{Fields: []Field{Msg("hello"), String("marker_", "AUDIT")}}
+
+	assert.That(t, audit.HasMarker("AUDIT")).True()
+	assert.That(t, audit.HasMarker("SECURITY")).False()
+	assert.That(t, security.HasMarker("SECURITY")).True()
+	assert.That(t, plain.HasMarker("AUDIT")).False()
+
+	f := &MarkerFilter{Markers: []string{"AUDIT", "SECURITY"}}
+	assert.That(t, f.Accept(audit)).True()
+	assert.That(t, f.Accept(security)).True()
+	assert.That(t, f.Accept(plain)).False()
+
+	f = &MarkerFilter{Markers: []string{"AUDIT"}, Deny: true}
+	assert.That(t, f.Accept(audit)).False()
+	assert.That(t, f.Accept(security)).True()
+	assert.That(t, f.Accept(plain)).True()
+}
+
+func TestLoggerFiltersRejectEvents(t *testing.T) {
+	s := flatten.NewPropertiesStorage(flatten.NewProperties(nil))
+	s.Set("logger.level", "debug")
+	s.Set("logger.appenderRef.ref", "capture")
+	s.Set("logger.filter[0].type", "LevelFilter")
+	s.Set("logger.filter[0].level", "info..error")
+	s.Set("logger.filter[1].type", "TagFilter")
+	s.Set("logger.filter[1].tag", "_com_health_*")
+	s.Set("logger.filter[1].deny", "true")
+	s.Set("logger.filter[2].type", "MarkerFilter")
+	s.Set("logger.filter[2].marker", "DEBUG_ONLY")
+	s.Set("logger.filter[2].deny", "true")
+	v, err := newPlugin(reflect.TypeFor[SyncLogger](), "logger", s)
+	assert.Error(t, err).Nil()
+
+	a := &CaptureAppender{}
+	l := v.Interface().(*SyncLogger)
+	l.AppenderRefs[0].Appender = a
+	assert.Number(t, len(l.Filters)).Equal(3)
+
+	l.Append(&Event{Level: DebugLevel, Tag: "_def", Fields: []Field{Msg("debug")}})
+	l.Append(&Event{Level: InfoLevel, Tag: "_def", Fields: []Field{Msg("info")}})
+	l.Append(&Event{Level: InfoLevel, Tag: "_com_health_check", Fields: []Field{Msg("health")}})
+	l.Append(&Event{Level: ErrorLevel, Tag: "_com_request_in", Fields: []Field{Msg("error")}})
+	l.Append(&Event{Level: WarnLevel, Tag: "_def", Fields: []Field{Msg("marked"), Marker("DEBUG_ONLY")}})
+	l.Append(&Event{Level: FatalLevel, Tag: "_def", Fields: []Field{Msg("fatal")}})
+
+	var msgs []string
+	for _, e := range a.Events() {
+		msgs = append(msgs, e.Message())
+	}
+	assert.That(t, msgs).Equal([]string{"info", "error"})
+}
@@ -17,58 +17,505 @@
 package log
 
 import (
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spring/stdlib/errutil"
 )
 
 func init() {
 	RegisterPlugin[TextLayout]("TextLayout")
 	RegisterPlugin[JSONLayout]("JSONLayout")
+	RegisterPlugin[CloudEventsLayout]("CloudEventsLayout")
+	defaultFileLineLength.Store(48)
+}
+
+// UseDefaultFileLineLength is the FileLineMaxLength of layouts that use
+// the default file:line length, see SetDefaultFileLineLength. It is the
+// default of the fileLineMaxLength attribute.
+const UseDefaultFileLineLength = -1
+
+// defaultFileLineLength is the file:line length of layouts whose
+// FileLineMaxLength is UseDefaultFileLineLength.
+var defaultFileLineLength atomic.Int64
+
+// SetDefaultFileLineLength sets the maximum file:line length used by layouts
+// that don't specify FileLineMaxLength, including the default logger's,
+// see UseDefaultFileLineLength.
+func SetDefaultFileLineLength(n int) error {
+	if n <= 0 {
+		return errutil.Explain(nil, "file line length %d must be positive", n)
+	}
+	defaultFileLineLength.Store(int64(n))
+	return nil
+}
+
+// TimePrecision specifies the fractional seconds of layout timestamps.
+type TimePrecision int
+
+const (
+	TimePrecisionMillis  = TimePrecision(0) // 2006-01-02T15:04:05.000
+	TimePrecisionSeconds = TimePrecision(1) // 2006-01-02T15:04:05
+	TimePrecisionMicros  = TimePrecision(2) // 2006-01-02T15:04:05.000000
+	TimePrecisionNanos   = TimePrecision(3) // 2006-01-02T15:04:05.000000000
+)
+
+// ParseTimePrecision converts "s", "ms", "us" or "ns" to a TimePrecision.
+func ParseTimePrecision(s string) (TimePrecision, error) {
+	switch s {
+	case "s":
+		return TimePrecisionSeconds, nil
+	case "ms":
+		return TimePrecisionMillis, nil
+	case "us":
+		return TimePrecisionMicros, nil
+	case "ns":
+		return TimePrecisionNanos, nil
+	default:
+		return -1, errutil.Explain(nil, "invalid TimePrecision %s", s)
+	}
+}
+
+// timeLayout returns the time layout of the precision.
+func (p TimePrecision) timeLayout() string {
+	switch p {
+	case TimePrecisionSeconds:
+		return "2006-01-02T15:04:05"
+	case TimePrecisionMicros:
+		return "2006-01-02T15:04:05.000000"
+	case TimePrecisionNanos:
+		return "2006-01-02T15:04:05.000000000"
+	default:
+		return "2006-01-02T15:04:05.000"
+	}
+}
+
+// DefaultLayout is used by appenders whose Layout is nil, e.g. when
+// they are constructed programmatically without a layout.
+var DefaultLayout Layout = &TextLayout{
+	BaseLayout: BaseLayout{
+		FileLineMaxLength: UseDefaultFileLineLength,
+	},
 }
 
 // Layout defines how a log event is encoded into a writer.
 // Implementations should write fully formatted log data to `w`.
 // Layouts do NOT manage memory or buffering; callers are responsible.
+//
+// A layout may also implement Lifecycle if it needs to be prepared before
+// use, e.g. to parse a pattern. The built-in layouts implement it through
+// BaseLayout. Refresh and SyncLoggerBuilder start the layouts of the
+// loggers, appenders and appender refs before the appenders, and stop
+// them after. DefaultLayout and the default logger's layout are started
+// on first use and never stopped.
 type Layout interface {
 	EncodeTo(e *Event, w Writer)
 }
 
+// startLayout starts the layout if it implements Lifecycle.
+func startLayout(layout Layout) error {
+	if l, ok := layout.(Lifecycle); ok {
+		return l.Start()
+	}
+	return nil
+}
+
+// startLayouts starts the layouts in order. If one fails to start,
+// those already started are stopped.
+func startLayouts(layouts []Layout) error {
+	for i, layout := range layouts {
+		if err := startLayout(layout); err != nil {
+			stopLayouts(layouts[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// layoutsNeedGoroutineID reports whether any of the layouts writes
+// goroutine IDs, see captureGoroutineID.
+func layoutsNeedGoroutineID(layouts []Layout) bool {
+	return slices.ContainsFunc(layouts, func(l Layout) bool {
+		x, ok := l.(interface{ needGoroutineID() bool })
+		return ok && x.needGoroutineID()
+	})
+}
+
+// stopLayouts stops the layouts that implement Lifecycle.
+func stopLayouts(layouts []Layout) {
+	for _, layout := range layouts {
+		if l, ok := layout.(Lifecycle); ok {
+			l.Stop()
+		}
+	}
+}
+
 // BaseLayout provides common utilities for layouts, e.g., file:line formatting.
 type BaseLayout struct {
-	FileLineMaxLength int `PluginAttribute:"fileLineMaxLength,default=48"`
+	FileLineMaxLength int  `PluginAttribute:"fileLineMaxLength,default=-1"`
+	EmptyAsNull       bool `PluginAttribute:"emptyAsNull,default=false"`
+	ZeroTimeAsNull    bool `PluginAttribute:"zeroTimeAsNull,default=false"`
+	StripANSI         bool `PluginAttribute:"stripANSI,default=false"`
+	StripControl      bool `PluginAttribute:"stripControl,default=false"`
+	Seq               bool `PluginAttribute:"seq,default=false"`
+	LoggerName        bool `PluginAttribute:"loggerName,default=false"`
+
+	// TimePrecision is the precision of the timestamp, milliseconds by default.
+	TimePrecision TimePrecision `PluginAttribute:"timePrecision,default=ms"`
+
+	// MaxEventBytes, if positive, caps the size of a rendered event. A
+	// larger event is replaced with a placeholder that keeps the header
+	// and reports the original size, e.g. "truncated":true,"origBytes":N.
+	MaxEventBytes int `PluginAttribute:"maxEventBytes,default=0"`
+
+	// DedupeFields keeps a single field per key among the context and
+	// event fields, which are encoded in that order. The last one wins by
+	// default, so event fields override context fields; DedupeKeepFirst
+	// makes the first one win instead. Each kept field stays in its place.
+	DedupeFields    bool `PluginAttribute:"dedupeFields,default=false"`
+	DedupeKeepFirst bool `PluginAttribute:"dedupeKeepFirst,default=false"`
+
+	// IncludeGoroutineID writes the ID of the logging goroutine as a "gid"
+	// field, to correlate events when debugging concurrency issues. Once
+	// such a layout is configured, every event pays about a microsecond to
+	// capture the ID, so it is meant for development only.
+	IncludeGoroutineID bool `PluginAttribute:"includeGoroutineID,default=false"`
+
+	// OmitEmpty drops the context and event fields whose values are empty,
+	// see Field.IsEmpty, for more compact logs. Fields nested in objects
+	// are kept.
+	OmitEmpty bool `PluginAttribute:"omitEmpty,default=false"`
+
+	// TrustKeys makes the encoder write printable ASCII keys in one piece,
+	// see JSONEncoder.SetTrustKeys. The output doesn't change.
+	TrustKeys bool `PluginAttribute:"trustKeys,default=false"`
+
+	// HideMarkers drops the fields created by Marker from the output, for
+	// markers that are only used to filter events, see MarkerFilter.
+	HideMarkers bool `PluginAttribute:"hideMarkers,default=false"`
+}
+
+func (c *BaseLayout) Start() error { return nil }
+func (c *BaseLayout) Stop()        {}
+
+// needGoroutineID reports whether the layout writes goroutine IDs,
+// in which case they have to be captured when events are recorded.
+func (c *BaseLayout) needGoroutineID() bool { return c.IncludeGoroutineID }
+
+// goroutineIDField returns the "gid" field of the event. Events recorded
+// without capture, e.g. for a layout created in code rather than by
+// Refresh, get the ID of the current goroutine, which is only right when
+// the layout runs in the logging goroutine.
+func (c *BaseLayout) goroutineIDField(e *Event) Field {
+	gid := e.GID
+	if gid == 0 {
+		gid = goroutineID()
+	}
+	return Uint("gid", gid)
 }
 
 // GetFileLine returns the "file:line" string for a log event.
 // If the result exceeds FileLineMaxLength,
 // the leading part is truncated and replaced with "...".
+// A FileLineMaxLength of 16 or less, e.g. zero, turns truncation off, and
+// UseDefaultFileLineLength uses the default length, which is 48 unless
+// changed by SetDefaultFileLineLength.
 func (c *BaseLayout) GetFileLine(e *Event) string {
 	fileLine := e.File + ":" + strconv.Itoa(e.Line)
-	if c.FileLineMaxLength <= 16 {
+	maxLength := c.FileLineMaxLength
+	if maxLength == UseDefaultFileLineLength {
+		maxLength = int(defaultFileLineLength.Load())
+	}
+	if maxLength <= 16 {
 		return fileLine
 	}
-	if n := len(fileLine); n > c.FileLineMaxLength {
-		fileLine = "..." + fileLine[n-c.FileLineMaxLength+3:]
+	if n := len(fileLine); n > maxLength {
+		fileLine = "..." + fileLine[n-maxLength+3:]
 	}
 	return fileLine
 }
 
+// eventFields returns the context and event fields to encode, without
+// the fields dropped by DedupeFields and OmitEmpty. The slices of the
+// event are returned as they are when there is nothing to drop.
+func (c *BaseLayout) eventFields(e *Event) (ctxFields []Field, fields []Field) {
+	ctxFields, fields = e.CtxFields, e.Fields
+	if c.DedupeFields {
+		ctxFields, fields = c.dedupe(ctxFields, fields)
+	}
+	if c.OmitEmpty {
+		ctxFields, fields = dropFields(ctxFields, Field.IsEmpty), dropFields(fields, Field.IsEmpty)
+	}
+	if c.HideMarkers {
+		ctxFields, fields = dropFields(ctxFields, isMarkerField), dropFields(fields, isMarkerField)
+	}
+	return
+}
+
+// dropFields returns the fields for which drop returns false. The slice
+// is only copied when it contains a field to drop.
+func dropFields(fields []Field, drop func(Field) bool) []Field {
+	if !slices.ContainsFunc(fields, drop) {
+		return fields
+	}
+	kept := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		if !drop(f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// dedupe returns the context and event fields with a single field per key,
+// see DedupeFields. The slices are returned as they are without duplicates.
+func (c *BaseLayout) dedupe(ctxFields []Field, fields []Field) ([]Field, []Field) {
+	n := len(ctxFields) + len(fields)
+	at := func(i int) *Field {
+		if i < len(ctxFields) {
+			return &ctxFields[i]
+		}
+		return &fields[i-len(ctxFields)]
+	}
+	// dropped reports whether the field at i is overridden by another one.
+	dropped := func(i int) bool {
+		key := at(i).Key
+		if c.DedupeKeepFirst {
+			for j := range i {
+				if at(j).Key == key {
+					return true
+				}
+			}
+			return false
+		}
+		for j := i + 1; j < n; j++ {
+			if at(j).Key == key {
+				return true
+			}
+		}
+		return false
+	}
+	hasDuplicates := false
+	for i := range n {
+		if dropped(i) {
+			hasDuplicates = true
+			break
+		}
+	}
+	if !hasDuplicates {
+		return ctxFields, fields
+	}
+	var keptCtx, kept []Field
+	for i := range n {
+		if dropped(i) {
+			continue
+		}
+		if i < len(ctxFields) {
+			keptCtx = append(keptCtx, *at(i))
+		} else {
+			kept = append(kept, *at(i))
+		}
+	}
+	return keptCtx, kept
+}
+
+// encodeLimited encodes the event with encode, unless the result exceeds
+// MaxEventBytes, in which case a placeholder is encoded instead.
+func (c *BaseLayout) encodeLimited(e *Event, w Writer, encode func(*Event, Writer)) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	encode(e, buf)
+	n := buf.Len()
+	if n <= c.MaxEventBytes {
+		_, _ = w.Write(buf.Bytes())
+		return
+	}
+	encode(&Event{
+		Level:     e.Level,
+		Time:      e.Time,
+		File:      e.File,
+		Line:      e.Line,
+		Tag:       e.Tag,
+		CtxString: e.CtxString,
+		Seq:       e.Seq,
+		Logger:    e.Logger,
+		GID:       e.GID,
+		Fields: []Field{
+			Bool("truncated", true),
+			Int("origBytes", n),
+		},
+	}, w)
+}
+
+// FormatTime formats the timestamp of an event with TimePrecision.
+func (c *BaseLayout) FormatTime(t time.Time) string {
+	return t.Format(c.TimePrecision.timeLayout())
+}
+
+// EncodeEvent encodes the header fields (level, time, fileLine, tag and
+// ctxString, plus logger and seq if enabled), followed by the context
+// fields and the event fields, using the given encoder.
+func (c *BaseLayout) EncodeEvent(enc Encoder, e *Event) {
+	c.encodeEvent(enc, e, String("tag", e.Tag))
+}
+
+// encodeEvent encodes the event like EncodeEvent, using the given tag field,
+// which is skipped if it has no key.
+func (c *BaseLayout) encodeEvent(enc Encoder, e *Event, tag Field) {
+	enc.AppendEncoderBegin()
+
+	// Write basic header fields
+	String("level", e.Level.LowerName()).Encode(enc)
+	String("time", c.FormatTime(e.Time)).Encode(enc)
+	String("fileLine", c.GetFileLine(e)).Encode(enc)
+	if tag.Key != "" {
+		tag.Encode(enc)
+	}
+	if c.LoggerName {
+		String("logger", e.Logger).Encode(enc)
+	}
+	if e.CtxString != "" {
+		String("ctxString", e.CtxString).Encode(enc)
+	}
+	if c.Seq {
+		Uint("seq", e.Seq).Encode(enc)
+	}
+	if c.IncludeGoroutineID {
+		c.goroutineIDField(e).Encode(enc)
+	}
+
+	// Encode structured fields
+	ctxFields, fields := c.eventFields(e)
+	EncodeFields(enc, ctxFields)
+	EncodeFields(enc, fields)
+	enc.AppendEncoderEnd()
+}
+
+// EncoderLayout encodes a log event with an Encoder created by NewEncoder.
+// It allows custom output formats (e.g. logfmt, CSV) to reuse the field
+// and context handling of the built-in layouts by only providing an Encoder.
+type EncoderLayout struct {
+	BaseLayout
+	NewEncoder func(w Writer) Encoder
+}
+
+// NewLayout creates an EncoderLayout that uses the given encoder factory.
+func NewLayout(newEncoder func(w Writer) Encoder) *EncoderLayout {
+	return &EncoderLayout{
+		BaseLayout: BaseLayout{
+			FileLineMaxLength: UseDefaultFileLineLength,
+		},
+		NewEncoder: newEncoder,
+	}
+}
+
+// EncodeTo writes the log event to the provided writer using the encoder.
+func (c *EncoderLayout) EncodeTo(e *Event, w Writer) {
+	if c.MaxEventBytes > 0 {
+		c.encodeLimited(e, w, c.encodeTo)
+		return
+	}
+	c.encodeTo(e, w)
+}
+
+// encodeTo writes the log event regardless of MaxEventBytes.
+func (c *EncoderLayout) encodeTo(e *Event, w Writer) {
+	c.EncodeEvent(c.NewEncoder(w), e)
+	_ = w.WriteByte('\n')
+}
+
+// DefaultMultilineKeys are the keys of the fields printed verbatim by
+// TextLayout when RawMultiline is set and MultilineKeys is empty.
+var DefaultMultilineKeys = []string{"stacktrace", "error"}
+
 // TextLayout encodes a log event as a human-readable text line.
 type TextLayout struct {
 	BaseLayout
+
+	// RawMultiline prints the multi-line string values of the fields in
+	// MultilineKeys verbatim after the log line, with real newlines and
+	// indentation, instead of escaping them. Note that such values are
+	// not escaped, so they should not contain untrusted input.
+	RawMultiline  bool     `PluginAttribute:"rawMultiline,default=false"`
+	MultilineKeys []string `PluginAttribute:"multilineKeys,default="`
+
+	// CompactLevel writes the level as its first letter, e.g. [E] instead
+	// of [ERROR], see CompactLevelName.
+	CompactLevel bool `PluginAttribute:"compactLevel,default=false"`
+
+	// TrailingKeys moves the fields with these keys to the end of the line,
+	// in the given order, e.g. "msg,detail" keeps the message readable after
+	// the other fields.
+	TrailingKeys []string `PluginAttribute:"trailingKeys,default="`
+
+	// PadLevel right-pads the level to the width of the longest built-in
+	// level name, e.g. [INFO ] and [ERROR], and PadTag right-pads the tag
+	// to the given width, so that the columns line up on a console. Longer
+	// names are not cut.
+	PadLevel bool `PluginAttribute:"padLevel,default=false"`
+	PadTag   int  `PluginAttribute:"padTag,default=0"`
+}
+
+// levelPadWidth is the width of the level with PadLevel, which is the
+// length of the longest built-in level name.
+const levelPadWidth = 5
+
+// writePadded writes s followed by spaces up to width bytes.
+func writePadded(w Writer, s string, width int) {
+	_, _ = w.WriteString(s)
+	for i := len(s); i < width; i++ {
+		_ = w.WriteByte(' ')
+	}
+}
+
+// CompactLevelName returns the single-character name of a level used by
+// TextLayout when CompactLevel is set, which is the first letter of its
+// upper name:
+//
+//	NONE → N, TRACE → T, DEBUG → D, INFO → I, WARN → W,
+//	ERROR → E, PANIC → P, FATAL → F, MAX → M
+//
+// The built-in levels have distinct letters. Custom levels sharing the
+// first letter of another level, e.g. NOTICE and NONE, are written the
+// same, so full names should be used to tell them apart.
+func CompactLevelName(l Level) string {
+	if l.upperName == "" {
+		return ""
+	}
+	return l.upperName[:1]
 }
 
 // EncodeTo writes the log event to the provided writer in plain-text format.
 func (c *TextLayout) EncodeTo(e *Event, w Writer) {
+	if c.MaxEventBytes > 0 {
+		c.encodeLimited(e, w, c.encodeTo)
+		return
+	}
+	c.encodeTo(e, w)
+}
+
+// encodeTo writes the log event regardless of MaxEventBytes.
+func (c *TextLayout) encodeTo(e *Event, w Writer) {
 	const separator = "||"
 
 	// Write basic header fields
 	_, _ = w.WriteString("[")
-	_, _ = w.WriteString(e.Level.UpperName())
+	if c.CompactLevel {
+		_, _ = w.WriteString(CompactLevelName(e.Level))
+	} else if c.PadLevel {
+		writePadded(w, e.Level.UpperName(), levelPadWidth)
+	} else {
+		_, _ = w.WriteString(e.Level.UpperName())
+	}
 	_, _ = w.WriteString("][")
-	_, _ = w.WriteString(e.Time.Format("2006-01-02T15:04:05.000"))
+	_, _ = w.WriteString(c.FormatTime(e.Time))
 	_, _ = w.WriteString("][")
 	_, _ = w.WriteString(c.GetFileLine(e))
 	_, _ = w.WriteString("] ")
-	_, _ = w.WriteString(e.Tag)
+	writePadded(w, e.Tag, c.PadTag)
 	_, _ = w.WriteString(separator)
 	if e.CtxString != "" {
 		_, _ = w.WriteString(e.CtxString)
@@ -77,37 +524,267 @@ func (c *TextLayout) EncodeTo(e *Event, w Writer) {
 
 	// Encode structured fields
 	enc := NewTextEncoder(w, separator)
+	enc.SetEmptyAsNull(c.EmptyAsNull)
+	enc.SetZeroTimeAsNull(c.ZeroTimeAsNull)
+	enc.SetStripANSI(c.StripANSI)
+	enc.SetStripControl(c.StripControl)
+	enc.SetTrustKeys(c.TrustKeys)
 	enc.AppendEncoderBegin()
-	EncodeFields(enc, e.CtxFields)
-	EncodeFields(enc, e.Fields)
+	if c.LoggerName {
+		String("logger", e.Logger).Encode(enc)
+	}
+	if c.Seq {
+		Uint("seq", e.Seq).Encode(enc)
+	}
+	if c.IncludeGoroutineID {
+		c.goroutineIDField(e).Encode(enc)
+	}
+	ctxFields, fields := c.eventFields(e)
+	var trailing []Field
+	if c.hasTrailingKeys() {
+		ctxFields, trailing = c.splitTrailing(ctxFields, trailing)
+		fields, trailing = c.splitTrailing(fields, trailing)
+		slices.SortStableFunc(trailing, func(a, b Field) int {
+			return slices.Index(c.TrailingKeys, a.Key) - slices.Index(c.TrailingKeys, b.Key)
+		})
+	}
+	var multiline []Field
+	if c.RawMultiline {
+		multiline = c.encodeInline(enc, ctxFields, multiline)
+		multiline = c.encodeInline(enc, fields, multiline)
+		multiline = c.encodeInline(enc, trailing, multiline)
+	} else {
+		EncodeFields(enc, ctxFields)
+		EncodeFields(enc, fields)
+		EncodeFields(enc, trailing)
+	}
 	enc.AppendEncoderEnd()
 
 	_ = w.WriteByte('\n')
+
+	// Write multi-line values verbatim after the log line
+	for _, f := range multiline {
+		c.writeMultiline(w, f)
+	}
+}
+
+// hasTrailingKeys reports whether any non-empty trailing key is set.
+func (c *TextLayout) hasTrailingKeys() bool {
+	return slices.ContainsFunc(c.TrailingKeys, func(k string) bool { return k != "" })
+}
+
+// splitTrailing appends the fields whose keys are in TrailingKeys to
+// trailing and returns the remaining fields. The input slice is only
+// copied when it contains such a field.
+func (c *TextLayout) splitTrailing(fields []Field, trailing []Field) ([]Field, []Field) {
+	if !slices.ContainsFunc(fields, func(f Field) bool { return slices.Contains(c.TrailingKeys, f.Key) }) {
+		return fields, trailing
+	}
+	front := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		if slices.Contains(c.TrailingKeys, f.Key) {
+			trailing = append(trailing, f)
+		} else {
+			front = append(front, f)
+		}
+	}
+	return front, trailing
+}
+
+// encodeInline encodes the fields, except those printed verbatim,
+// which are appended to multiline and returned.
+func (c *TextLayout) encodeInline(enc Encoder, fields []Field, multiline []Field) []Field {
+	keys := c.MultilineKeys
+	if !slices.ContainsFunc(keys, func(k string) bool { return k != "" }) {
+		keys = DefaultMultilineKeys
+	}
+	for _, f := range fields {
+		if f.Type == ValueTypeString && slices.Contains(keys, f.Key) &&
+			strings.Contains(f.stringValue(), "\n") {
+			multiline = append(multiline, f)
+			continue
+		}
+		f.Encode(enc)
+	}
+	return multiline
+}
+
+// writeMultiline writes the key of the field, followed by each line
+// of its value, all indented so that they are seen as continuation lines.
+func (c *TextLayout) writeMultiline(w Writer, f Field) {
+	s := f.stringValue()
+	if c.StripANSI || c.StripControl {
+		s = StripString(s, c.StripANSI, c.StripControl)
+	}
+	_, _ = w.WriteString("  ")
+	_, _ = w.WriteString(f.Key)
+	_, _ = w.WriteString(":\n")
+	for line := range strings.Lines(s) {
+		_, _ = w.WriteString("    ")
+		_, _ = w.WriteString(strings.TrimSuffix(line, "\n"))
+		_ = w.WriteByte('\n')
+	}
 }
 
 // JSONLayout encodes a log event as a structured JSON object.
+// If TagAsArray is set, the tag is written as an array of its segments,
+// e.g. ["com","request","in"] for "_com_request_in". If Pretty is set,
+// the event is indented over several lines, which is meant for local
+// debugging only, since log processors usually expect one line per event.
 type JSONLayout struct {
 	BaseLayout
+	TagAsArray bool `PluginAttribute:"tagAsArray,default=false"`
+	Pretty     bool `PluginAttribute:"pretty,default=false"`
+
+	// TagKey is the key of the tag, e.g. "log.logger" for ECS. It is
+	// written as is, so "labels.tag" is a flat key, not a nested object.
+	// "-" omits the tag. An empty key means "tag" rather than omitting
+	// it, since it is also the zero value, and a JSONLayout built in code
+	// without TagKey must keep writing the tag.
+	TagKey string `PluginAttribute:"tagKey,default=tag"`
 }
 
 // EncodeTo writes the log event to the provided writer in JSON format.
 func (c *JSONLayout) EncodeTo(e *Event, w Writer) {
+	if c.MaxEventBytes > 0 {
+		c.encodeLimited(e, w, c.encodeTo)
+		return
+	}
+	c.encodeTo(e, w)
+}
+
+// encodeTo writes the log event regardless of MaxEventBytes.
+func (c *JSONLayout) encodeTo(e *Event, w Writer) {
 	enc := NewJSONEncoder(w)
+	if c.Pretty {
+		enc = NewPrettyJSONEncoder(w, "  ")
+	}
+	enc.SetEmptyAsNull(c.EmptyAsNull)
+	enc.SetZeroTimeAsNull(c.ZeroTimeAsNull)
+	enc.SetStripANSI(c.StripANSI)
+	enc.SetStripControl(c.StripControl)
+	enc.SetTrustKeys(c.TrustKeys)
+	c.encodeEvent(enc, e, c.tagField(e))
+	_ = w.WriteByte('\n')
+}
+
+// tagField returns the tag field of the event according to TagKey and
+// TagAsArray, or a field without key if the tag is omitted.
+func (c *JSONLayout) tagField(e *Event) Field {
+	key := c.TagKey
+	switch key {
+	case "-":
+		return Field{}
+	case "":
+		key = "tag"
+	}
+	if c.TagAsArray {
+		return Strings(key, splitTag(e.Tag))
+	}
+	return String(key, e.Tag)
+}
+
+// splitTag splits a tag into its segments, dropping the leading underscore,
+// e.g. "_com_request_in" becomes ["com", "request", "in"].
+func splitTag(tag string) []string {
+	return strings.Split(strings.TrimPrefix(tag, "_"), "_")
+}
+
+// cloudEventsIDPrefix makes the IDs of CloudEvents unique across restarts
+// of the process, since sequence numbers start over.
+var cloudEventsIDPrefix = strconv.FormatInt(time.Now().UnixNano(), 36) + "-"
+
+// CloudEventsLayout encodes a log event as a CloudEvent in structured
+// mode JSON, so that logs can flow through the same bus as other events.
+// The envelope attributes are specversion "1.0", id, source, type, time
+// and datacontenttype "application/json". The header fields and the
+// context and event fields are written under "data".
+//
+// Source is required. Type is a template where {level}, {tag} and
+// {logger} are replaced with the level name, the tag with its segments
+// joined by dots, e.g. "com.request.in", and the logger name. It is
+// configured as "eventType", since "type" selects the layout plugin,
+// and it must not be empty. The id
+// is the event sequence number, prefixed with the start time of the
+// process.
+type CloudEventsLayout struct {
+	BaseLayout
+	Source string `PluginAttribute:"source"`
+	Type   string `PluginAttribute:"eventType,default=log.{level}"`
+}
+
+// Start checks the envelope attributes.
+func (c *CloudEventsLayout) Start() error {
+	if c.Source == "" {
+		return errutil.Explain(nil, "cloudevents source is empty")
+	}
+	if c.Type == "" {
+		return errutil.Explain(nil, "cloudevents type is empty")
+	}
+	return nil
+}
+
+// EncodeTo writes the log event to the provided writer as a CloudEvent.
+func (c *CloudEventsLayout) EncodeTo(e *Event, w Writer) {
+	if c.MaxEventBytes > 0 {
+		c.encodeLimited(e, w, c.encodeTo)
+		return
+	}
+	c.encodeTo(e, w)
+}
+
+// encodeTo writes the log event regardless of MaxEventBytes.
+func (c *CloudEventsLayout) encodeTo(e *Event, w Writer) {
+	enc := NewJSONEncoder(w)
+	enc.SetEmptyAsNull(c.EmptyAsNull)
+	enc.SetZeroTimeAsNull(c.ZeroTimeAsNull)
+	enc.SetStripANSI(c.StripANSI)
+	enc.SetStripControl(c.StripControl)
+	enc.SetTrustKeys(c.TrustKeys)
 	enc.AppendEncoderBegin()
 
-	// Write basic header fields
+	// Write the envelope attributes
+	String("specversion", "1.0").Encode(enc)
+	String("id", cloudEventsIDPrefix+strconv.FormatUint(e.Seq, 10)).Encode(enc)
+	String("source", c.Source).Encode(enc)
+	String("type", c.eventType(e)).Encode(enc)
+	String("time", e.Time.Format(c.TimePrecision.timeLayout()+"Z07:00")).Encode(enc)
+	String("datacontenttype", "application/json").Encode(enc)
+
+	enc.AppendKey("data")
+	enc.AppendObjectBegin()
 	String("level", e.Level.LowerName()).Encode(enc)
-	String("time", e.Time.Format("2006-01-02T15:04:05.000")).Encode(enc)
 	String("fileLine", c.GetFileLine(e)).Encode(enc)
 	String("tag", e.Tag).Encode(enc)
+	if c.LoggerName {
+		String("logger", e.Logger).Encode(enc)
+	}
 	if e.CtxString != "" {
 		String("ctxString", e.CtxString).Encode(enc)
 	}
+	if c.Seq {
+		Uint("seq", e.Seq).Encode(enc)
+	}
+	if c.IncludeGoroutineID {
+		c.goroutineIDField(e).Encode(enc)
+	}
+	ctxFields, fields := c.eventFields(e)
+	EncodeFields(enc, ctxFields)
+	EncodeFields(enc, fields)
+	enc.AppendObjectEnd()
 
-	// Encode structured fields
-	EncodeFields(enc, e.CtxFields)
-	EncodeFields(enc, e.Fields)
 	enc.AppendEncoderEnd()
-
 	_ = w.WriteByte('\n')
 }
+
+// eventType returns the type attribute of the event from the Type template.
+func (c *CloudEventsLayout) eventType(e *Event) string {
+	if !strings.Contains(c.Type, "{") {
+		return c.Type
+	}
+	return strings.NewReplacer(
+		"{level}", e.Level.LowerName(),
+		"{tag}", strings.Join(splitTag(e.Tag), "."),
+		"{logger}", e.Logger,
+	).Replace(c.Type)
+}
@@ -17,9 +17,20 @@
 package log
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-spring/stdlib/errutil"
+	"github.com/go-spring/stdlib/flatten"
+	"github.com/go-spring/stdlib/testing/assert"
 )
 
 func TestParseHumanizeBytes(t *testing.T) {
@@ -80,6 +91,32 @@ func TestParseHumanizeBytes(t *testing.T) {
 	}
 }
 
+func TestHumanizeBytes(t *testing.T) {
+	for _, c := range []struct {
+		n    int64
+		want string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{1023, "1023B"},
+		{1024, "1KB"},
+		{1536, "1.5KB"},
+		{1024*1024 + 1024*1024/2, "1.5MB"},
+		{1024*1024 - 1024, "1023KB"},
+		{1048524, "1023.9KB"},
+		{1048525, "1MB"},
+		{1024*1024 - 1, "1MB"},
+		{1024*1024*1024 - 1, "1GB"},
+		{-(1024*1024 - 1), "-1MB"},
+		{10 * 1024 * 1024 * 1024, "10GB"},
+		{-2048, "-2KB"},
+		{math.MaxInt64, "8EB"},
+		{math.MinInt64, "-8EB"},
+	} {
+		assert.String(t, HumanizeBytes(c.n)).Equal(c.want)
+	}
+}
+
 func TestBaseLayout(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -127,6 +164,75 @@ func TestBaseLayout(t *testing.T) {
 	}
 }
 
+// pairEncoder is a trivial Encoder that writes "key:value;" pairs.
+type pairEncoder struct {
+	out Writer
+}
+
+func (enc *pairEncoder) AppendEncoderBegin()     {}
+func (enc *pairEncoder) AppendEncoderEnd()       {}
+func (enc *pairEncoder) AppendObjectBegin()      {}
+func (enc *pairEncoder) AppendObjectEnd()        {}
+func (enc *pairEncoder) AppendArrayBegin()       {}
+func (enc *pairEncoder) AppendArrayEnd()         {}
+func (enc *pairEncoder) AppendKey(key string)    { _, _ = enc.out.WriteString(key + ":") }
+func (enc *pairEncoder) AppendBool(v bool)       { enc.appendValue(v) }
+func (enc *pairEncoder) AppendInt64(v int64)     { enc.appendValue(v) }
+func (enc *pairEncoder) AppendUint64(v uint64)   { enc.appendValue(v) }
+func (enc *pairEncoder) AppendFloat64(v float64) { enc.appendValue(v) }
+func (enc *pairEncoder) AppendString(v string)   { enc.appendValue(v) }
+func (enc *pairEncoder) AppendReflect(v any)     { enc.appendValue(v) }
+
+func (enc *pairEncoder) appendValue(v any) {
+	_, _ = fmt.Fprintf(enc.out, "%v;", v)
+}
+
+func TestEncoderLayout(t *testing.T) {
+	layout := NewLayout(func(w Writer) Encoder {
+		return &pairEncoder{out: w}
+	})
+	buf := bytes.NewBuffer(nil)
+	layout.EncodeTo(&Event{
+		Level:     InfoLevel,
+		Time:      time.Time{},
+		File:      "file.go",
+		Line:      100,
+		Tag:       "_def",
+		Fields:    []Field{Msg("hello world"), Int("count", 3)},
+		CtxString: "trace",
+		CtxFields: []Field{Bool("ok", true)},
+	}, buf)
+	assert.String(t, buf.String()).Equal("level:info;time:0001-01-01T00:00:00.000;fileLine:file.go:100;" +
+		"tag:_def;ctxString:trace;ok:true;msg:hello world;count:3;\n")
+
+	// the options of BaseLayout apply to the fields and the size
+	layout.OmitEmpty = true
+	layout.HideMarkers = true
+	layout.DedupeFields = true
+	buf.Reset()
+	layout.EncodeTo(&Event{
+		Level:  InfoLevel,
+		File:   "file.go",
+		Line:   100,
+		Tag:    "_def",
+		Fields: []Field{Msg("hello"), String("empty", ""), Marker("audit"), Msg("world")},
+	}, buf)
+	assert.String(t, buf.String()).Equal("level:info;time:0001-01-01T00:00:00.000;fileLine:file.go:100;" +
+		"tag:_def;msg:world;\n")
+
+	layout.MaxEventBytes = 80
+	buf.Reset()
+	layout.EncodeTo(&Event{
+		Level:  InfoLevel,
+		File:   "file.go",
+		Line:   100,
+		Tag:    "_def",
+		Fields: []Field{Msg(strings.Repeat("x", 100))},
+	}, buf)
+	assert.String(t, buf.String()).Equal("level:info;time:0001-01-01T00:00:00.000;fileLine:file.go:100;" +
+		"tag:_def;truncated:true;origBytes:176;\n")
+}
+
 //func TestTextLayout(t *testing.T) {
 //
 //	t.Run("without ctx string & fields", func(t *testing.T) {
@@ -240,3 +346,615 @@ func TestBaseLayout(t *testing.T) {
 //		assert.String(t, string(b)).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","key":"value","msg":"hello world"}` + "\n")
 //	})
 //}
+
+func TestTextLayoutRawMultiline(t *testing.T) {
+	const stack = "goroutine 1 [running]:\nmain.main()\n\t/app/main.go:10 +0x1d\n"
+	e := &Event{
+		Level: ErrorLevel,
+		Time:  time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		File:  "file.go",
+		Line:  100,
+		Tag:   "_def",
+		Fields: []Field{
+			Msg("request failed"),
+			String("stacktrace", stack),
+			String("error", "single line"),
+			Int("code", 500),
+		},
+	}
+	const header = "[ERROR][2025-06-01T00:00:00.000][file.go:100] _def||"
+
+	t.Run("disabled", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		(&TextLayout{}).EncodeTo(e, buf)
+		const expect = header + `msg=request failed||stacktrace=goroutine 1 [running]:\nmain.main()\n\t/app/main.go:10 +0x1d\n||error=single line||code=500` + "\n"
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		(&TextLayout{RawMultiline: true}).EncodeTo(e, buf)
+		const expect = header + "msg=request failed||error=single line||code=500\n" +
+			"  stacktrace:\n" +
+			"    goroutine 1 [running]:\n" +
+			"    main.main()\n" +
+			"    \t/app/main.go:10 +0x1d\n"
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("custom keys", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		l := &TextLayout{RawMultiline: true, MultilineKeys: []string{"msg"}}
+		l.EncodeTo(&Event{Tag: "_def", Fields: []Field{Msg("a\nb"), String("stacktrace", "c\nd")}}, buf)
+		const expect = `[][0001-01-01T00:00:00.000][:0] _def||stacktrace=c\nd` + "\n  msg:\n    a\n    b\n"
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("json escaped", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		(&JSONLayout{}).EncodeTo(e, buf)
+		assert.String(t, buf.String()).Matches(`"stacktrace":"goroutine 1 \[running\]:\\nmain.main\(\)\\n\\t/app/main.go:10 \+0x1d\\n"`)
+	})
+}
+
+func TestJSONLayoutTagAsArray(t *testing.T) {
+	newEvent := func(tag string) *Event {
+		return &Event{
+			Level:  InfoLevel,
+			Time:   time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			File:   "file.go",
+			Line:   100,
+			Tag:    tag,
+			Fields: []Field{Msg("hello")},
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		(&JSONLayout{}).EncodeTo(newEvent("_com_request_in"), buf)
+		const expect = `{"level":"info","time":"2025-06-01T00:00:00.000","fileLine":"file.go:100","tag":"_com_request_in","msg":"hello"}` + "\n"
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("multi segments", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		(&JSONLayout{TagAsArray: true}).EncodeTo(newEvent("_com_request_in"), buf)
+		const expect = `{"level":"info","time":"2025-06-01T00:00:00.000","fileLine":"file.go:100","tag":["com","request","in"],"msg":"hello"}` + "\n"
+		assert.String(t, buf.String()).Equal(expect)
+	})
+
+	t.Run("single segment", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		(&JSONLayout{TagAsArray: true}).EncodeTo(newEvent("_def"), buf)
+		const expect = `{"level":"info","time":"2025-06-01T00:00:00.000","fileLine":"file.go:100","tag":["def"],"msg":"hello"}` + "\n"
+		assert.String(t, buf.String()).Equal(expect)
+	})
+}
+
+func TestSetDefaultFileLineLength(t *testing.T) {
+	defer func() { _ = SetDefaultFileLineLength(48) }()
+
+	err := SetDefaultFileLineLength(0)
+	assert.Error(t, err).Matches("file line length 0 must be positive")
+
+	e := &Event{File: "very/long/path/to/file.go", Line: 100}
+	l := &BaseLayout{FileLineMaxLength: UseDefaultFileLineLength}
+	assert.String(t, l.GetFileLine(e)).Equal("very/long/path/to/file.go:100")
+
+	err = SetDefaultFileLineLength(20)
+	assert.Error(t, err).Nil()
+	assert.String(t, l.GetFileLine(e)).Equal("...th/to/file.go:100")
+
+	// an explicit length is kept, and zero still turns truncation off
+	assert.String(t, (&BaseLayout{FileLineMaxLength: 24}).GetFileLine(e)).Equal("...g/path/to/file.go:100")
+	assert.String(t, (&BaseLayout{}).GetFileLine(e)).Equal("very/long/path/to/file.go:100")
+
+	// the attribute defaults to the default length
+	s := flatten.NewPropertiesStorage(flatten.NewProperties(nil))
+	v, err := newPlugin(reflect.TypeFor[TextLayout](), "layout", s)
+	assert.Error(t, err).Nil()
+	assert.String(t, v.Interface().(*TextLayout).GetFileLine(e)).Equal("...th/to/file.go:100")
+}
+
+func TestLayoutSeq(t *testing.T) {
+	e := &Event{Level: InfoLevel, File: "file.go", Line: 100, Tag: "_def", Seq: 42, Fields: []Field{Msg("hello")}}
+
+	buf := bytes.NewBuffer(nil)
+	(&TextLayout{BaseLayout: BaseLayout{Seq: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _def||seq=42||msg=hello\n")
+
+	buf.Reset()
+	(&JSONLayout{BaseLayout: BaseLayout{Seq: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","seq":42,"msg":"hello"}` + "\n")
+
+	buf.Reset()
+	(&JSONLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","msg":"hello"}` + "\n")
+}
+
+func TestEventSeq(t *testing.T) {
+	defer Destroy()
+
+	a := &CaptureAppender{}
+	_, err := NewSyncLoggerBuilder().Appender(a).Tags("_com_request_*").Build()
+	assert.Error(t, err).Nil()
+
+	tag := RegisterTag("_com_request_in")
+	ctx := context.Background()
+	for range 5 {
+		Record(ctx, InfoLevel, tag, 2, Msg("hello"))
+	}
+
+	events := a.Events()
+	assert.Number(t, len(events)).Equal(5)
+	for i := 1; i < len(events); i++ {
+		assert.Number(t, events[i].Seq).GreaterThan(events[i-1].Seq)
+	}
+}
+
+func TestEventLoggerName(t *testing.T) {
+	defer Destroy()
+
+	a := &CaptureAppender{}
+	_, err := NewSyncLoggerBuilder().Name("access").Appender(a).Tags("_com_request_*").Build()
+	assert.Error(t, err).Nil()
+
+	tag := RegisterTag("_com_request_in")
+	Record(context.Background(), InfoLevel, tag, 2, Msg("hello"))
+
+	events := a.Events()
+	assert.Number(t, len(events)).Equal(1)
+	e := events[0]
+	assert.String(t, e.Tag).Equal("_com_request_in")
+	assert.String(t, e.Logger).Equal("access")
+
+	e.Time = time.Time{}
+	e.File, e.Line = "file.go", 100
+
+	buf := bytes.NewBuffer(nil)
+	(&JSONLayout{BaseLayout: BaseLayout{LoggerName: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_com_request_in","logger":"access","msg":"hello"}` + "\n")
+
+	buf.Reset()
+	(&TextLayout{BaseLayout: BaseLayout{LoggerName: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _com_request_in||logger=access||msg=hello\n")
+}
+
+func TestMaxEventBytes(t *testing.T) {
+	huge := strings.Repeat("x", 4096)
+	newEvent := func(fields ...Field) *Event {
+		return &Event{
+			Level:  InfoLevel,
+			Time:   time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			File:   "file.go",
+			Line:   100,
+			Tag:    "_def",
+			Fields: fields,
+		}
+	}
+
+	t.Run("json", func(t *testing.T) {
+		l := &JSONLayout{BaseLayout: BaseLayout{MaxEventBytes: 1024}}
+		buf := bytes.NewBuffer(nil)
+		l.EncodeTo(newEvent(Msg("hello")), buf)
+		assert.String(t, buf.String()).Equal(`{"level":"info","time":"2025-06-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","msg":"hello"}` + "\n")
+
+		buf.Reset()
+		l.EncodeTo(newEvent(Msg("hello"), String("data", huge)), buf)
+		assert.String(t, buf.String()).Equal(`{"level":"info","time":"2025-06-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","truncated":true,"origBytes":4208}` + "\n")
+	})
+
+	t.Run("text", func(t *testing.T) {
+		l := &TextLayout{BaseLayout: BaseLayout{MaxEventBytes: 1024}}
+		buf := bytes.NewBuffer(nil)
+		l.EncodeTo(newEvent(Msg("hello")), buf)
+		assert.String(t, buf.String()).Equal("[INFO][2025-06-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+
+		buf.Reset()
+		l.EncodeTo(newEvent(Msg("hello"), String("data", huge)), buf)
+		assert.String(t, buf.String()).Equal("[INFO][2025-06-01T00:00:00.000][file.go:100] _def||truncated=true||origBytes=4164\n")
+	})
+
+	t.Run("ctx string", func(t *testing.T) {
+		l := &TextLayout{BaseLayout: BaseLayout{MaxEventBytes: 1024}}
+		buf := bytes.NewBuffer(nil)
+		e := newEvent(Msg("hello"), String("data", huge))
+		e.CtxString = "trace_id=abc"
+		l.EncodeTo(e, buf)
+		assert.String(t, buf.String()).Equal("[INFO][2025-06-01T00:00:00.000][file.go:100] _def||trace_id=abc||truncated=true||origBytes=4178\n")
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		(&JSONLayout{}).EncodeTo(newEvent(String("data", huge)), buf)
+		assert.Number(t, buf.Len()).GreaterThan(4096)
+	})
+}
+
+func TestCompactLevel(t *testing.T) {
+	for level, name := range map[Level]string{
+		NoneLevel:  "N",
+		TraceLevel: "T",
+		DebugLevel: "D",
+		InfoLevel:  "I",
+		WarnLevel:  "W",
+		ErrorLevel: "E",
+		PanicLevel: "P",
+		FatalLevel: "F",
+		MaxLevel:   "M",
+	} {
+		assert.String(t, CompactLevelName(level)).Equal(name)
+	}
+	assert.String(t, CompactLevelName(Level{})).Equal("")
+
+	e := &Event{Level: ErrorLevel, File: "file.go", Line: 100, Tag: "_def", Fields: []Field{Msg("hello")}}
+	buf := bytes.NewBuffer(nil)
+	(&TextLayout{CompactLevel: true}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal("[E][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+
+	buf.Reset()
+	(&TextLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal("[ERROR][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+}
+
+func TestPadColumns(t *testing.T) {
+	encode := func(l *TextLayout, level Level, tag string) string {
+		e := &Event{Level: level, File: "file.go", Line: 100, Tag: tag, Fields: []Field{Msg("hello")}}
+		buf := bytes.NewBuffer(nil)
+		l.EncodeTo(e, buf)
+		return buf.String()
+	}
+
+	// unpadded by default
+	assert.String(t, encode(&TextLayout{}, InfoLevel, "_def")).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+
+	l := &TextLayout{PadLevel: true}
+	assert.String(t, encode(l, InfoLevel, "_def")).Equal("[INFO ][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+	assert.String(t, encode(l, WarnLevel, "_def")).Equal("[WARN ][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+	assert.String(t, encode(l, ErrorLevel, "_def")).Equal("[ERROR][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+	assert.String(t, encode(l, MaxLevel, "_def")).Equal("[MAX  ][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+
+	l = &TextLayout{PadTag: 16}
+	assert.String(t, encode(l, InfoLevel, "_def")).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _def            ||msg=hello\n")
+	assert.String(t, encode(l, InfoLevel, "_com_request_in")).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _com_request_in ||msg=hello\n")
+	assert.String(t, encode(l, InfoLevel, "_com_request_out_x")).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _com_request_out_x||msg=hello\n")
+
+	// the compact level is not padded
+	l = &TextLayout{CompactLevel: true, PadLevel: true}
+	assert.String(t, encode(l, InfoLevel, "_def")).Equal("[I][0001-01-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+}
+
+func TestTimePrecision(t *testing.T) {
+	e := &Event{
+		Level:  InfoLevel,
+		Time:   time.Date(2025, 6, 1, 12, 30, 45, 123456789, time.UTC),
+		File:   "file.go",
+		Line:   100,
+		Tag:    "_def",
+		Fields: []Field{Msg("hello")},
+	}
+	for s, want := range map[string]string{
+		"s":  "2025-06-01T12:30:45",
+		"ms": "2025-06-01T12:30:45.123",
+		"us": "2025-06-01T12:30:45.123456",
+		"ns": "2025-06-01T12:30:45.123456789",
+	} {
+		p, err := ParseTimePrecision(s)
+		assert.Error(t, err).Nil()
+
+		buf := bytes.NewBuffer(nil)
+		(&TextLayout{BaseLayout: BaseLayout{TimePrecision: p}}).EncodeTo(e, buf)
+		assert.String(t, buf.String()).Equal("[INFO][" + want + "][file.go:100] _def||msg=hello\n")
+
+		buf.Reset()
+		(&JSONLayout{BaseLayout: BaseLayout{TimePrecision: p}}).EncodeTo(e, buf)
+		assert.String(t, buf.String()).Equal(`{"level":"info","time":"` + want + `","fileLine":"file.go:100","tag":"_def","msg":"hello"}` + "\n")
+	}
+
+	// Milliseconds by default.
+	buf := bytes.NewBuffer(nil)
+	(&TextLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal("[INFO][2025-06-01T12:30:45.123][file.go:100] _def||msg=hello\n")
+
+	_, err := ParseTimePrecision("minute")
+	assert.Error(t, err).Matches("invalid TimePrecision minute")
+}
+
+func TestEncoderLayoutPreEncoded(t *testing.T) {
+	// Encoders without AppendPreEncoded get the raw bytes via AppendReflect.
+	buf := bytes.NewBuffer(nil)
+	enc := &pairEncoder{out: buf}
+	PreEncoded("v", []byte(`{"a":1}`)).Encode(enc)
+	assert.String(t, buf.String()).Equal(`v:{"a":1};`)
+}
+
+func TestTrailingKeys(t *testing.T) {
+	assert.That(t, Detail("full story")).Equal(String("detail", "full story"))
+
+	e := &Event{
+		Level:     InfoLevel,
+		File:      "file.go",
+		Line:      100,
+		Tag:       "_def",
+		CtxFields: []Field{String("traceId", "abc")},
+		Fields:    []Field{Detail("full story"), Msg("short"), Int("code", 1)},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	(&TextLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _def||traceId=abc||detail=full story||msg=short||code=1\n")
+
+	buf.Reset()
+	(&TextLayout{TrailingKeys: []string{"msg", "detail"}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _def||traceId=abc||code=1||msg=short||detail=full story\n")
+
+	buf.Reset()
+	(&TextLayout{TrailingKeys: []string{"traceId"}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal("[INFO][0001-01-01T00:00:00.000][file.go:100] _def||detail=full story||msg=short||code=1||traceId=abc\n")
+
+	buf.Reset()
+	(&JSONLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","traceId":"abc","detail":"full story","msg":"short","code":1}` + "\n")
+}
+
+func TestDedupeFields(t *testing.T) {
+	e := &Event{
+		Level:     InfoLevel,
+		File:      "file.go",
+		Line:      100,
+		Tag:       "_def",
+		CtxFields: []Field{String("user_id", "ctx"), String("traceId", "abc")},
+		Fields:    []Field{Msg("hello"), String("user_id", "call"), Int("code", 1), Int("code", 2)},
+	}
+	const header = "[INFO][0001-01-01T00:00:00.000][file.go:100] _def||"
+
+	buf := bytes.NewBuffer(nil)
+	(&TextLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(header + "user_id=ctx||traceId=abc||msg=hello||user_id=call||code=1||code=2\n")
+
+	// the last one wins by default, so event fields override context fields
+	buf.Reset()
+	(&TextLayout{BaseLayout: BaseLayout{DedupeFields: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(header + "traceId=abc||msg=hello||user_id=call||code=2\n")
+
+	buf.Reset()
+	(&TextLayout{BaseLayout: BaseLayout{DedupeFields: true, DedupeKeepFirst: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(header + "user_id=ctx||traceId=abc||msg=hello||code=1\n")
+
+	buf.Reset()
+	(&JSONLayout{BaseLayout: BaseLayout{DedupeFields: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","traceId":"abc","msg":"hello","user_id":"call","code":2}` + "\n")
+
+	// the event itself is left untouched
+	assert.Number(t, len(e.CtxFields)).Equal(2)
+	assert.Number(t, len(e.Fields)).Equal(4)
+
+	// no duplicates, no copies
+	e.Fields = []Field{Msg("hello")}
+	l := &BaseLayout{DedupeFields: true}
+	ctxFields, fields := l.eventFields(e)
+	assert.That(t, &ctxFields[0] == &e.CtxFields[0]).True()
+	assert.That(t, &fields[0] == &e.Fields[0]).True()
+}
+
+func TestIncludeGoroutineID(t *testing.T) {
+	defer captureGoroutineID.Store(false)
+
+	gids := make([]uint64, 2)
+	var wg sync.WaitGroup
+	for i := range gids {
+		wg.Go(func() { gids[i] = goroutineID() })
+	}
+	wg.Wait()
+	assert.Number(t, gids[0]).GreaterThan(0)
+	assert.Number(t, gids[1]).GreaterThan(0)
+	assert.That(t, gids[0] != gids[1]).True()
+
+	// a refreshed layout turns on the capture, and Destroy turns it off
+	captureGoroutineID.Store(false)
+	m := map[string]string{
+		"appender.console.type":                      "ConsoleAppender",
+		"appender.console.layout.type":               "TextLayout",
+		"appender.console.layout.includeGoroutineID": "true",
+		"logger.root.type":                           "Logger",
+		"logger.root.appenderRef.ref":                "console",
+		"logger.myLogger.type":                       "Logger",
+		"logger.myLogger.tag":                        "_com_request_*",
+		"logger.myLogger.appenderRef[0].ref":         "console",
+	}
+	c, err := RefreshConfigWithOptions(m, RefreshOptions{NoStart: true})
+	assert.Error(t, err).Nil()
+	assert.That(t, captureGoroutineID.Load()).False()
+	layout := c.Appenders["console"].(*ConsoleAppender).Layout.(*TextLayout)
+	err = RefreshConfig(m)
+	assert.Error(t, err).Nil()
+	assert.That(t, captureGoroutineID.Load()).True()
+	Destroy()
+	assert.That(t, captureGoroutineID.Load()).False()
+
+	buf := bytes.NewBuffer(nil)
+	for _, gid := range gids {
+		e := &Event{Level: InfoLevel, File: "file.go", Line: 100, Tag: "_def", GID: gid, Fields: []Field{Msg("hello")}}
+		layout.EncodeTo(e, buf)
+	}
+	assert.String(t, buf.String()).Equal(fmt.Sprintf(
+		"[INFO][0001-01-01T00:00:00.000][file.go:100] _def||gid=%d||msg=hello\n"+
+			"[INFO][0001-01-01T00:00:00.000][file.go:100] _def||gid=%d||msg=hello\n", gids[0], gids[1]))
+
+	// an event recorded without capture gets the current goroutine
+	captureGoroutineID.Store(false)
+	buf.Reset()
+	e := &Event{Level: InfoLevel, File: "file.go", Line: 100, Tag: "_def", Fields: []Field{Msg("hello")}}
+	(&JSONLayout{BaseLayout: BaseLayout{IncludeGoroutineID: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(fmt.Sprintf(
+		`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","gid":%d,"msg":"hello"}`+"\n", goroutineID()))
+	assert.That(t, captureGoroutineID.Load()).False()
+}
+
+func TestJSONLayoutTagKey(t *testing.T) {
+	e := &Event{Level: InfoLevel, File: "file.go", Line: 100, Tag: "_com_request_in", Fields: []Field{Msg("hello")}}
+	const header = `{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100",`
+	for key, want := range map[string]string{
+		"":           header + `"tag":"_com_request_in","msg":"hello"}` + "\n",
+		"tag":        header + `"tag":"_com_request_in","msg":"hello"}` + "\n",
+		"log.logger": header + `"log.logger":"_com_request_in","msg":"hello"}` + "\n",
+		"-":          header + `"msg":"hello"}` + "\n",
+	} {
+		buf := bytes.NewBuffer(nil)
+		(&JSONLayout{TagKey: key}).EncodeTo(e, buf)
+		assert.String(t, buf.String()).Equal(want)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	(&JSONLayout{TagKey: "labels.tag", TagAsArray: true}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(header + `"labels.tag":["com","request","in"],"msg":"hello"}` + "\n")
+
+	s := flatten.NewPropertiesStorage(flatten.NewProperties(nil))
+	v, err := newPlugin(reflect.TypeFor[JSONLayout](), "layout", s)
+	assert.Error(t, err).Nil()
+	assert.String(t, v.Interface().(*JSONLayout).TagKey).Equal("tag")
+}
+
+func TestOmitEmpty(t *testing.T) {
+	e := &Event{
+		Level:     InfoLevel,
+		File:      "file.go",
+		Line:      100,
+		Tag:       "_def",
+		CtxFields: []Field{String("traceId", ""), String("spanId", "s1")},
+		Fields:    []Field{Msg("hello"), String("user", ""), Int("retries", 0), Bool("ok", false), Nil("err"), Object("o", Int("n", 0))},
+	}
+	const header = "[INFO][0001-01-01T00:00:00.000][file.go:100] _def||"
+
+	buf := bytes.NewBuffer(nil)
+	(&TextLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(header + `traceId=||spanId=s1||msg=hello||user=||retries=0||ok=false||err=null||o={"n":0}` + "\n")
+
+	buf.Reset()
+	(&TextLayout{BaseLayout: BaseLayout{OmitEmpty: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(header + `spanId=s1||msg=hello||o={"n":0}` + "\n")
+
+	buf.Reset()
+	(&JSONLayout{BaseLayout: BaseLayout{OmitEmpty: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","spanId":"s1","msg":"hello","o":{"n":0}}` + "\n")
+}
+
+func TestHideMarkers(t *testing.T) {
+	e := &Event{
+		Level:     InfoLevel,
+		File:      "file.go",
+		Line:      100,
+		Tag:       "_def",
+		CtxFields: []Field{Marker("SECURITY")},
+		Fields:    []Field{Msg("login failed"), Marker("AUDIT"), String("user", "bob")},
+	}
+	const header = "[INFO][0001-01-01T00:00:00.000][file.go:100] _def||"
+
+	buf := bytes.NewBuffer(nil)
+	(&TextLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(header + `marker=SECURITY||msg=login failed||marker=AUDIT||user=bob` + "\n")
+
+	buf.Reset()
+	(&JSONLayout{}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","marker":"SECURITY","msg":"login failed","marker":"AUDIT","user":"bob"}` + "\n")
+
+	buf.Reset()
+	(&TextLayout{BaseLayout: BaseLayout{HideMarkers: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(header + `msg=login failed||user=bob` + "\n")
+
+	buf.Reset()
+	(&JSONLayout{BaseLayout: BaseLayout{HideMarkers: true}}).EncodeTo(e, buf)
+	assert.String(t, buf.String()).Equal(`{"level":"info","time":"0001-01-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","msg":"login failed","user":"bob"}` + "\n")
+}
+
+func TestCloudEventsLayout(t *testing.T) {
+	e := &Event{
+		Level:     WarnLevel,
+		Time:      time.Date(2026, 1, 2, 3, 4, 5, 6e6, time.UTC),
+		File:      "file.go",
+		Line:      100,
+		Tag:       "_com_request_in",
+		Seq:       7,
+		Logger:    "access",
+		CtxFields: []Field{String("traceId", "t1")},
+		Fields:    []Field{Msg("slow request"), Int("elapsed", 250)},
+	}
+
+	decode := func(layout Layout) map[string]any {
+		buf := bytes.NewBuffer(nil)
+		layout.EncodeTo(e, buf)
+		assert.That(t, strings.Count(buf.String(), "\n")).Equal(1)
+		var m map[string]any
+		assert.Error(t, json.Unmarshal(buf.Bytes(), &m)).Nil()
+		return m
+	}
+
+	m := decode(&CloudEventsLayout{Source: "/svc/order", Type: "log.{level}"})
+	assert.String(t, m["specversion"].(string)).Equal("1.0")
+	assert.String(t, m["id"].(string)).Matches("^[0-9a-z]+-7$")
+	assert.String(t, m["source"].(string)).Equal("/svc/order")
+	assert.String(t, m["type"].(string)).Equal("log.warn")
+	assert.String(t, m["datacontenttype"].(string)).Equal("application/json")
+	ts, err := time.Parse(time.RFC3339Nano, m["time"].(string))
+	assert.Error(t, err).Nil()
+	assert.That(t, ts.Equal(e.Time)).True()
+	assert.That(t, m["data"]).Equal(map[string]any{
+		"level":    "warn",
+		"fileLine": "file.go:100",
+		"tag":      "_com_request_in",
+		"traceId":  "t1",
+		"msg":      "slow request",
+		"elapsed":  float64(250),
+	})
+
+	m = decode(&CloudEventsLayout{
+		BaseLayout: BaseLayout{LoggerName: true},
+		Source:     "/svc/order",
+		Type:       "com.example.{tag}.{logger}.{level}",
+	})
+	assert.String(t, m["type"].(string)).Equal("com.example.com.request.in.access.warn")
+	assert.String(t, m["data"].(map[string]any)["logger"].(string)).Equal("access")
+
+	// the id stays unique across events
+	e2 := *e
+	e2.Seq = 8
+	buf := bytes.NewBuffer(nil)
+	(&CloudEventsLayout{Source: "/svc/order", Type: "log"}).EncodeTo(&e2, buf)
+	assert.String(t, buf.String()).Matches(`"id":"[0-9a-z]+-8","source":"/svc/order","type":"log",`)
+
+	s := flatten.NewPropertiesStorage(flatten.NewProperties(map[string]string{
+		"layout.source": "/svc/order",
+	}))
+	v, err := newPlugin(reflect.TypeFor[CloudEventsLayout](), "layout", s)
+	assert.Error(t, err).Nil()
+	assert.String(t, v.Interface().(*CloudEventsLayout).Type).Equal("log.{level}")
+
+	s = flatten.NewPropertiesStorage(flatten.NewProperties(nil))
+	_, err = newPlugin(reflect.TypeFor[CloudEventsLayout](), "layout", s)
+	assert.Error(t, err).Matches("no value configured and no default specified")
+
+	err = (&CloudEventsLayout{Source: "/svc/order"}).Start()
+	assert.Error(t, err).Matches("cloudevents type is empty")
+
+	// the type is configured as eventType, and an empty one is rejected
+	// when the configuration is refreshed
+	cfg := map[string]string{
+		"appender.console.type":              "ConsoleAppender",
+		"appender.console.layout.type":       "CloudEventsLayout",
+		"appender.console.layout.source":     "/svc/order",
+		"appender.console.layout.eventType":  "log.{tag}",
+		"logger.root.type":                   "Logger",
+		"logger.root.appenderRef.ref":        "console",
+		"logger.myLogger.type":               "Logger",
+		"logger.myLogger.tag":                "_com_request_*",
+		"logger.myLogger.appenderRef[0].ref": "console",
+	}
+	c, err := RefreshConfigWithOptions(cfg, RefreshOptions{NoStart: true})
+	assert.Error(t, err).Nil()
+	layout := c.Appenders["console"].(*ConsoleAppender).Layout.(*CloudEventsLayout)
+	assert.String(t, layout.Type).Equal("log.{tag}")
+
+	cfg["appender.console.layout.eventType"] = ""
+	err = RefreshConfig(cfg)
+	assert.Error(t, err).Matches("layout start error: cloudevents type is empty")
+}
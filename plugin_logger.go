@@ -17,6 +17,12 @@
 package log
 
 import (
+	"bytes"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -24,8 +30,6 @@ import (
 )
 
 func init() {
-	RegisterConverter(ParseBufferFullPolicy)
-
 	RegisterPlugin[SyncLogger]("Logger")
 	RegisterPlugin[SyncLogger]("SyncLogger")
 	RegisterPlugin[AsyncLogger]("AsyncLogger")
@@ -37,6 +41,9 @@ func init() {
 
 // Logger is the interface implemented by all logger implementations.
 // A Logger receives log events and forwards them to one or more appenders.
+//
+// Stop must not return before the logger has finished writing to its
+// appenders, since they are stopped right after it, see Destroy.
 type Logger interface {
 	Lifecycle             // Start/Stop methods for resource management
 	GetName() string      // Appender's name
@@ -50,19 +57,143 @@ type Logger interface {
 // corresponding Appender instance is injected into the Appender field.
 //
 // Level optionally restricts the level range forwarded to this appender.
+// If it is empty or "all", or left unset when the AppenderRef is built in
+// code, all the events of the logger are forwarded, so the appender
+// inherits the level range of the logger.
+//
+// Layout optionally renders the events for this appender only. It takes
+// precedence over the layouts of both the logger and the appender, so
+// the same event may be written as JSON to one appender and as text to
+// another. Raw data written by Write is forwarded as is.
 type AppenderRef struct {
 	Appender
-	Ref   string     `PluginAttribute:"ref"`
-	Level LevelRange `PluginAttribute:"level,default="`
+	Ref    string     `PluginAttribute:"ref"`
+	Level  LevelRange `PluginAttribute:"level,default="`
+	Layout Layout     `PluginElement:"layout?"`
 }
 
 // Append forwards the event to the referenced appender if the level matches.
 func (c *AppenderRef) Append(e *Event) {
-	if c.Level.Enable(e.Level) {
+	if c.enable(e.Level) {
+		raw, buf := c.render(e)
 		c.Appender.Append(e)
+		c.restore(e, raw, buf)
+	}
+}
+
+// enable reports whether events of the level are forwarded.
+// An unset Level, which would forward nothing, forwards everything.
+func (c *AppenderRef) enable(l Level) bool {
+	return c.Level.isZero() || c.Level.Enable(l)
+}
+
+// render encodes the event with the layout of the ref, if any, into
+// e.RawBytes. It returns the previous raw bytes and the buffer to be
+// passed to restore once the event has been forwarded.
+func (c *AppenderRef) render(e *Event) ([]byte, *bytes.Buffer) {
+	if c.Layout == nil || (e.RawBytes != nil && !e.rendered) {
+		return nil, nil
+	}
+	raw := e.RawBytes
+	buf := getBuffer()
+	c.Layout.EncodeTo(e, buf)
+	e.RawBytes = buf.Bytes()
+	return raw, buf
+}
+
+// restore undoes render, so that the other refs see the event unchanged.
+func (c *AppenderRef) restore(e *Event, raw []byte, buf *bytes.Buffer) {
+	if buf != nil {
+		e.RawBytes = raw
+		putBuffer(buf)
 	}
 }
 
+// AppendBatch forwards the events whose level matches to the referenced
+// appender, in a single call if the appender implements BatchAppender.
+func (c *AppenderRef) AppendBatch(events []*Event) {
+	b, ok := c.Appender.(BatchAppender)
+	if !ok || len(events) == 1 {
+		for _, e := range events {
+			c.Append(e)
+		}
+		return
+	}
+	matched := events
+	for i, e := range events {
+		if !c.enable(e.Level) {
+			matched = slices.Clone(events[:i])
+			for _, x := range events[i+1:] {
+				if c.enable(x.Level) {
+					matched = append(matched, x)
+				}
+			}
+			break
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+	if c.Layout == nil {
+		b.AppendBatch(matched)
+		return
+	}
+	raws := make([][]byte, len(matched))
+	bufs := make([]*bytes.Buffer, len(matched))
+	for i, e := range matched {
+		raws[i], bufs[i] = c.render(e)
+	}
+	b.AppendBatch(matched)
+	for i, e := range matched {
+		c.restore(e, raws[i], bufs[i])
+	}
+}
+
+// EffectiveRanges returns, for each appender ref of the logger, the
+// levels the referenced appender actually receives: the level range of
+// the logger narrowed by the level of the ref. It returns nil if the
+// logger doesn't support appender references.
+func EffectiveRanges(l Logger) []LevelRange {
+	i, ok := l.(AppenderRefs)
+	if !ok {
+		return nil
+	}
+	_, appenderRefs := i.GetAppenderRefs()
+	ranges := make([]LevelRange, len(appenderRefs))
+	for j, r := range appenderRefs {
+		ranges[j] = l.GetLevel()
+		if !r.Level.isZero() {
+			ranges[j] = ranges[j].intersect(r.Level)
+		}
+	}
+	return ranges
+}
+
+// LayoutLogger is implemented by loggers that may render events with their
+// own layout. If the layout is not nil, each event is rendered once by the
+// logger, and the appenders write the rendered bytes as is, so the layouts
+// of the appenders are not used. An AppenderRef with its own layout still
+// renders the events again for its appender. Refresh rejects an appender
+// whose layout is configured explicitly if it is referenced by such a
+// logger.
+type LayoutLogger interface {
+	GetLayout() Layout
+}
+
+// renderEvent encodes the event with the layout into e.RawBytes, unless
+// it is already raw. The returned buffer, if not nil, must be released
+// by putBuffer once the event has been written.
+func renderEvent(e *Event, layout Layout) *bytes.Buffer {
+	if layout == nil || e.RawBytes != nil {
+		return nil
+	}
+	buf := getBuffer()
+	layout.EncodeTo(e, buf)
+	e.RawBytes = buf.Bytes()
+	e.rendered = true
+	return buf
+}
+
 // AppenderRefs is implemented by loggers that support appender references.
 type AppenderRefs interface {
 	// GetAppenderRefs returns the logger's synchronization mode
@@ -74,6 +205,19 @@ type AppenderRefs interface {
 	// In async mode, appenders are invoked by a single background goroutine,
 	// so they do not require strict thread safety.
 	GetAppenderRefs() (syncMode bool, _ []*AppenderRef)
+
+	// SetAppenderRefs replaces the list of appender references before the
+	// logger is started, e.g. to drop the refs to missing appenders.
+	SetAppenderRefs(refs []*AppenderRef)
+}
+
+// OverflowAppenderRef is implemented by loggers that support an overflow
+// appender for events they cannot buffer. The overflow appender is invoked
+// in the caller goroutines, so it must be concurrent-safe.
+type OverflowAppenderRef interface {
+	// GetOverflowAppenderRef returns the overflow appender reference,
+	// or nil if it is not configured.
+	GetOverflowAppenderRef() *AppenderRef
 }
 
 // LoggerBase contains fields shared by all logger configurations.
@@ -81,11 +225,37 @@ type LoggerBase struct {
 	Name  string     `PluginAttribute:"name"`           // Logger name
 	Tags  []string   `PluginAttribute:"tag,default=*"`  // Optional tags associated with this logger
 	Level LevelRange `PluginAttribute:"level,default="` // Level range handled by this logger
+
+	// Filters drop the events that any of them rejects, after the level
+	// check, see Filter.
+	Filters []Filter `PluginElement:"filter?"`
+
+	level atomic.Pointer[LevelRange] // Level set by SetLevel, overrides Level
 }
 
-func (c *LoggerBase) GetName() string      { return c.Name }
-func (c *LoggerBase) GetTags() []string    { return c.Tags }
-func (c *LoggerBase) GetLevel() LevelRange { return c.Level }
+func (c *LoggerBase) GetName() string   { return c.Name }
+func (c *LoggerBase) GetTags() []string { return c.Tags }
+
+// GetLevel returns the level range set by SetLevel, or Level if it has
+// not been called.
+func (c *LoggerBase) GetLevel() LevelRange {
+	if l := c.level.Load(); l != nil {
+		return *l
+	}
+	return c.Level
+}
+
+// accept reports whether the logger handles the event, i.e. its level
+// is enabled and no filter rejects it.
+func (c *LoggerBase) accept(e *Event) bool {
+	return c.GetLevel().Enable(e.Level) && acceptFilters(c.Filters, e)
+}
+
+// SetLevel atomically replaces the level range of a running logger,
+// without restarting it or its appenders, see SetLevels.
+func (c *LoggerBase) SetLevel(l LevelRange) {
+	c.level.Store(&l)
+}
 
 var (
 	_ Logger = (*DiscardLogger)(nil)
@@ -94,6 +264,9 @@ var (
 	_ Logger = (*AsyncLogger)(nil)
 	_ Logger = (*FileLogger)(nil)
 	_ Logger = (*RollingFileLogger)(nil)
+
+	_ LayoutLogger = (*SyncLogger)(nil)
+	_ LayoutLogger = (*AsyncLogger)(nil)
 )
 
 // SyncLogger is a synchronous logger that forwards events to appenders
@@ -101,6 +274,18 @@ var (
 type SyncLogger struct {
 	LoggerBase
 	AppenderRefs []*AppenderRef `PluginElement:"appenderRef"`
+	Layout       Layout         `PluginElement:"layout?"` // see LayoutLogger
+
+	// FlushLevel makes Append sync the appenders that implement
+	// SyncAppender after writing an event at or above this level, so that
+	// e.g. a fatal event is on disk before the process exits. The default
+	// "none" disables it.
+	FlushLevel Level `PluginAttribute:"flushLevel,default=none"`
+}
+
+// GetLayout returns the layout of the logger, which may be nil.
+func (c *SyncLogger) GetLayout() Layout {
+	return c.Layout
 }
 
 // GetAppenderRefs returns true for sync mode and the appender refs.
@@ -108,19 +293,43 @@ func (c *SyncLogger) GetAppenderRefs() (syncMode bool, _ []*AppenderRef) {
 	return true, c.AppenderRefs
 }
 
+// SetAppenderRefs replaces the appender refs.
+func (c *SyncLogger) SetAppenderRefs(refs []*AppenderRef) {
+	c.AppenderRefs = refs
+}
+
 func (c *SyncLogger) Start() error { return nil }
 func (c *SyncLogger) Stop()        {}
 
 // Append sends the event directly to appenders.
 func (c *SyncLogger) Append(e *Event) {
-	if c.Level.Enable(e.Level) {
+	if c.accept(e) {
+		buf := renderEvent(e, c.Layout)
 		for _, r := range c.AppenderRefs {
 			r.Append(e)
 		}
+		if buf != nil {
+			putBuffer(buf)
+		}
+		if NoneLevel.Less(c.FlushLevel) && e.Level.AtLeast(c.FlushLevel) {
+			syncAppenders(c.AppenderRefs)
+		}
 	}
 	e.Reset()
 }
 
+// syncAppenders syncs the referenced appenders that implement SyncAppender,
+// errors are reported via ReportError.
+func syncAppenders(refs []*AppenderRef) {
+	for _, r := range refs {
+		if a, ok := r.Appender.(SyncAppender); ok {
+			if err := a.Sync(); err != nil {
+				internalErrorf(err, "sync appender %s error", r.Ref)
+			}
+		}
+	}
+}
+
 // BufferFullPolicy specifies how AsyncLogger behaves when its buffer is full.
 type BufferFullPolicy int
 
@@ -144,6 +353,28 @@ func ParseBufferFullPolicy(s string) (BufferFullPolicy, error) {
 	}
 }
 
+// Ratio is a fraction in [0,1], e.g. a sampling rate.
+type Ratio float64
+
+// ParseRatio converts a bare float like "0.1" or a percentage like "10%"
+// to a Ratio, which must be between 0 and 1 inclusive.
+func ParseRatio(s string) (Ratio, error) {
+	str := strings.TrimSpace(s)
+	scale := 1.0
+	if v, ok := strings.CutSuffix(str, "%"); ok {
+		str, scale = strings.TrimSpace(v), 100
+	}
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, errutil.Explain(nil, "invalid ratio %q", s)
+	}
+	f /= scale
+	if math.IsNaN(f) || f < 0 || f > 1 {
+		return 0, errutil.Explain(nil, "ratio %q out of range [0,1]", s)
+	}
+	return Ratio(f), nil
+}
+
 // AsyncLogger is an asynchronous logger that buffers events in a channel
 // and processes them in a background goroutine.
 type AsyncLogger struct {
@@ -151,10 +382,34 @@ type AsyncLogger struct {
 	AppenderRefs []*AppenderRef   `PluginElement:"appenderRef"`
 	BufferSize   int              `PluginAttribute:"bufferSize,default=10000"`
 	OnBufferFull BufferFullPolicy `PluginAttribute:"onBufferFull,default=discard"`
-
-	buf  chan *Event   // Channel buffering events
-	wait chan struct{} // Waiting for the worker goroutine to finish
-	stop *Event        // Sentinel value used to signal shutdown
+	Layout       Layout           `PluginElement:"layout?"` // see LayoutLogger
+
+	// OverflowRef optionally receives the events discarded because the
+	// buffer is full, so that they are spilled instead of being lost.
+	OverflowRef *AppenderRef `PluginElement:"overflowRef?"`
+
+	// MaxBatchSize is the maximum number of buffered events the worker
+	// forwards to the appenders at once, see BatchAppender.
+	MaxBatchSize int `PluginAttribute:"maxBatchSize,default=1"`
+
+	// MaxBatchBytes caps a batch by the estimated rendered size of its
+	// events, so that a batch of large events does not cause a latency
+	// spike. A batch is complete once it reaches this size.
+	// Zero means no limit.
+	MaxBatchBytes int `PluginAttribute:"maxBatchBytes,default=0"`
+
+	// FlushLevel makes events at or above this level drain the buffer
+	// synchronously: they are never discarded, and Append returns only
+	// after they and all events buffered before them have been written
+	// and synced, see SyncLogger. Under drop-oldest, a flush event that
+	// would be evicted is queued again, so it may be written after some
+	// later events. The default "none" disables it.
+	FlushLevel Level `PluginAttribute:"flushLevel,default=none"`
+
+	buf      chan *Event   // Channel buffering events
+	wait     chan struct{} // Waiting for the worker goroutine to finish
+	stop     *Event        // Sentinel value used to signal shutdown
+	stopOnce sync.Once     // Makes Stop idempotent
 
 	discardCounter atomic.Int64 // Count of discarded events
 }
@@ -164,11 +419,28 @@ func (c *AsyncLogger) GetDiscardCounter() int64 {
 	return c.discardCounter.Load()
 }
 
+// GetLayout returns the layout of the logger, which may be nil.
+// Events are rendered by the worker goroutine, except those passed to
+// the overflow appender, which uses its own layout.
+func (c *AsyncLogger) GetLayout() Layout {
+	return c.Layout
+}
+
 // GetAppenderRefs returns false for async mode and the appender references.
 func (c *AsyncLogger) GetAppenderRefs() (syncMode bool, _ []*AppenderRef) {
 	return false, c.AppenderRefs
 }
 
+// SetAppenderRefs replaces the appender refs.
+func (c *AsyncLogger) SetAppenderRefs(refs []*AppenderRef) {
+	c.AppenderRefs = refs
+}
+
+// GetOverflowAppenderRef returns the overflow appender reference.
+func (c *AsyncLogger) GetOverflowAppenderRef() *AppenderRef {
+	return c.OverflowRef
+}
+
 // Start initializes the buffer and starts the background worker goroutine.
 func (c *AsyncLogger) Start() error {
 	if c.BufferSize < 100 {
@@ -178,43 +450,190 @@ func (c *AsyncLogger) Start() error {
 	c.buf = make(chan *Event, c.BufferSize)
 	c.wait = make(chan struct{})
 	c.stop = &Event{}
+	c.stopOnce = sync.Once{}
 
 	// Worker goroutine that processes events from the buffer
-	// and forwards them to appenders.
+	// and forwards them to appenders in batches.
 	go func() {
+		var batch []*Event
 		for e := range c.buf {
 			// Make a best effort to flush all logs before exiting.
-			if e == c.stop {
-				break
+			stop := e == c.stop
+			batch = batch[:0]
+			if !stop {
+				batch, stop = c.collectBatch(append(batch, e))
 			}
-			for _, r := range c.AppenderRefs {
-				r.Append(e)
+			c.appendBatch(batch)
+			if stop {
+				break
 			}
-			e.Reset()
 		}
+		c.discardStopped()
 		close(c.wait)
 	}()
 	return nil
 }
 
+// discardStopped discards the events queued behind the stop signal,
+// releasing the callers waiting for them to be written, see FlushLevel.
+func (c *AsyncLogger) discardStopped() {
+	for {
+		select {
+		case e := <-c.buf:
+			if e.flushed != nil {
+				close(e.flushed)
+			}
+			if !e.flushOnly {
+				c.discard(e)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// collectBatch adds the events already buffered to the batch until
+// it is full. It reports whether the stop signal has been received.
+func (c *AsyncLogger) collectBatch(batch []*Event) (_ []*Event, stop bool) {
+	size := eventSize(batch[0])
+	for len(batch) < c.MaxBatchSize && (c.MaxBatchBytes <= 0 || size < c.MaxBatchBytes) {
+		// Events at or above FlushLevel are written without waiting.
+		if batch[len(batch)-1].flushed != nil {
+			break
+		}
+		select {
+		case e := <-c.buf:
+			if e == c.stop {
+				return batch, true
+			}
+			batch = append(batch, e)
+			size += eventSize(e)
+		default:
+			return batch, false
+		}
+	}
+	return batch, false
+}
+
+// appendBatch forwards the batch to the appenders and releases the events.
+func (c *AsyncLogger) appendBatch(batch []*Event) {
+	if len(batch) == 0 {
+		return
+	}
+	// Only the last event of a batch can wait for a flush, see collectBatch.
+	last := batch[len(batch)-1]
+	events := batch
+	if last.flushOnly {
+		events = batch[:len(batch)-1]
+	}
+	if len(events) > 0 {
+		var bufs []*bytes.Buffer
+		if c.Layout != nil {
+			for _, e := range events {
+				if buf := renderEvent(e, c.Layout); buf != nil {
+					bufs = append(bufs, buf)
+				}
+			}
+		}
+		for _, r := range c.AppenderRefs {
+			r.AppendBatch(events)
+		}
+		for _, buf := range bufs {
+			putBuffer(buf)
+		}
+	}
+	if last.flushed != nil && !last.flushOnly {
+		syncAppenders(c.AppenderRefs)
+	}
+	for _, e := range events {
+		if e.flushed != nil {
+			close(e.flushed)
+		}
+		e.Reset()
+	}
+	if last.flushOnly {
+		close(last.flushed)
+	}
+}
+
+// Flush waits until the events buffered before the call are written,
+// without stopping the logger. The appenders are not synced, see
+// SyncAppender. It returns at once if the logger is not started.
+func (c *AsyncLogger) Flush() {
+	if c.buf == nil {
+		return
+	}
+	flushed := make(chan struct{})
+	c.buf <- &Event{flushed: flushed, flushOnly: true}
+	select {
+	case <-flushed:
+	case <-c.wait: // stopped, the events are written or discarded
+	}
+}
+
+// eventSize estimates the rendered size of an event without encoding it.
+func eventSize(e *Event) int {
+	if e.RawBytes != nil {
+		return len(e.RawBytes)
+	}
+	n := 64 + len(e.Tag) + len(e.CtxString) // level, time and fileLine
+	n += fieldsSize(e.CtxFields)
+	n += fieldsSize(e.Fields)
+	return n
+}
+
+// fieldsSize estimates the rendered size of fields.
+func fieldsSize(fields []Field) int {
+	var n int
+	for _, f := range fields {
+		n += len(f.Key) + 4 // quotes and separators
+		switch f.Type {
+		case ValueTypeString:
+			n += int(f.Num)
+		case ValueTypeObject:
+			n += fieldsSize(f.Any.([]Field))
+		default:
+			n += 16
+		}
+	}
+	return n
+}
+
 // Stop gracefully shuts down the AsyncLogger.
 // It guarantees that events already in the buffer before the stop signal
 // are processed before the background worker goroutine exits.
 func (c *AsyncLogger) Stop() {
-	// To ensure that more log events are written, a blocking approach is used here.
-	c.buf <- c.stop
-	<-c.wait
-	close(c.buf)
+	// Concurrent and repeated calls wait for the first one to finish.
+	c.stopOnce.Do(func() {
+		if c.buf == nil { // not started
+			return
+		}
+		// To ensure that more log events are written, a blocking approach is used here.
+		c.buf <- c.stop
+		<-c.wait
+		close(c.buf)
+	})
 }
 
 // Append enqueues a log event into the async buffer.
 // Behavior on full buffer depends on BufferFullPolicy.
 func (c *AsyncLogger) Append(e *Event) {
-	if !c.Level.Enable(e.Level) {
+	if !c.accept(e) {
 		e.Reset()
 		return
 	}
 
+	if NoneLevel.Less(c.FlushLevel) && e.Level.AtLeast(c.FlushLevel) {
+		flushed := make(chan struct{})
+		e.flushed = flushed
+		c.buf <- e // Block until space is available
+		select {
+		case <-flushed:
+		case <-c.wait: // stopped, the event is written or discarded
+		}
+		return
+	}
+
 	select {
 	case c.buf <- e:
 		return
@@ -226,8 +645,14 @@ func (c *AsyncLogger) Append(e *Event) {
 		for {
 			select {
 			case x := <-c.buf: // Remove one element to make space
-				c.discardCounter.Add(1)
-				x.Reset()
+				// Flush events and the stop signal must reach the worker,
+				// so they are queued again and the new event is dropped.
+				if x == c.stop || x.flushed != nil {
+					c.buf <- x
+					c.discard(e)
+					return
+				}
+				c.discard(x)
 			default: // for linter
 			}
 			select {
@@ -239,20 +664,29 @@ func (c *AsyncLogger) Append(e *Event) {
 	case BufferFullPolicyBlock:
 		c.buf <- e // Block until space is available
 	case BufferFullPolicyDiscard:
-		c.discardCounter.Add(1)
-		e.Reset()
+		c.discard(e)
 	default: // for linter
 	}
 }
 
+// discard counts an event dropped from the buffer and forwards it
+// to the overflow appender, if one is configured.
+func (c *AsyncLogger) discard(e *Event) {
+	c.discardCounter.Add(1)
+	if c.OverflowRef != nil {
+		c.OverflowRef.Append(e)
+	}
+	e.Reset()
+}
+
 // DiscardLogger ignores all log events (no-op).
 type DiscardLogger struct {
 	LoggerBase
 }
 
-func (d DiscardLogger) Start() error    { return nil }
-func (d DiscardLogger) Stop()           {}
-func (d DiscardLogger) Append(e *Event) { e.Reset() }
+func (d *DiscardLogger) Start() error    { return nil }
+func (d *DiscardLogger) Stop()           {}
+func (d *DiscardLogger) Append(e *Event) { e.Reset() }
 
 // ConsoleLogger writes log events to standard output.
 type ConsoleLogger struct {
@@ -280,9 +714,9 @@ func (c *ConsoleLogger) Stop() {
 	c.appender.Stop()
 }
 
-// Append writes the event to the console if its level is enabled.
+// Append writes the event to the console if the logger accepts it.
 func (c *ConsoleLogger) Append(e *Event) {
-	if c.Level.Enable(e.Level) {
+	if c.accept(e) {
 		c.appender.Append(e)
 	}
 	e.Reset()
@@ -294,6 +728,7 @@ type FileLogger struct {
 	Layout   Layout `PluginElement:"layout,default=TextLayout"`
 	FileDir  string `PluginAttribute:"dir,default=./logs"`
 	FileName string `PluginAttribute:"file"`
+	Truncate bool   `PluginAttribute:"truncate,default=false"`
 
 	appender *FileAppender
 }
@@ -306,6 +741,7 @@ func (c *FileLogger) Start() error {
 		},
 		FileDir:  c.FileDir,
 		FileName: c.FileName,
+		Truncate: c.Truncate,
 	}
 	// Append operation is not managed by the framework,
 	// so we start the appender manually.
@@ -319,17 +755,18 @@ func (c *FileLogger) Stop() {
 	c.appender.Stop()
 }
 
-// Append writes the log event to the file if its level is enabled.
+// Append writes the log event to the file if the logger accepts it.
 func (c *FileLogger) Append(e *Event) {
-	if c.Level.Enable(e.Level) {
+	if c.accept(e) {
 		c.appender.Append(e)
 	}
 	e.Reset()
 }
 
 // RollingFileLogger writes log events to files with time-based rotation
-// and optional level-based separation. It supports both synchronous and
-// asynchronous modes.
+// and optional level-based separation, either into two files, see
+// Separate, or into one file per level range, see Routes. It supports
+// both synchronous and asynchronous modes.
 type RollingFileLogger struct {
 	LoggerBase
 
@@ -348,9 +785,9 @@ type RollingFileLogger struct {
 	// Defaults to "./logs".
 	FileDir string `PluginAttribute:"dir,default=./logs"`
 
-	// Base name of the log file.
+	// Base name of the log file, required unless Routes are set.
 	// Actual file names may include rotation suffixes.
-	FileName string `PluginAttribute:"file"`
+	FileName string `PluginAttribute:"file,default="`
 
 	// If true, warning and error logs are written to a separate file
 	// with ".wf" suffix (e.g. app.log.wf).
@@ -360,14 +797,28 @@ type RollingFileLogger struct {
 	//   - ".wf" file contains WARN and above
 	Separate bool `PluginAttribute:"separate,default=false"`
 
+	// Routes write each level range to its own file instead of FileName,
+	// e.g. debug.log, info.log and error.log. The ranges must not overlap,
+	// and events in no range are dropped. It cannot be used with Separate.
+	Routes []*LevelRoute `PluginElement:"route?"`
+
 	// Rotation interval for log files.
 	// A new file is created after each interval (e.g. 1h, 24h).
 	Interval time.Duration `PluginAttribute:"interval,default=1h"`
 
+	// Time layout of the file name suffix, see RollingFileAppender.
+	FilePattern string `PluginAttribute:"filePattern,default=20060102150405"`
+
+	// Whether to open a new file on start, see RollingFileAppender.
+	RotateOnStart bool `PluginAttribute:"rotateOnStart,default=false"`
+
 	// Maximum retention duration for old log files.
 	// Files older than this duration will be automatically removed.
 	MaxAge time.Duration `PluginAttribute:"maxAge,default=168h"`
 
+	// Maximum number of old log files to keep, zero means no limit.
+	MaxBackups int `PluginAttribute:"maxBackups,default=0"`
+
 	// Whether to enable asynchronous logging.
 	AsyncWrite bool `PluginAttribute:"async,default=false"`
 
@@ -378,69 +829,66 @@ type RollingFileLogger struct {
 	// Behavior when async buffer is full.
 	// Ignored if AsyncWrite is false.
 	OnBufferFull BufferFullPolicy `PluginAttribute:"onBufferFull,default=discard"`
+
+	// Level at or above which events are written synchronously in async
+	// mode, and synced to disk in both modes.
+	FlushLevel Level `PluginAttribute:"flushLevel,default=none"`
+}
+
+// LevelRoute routes a level range of a RollingFileLogger to a file.
+type LevelRoute struct {
+	Level LevelRange `PluginAttribute:"level"`
+	File  string     `PluginAttribute:"file"`
 }
 
 // Start initializes the internal logger and configures rolling file appenders.
 // Depending on AsyncWrite, either SyncLogger or AsyncLogger will be used.
 func (f *RollingFileLogger) Start() error {
 
-	normalMaxLevel := MaxLevel
-	if f.Separate {
-		normalMaxLevel = WarnLevel
-	}
-
-	// Create the appender for the normal log file
-	f.appenders = []*AppenderRef{
-		{
-			Appender: &RollingFileAppender{
-				AppenderBase: AppenderBase{
-					Layout: f.Layout,
-				},
-				FileDir:  f.FileDir,
-				FileName: f.FileName,
-				Interval: f.Interval,
-				MaxAge:   f.MaxAge,
-				SyncLock: !f.AsyncWrite,
-			},
-			Level: LevelRange{
-				MinLevel: f.Level.MinLevel,
-				MaxLevel: normalMaxLevel,
-			},
-		},
-	}
-
-	if f.Separate {
-		// Create the second appender for warning/error logs.
-		f.appenders = append(f.appenders, &AppenderRef{
-			Appender: &RollingFileAppender{
-				AppenderBase: AppenderBase{
-					Layout: f.Layout,
-				},
-				FileDir:  f.FileDir,
-				FileName: f.FileName + ".wf",
-				Interval: f.Interval,
-				MaxAge:   f.MaxAge,
-				SyncLock: !f.AsyncWrite,
-			},
-			Level: LevelRange{
-				MinLevel: normalMaxLevel,
-				MaxLevel: f.Level.MaxLevel,
-			},
-		})
+	switch {
+	case len(f.Routes) > 0:
+		if f.Separate {
+			return errutil.Explain(nil, "separate cannot be used with routes")
+		}
+		f.appenders = nil
+		for i, r := range f.Routes {
+			for _, prev := range f.Routes[:i] {
+				if o := r.Level.intersect(prev.Level); o.MinLevel.Less(o.MaxLevel) {
+					return errutil.Explain(nil, "level ranges of routes %s and %s overlap", prev.File, r.File)
+				}
+			}
+			f.appenders = append(f.appenders, f.newAppenderRef(r.File, r.Level))
+		}
+	case f.FileName == "":
+		return errutil.Explain(nil, "file is required")
+	case f.Separate:
+		// Warning and error logs go to the second file.
+		f.appenders = []*AppenderRef{
+			f.newAppenderRef(f.FileName, LevelRange{MinLevel: NoneLevel, MaxLevel: WarnLevel}),
+			f.newAppenderRef(f.FileName+".wf", LevelRange{MinLevel: WarnLevel, MaxLevel: MaxLevel}),
+		}
+	default:
+		f.appenders = []*AppenderRef{
+			f.newAppenderRef(f.FileName, LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}),
+		}
 	}
 
-	// Initialize the underlay logger
+	// Initialize the underlay logger. The level is checked by Append,
+	// so that SetLevel takes effect without restarting the logger.
+	allLevels := LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}
 	if f.AsyncWrite {
 		f.logger = &AsyncLogger{
-			LoggerBase:   f.LoggerBase,
+			LoggerBase:   LoggerBase{Name: f.Name, Tags: f.Tags, Level: allLevels},
 			AppenderRefs: f.appenders,
 			BufferSize:   f.BufferSize,
 			OnBufferFull: f.OnBufferFull,
+			FlushLevel:   f.FlushLevel,
 		}
 	} else {
 		f.logger = &SyncLogger{
-			LoggerBase:   f.LoggerBase,
+			LoggerBase:   LoggerBase{Name: f.Name, Tags: f.Tags, Level: allLevels},
 			AppenderRefs: f.appenders,
+			FlushLevel:   f.FlushLevel,
 		}
 	}
 
@@ -453,6 +901,27 @@ func (f *RollingFileLogger) Start() error {
 	return f.logger.Start()
 }
 
+// newAppenderRef creates the reference to a rolling file appender that
+// writes the given level range to the given file.
+func (f *RollingFileLogger) newAppenderRef(fileName string, level LevelRange) *AppenderRef {
+	return &AppenderRef{
+		Appender: &RollingFileAppender{
+			AppenderBase: AppenderBase{
+				Layout: f.Layout,
+			},
+			FileDir:       f.FileDir,
+			FileName:      fileName,
+			Interval:      f.Interval,
+			MaxAge:        f.MaxAge,
+			MaxBackups:    f.MaxBackups,
+			SyncLock:      !f.AsyncWrite,
+			FilePattern:   f.FilePattern,
+			RotateOnStart: f.RotateOnStart,
+		},
+		Level: level,
+	}
+}
+
 // Stop stops all appenders managed by this logger
 // and gracefully shuts down the logger.
 func (f *RollingFileLogger) Stop() {
@@ -465,7 +934,19 @@ func (f *RollingFileLogger) Stop() {
 	}
 }
 
-// Append forwards the log event to the internal logger.
+// Append forwards the log event to the internal logger if the logger accepts it.
 func (f *RollingFileLogger) Append(e *Event) {
+	if !f.accept(e) {
+		e.Reset()
+		return
+	}
 	f.logger.Append(e)
 }
+
+// GetDiscardCounter returns the number of events discarded in async mode.
+func (f *RollingFileLogger) GetDiscardCounter() int64 {
+	if l, ok := f.logger.(*AsyncLogger); ok {
+		return l.GetDiscardCounter()
+	}
+	return 0
+}
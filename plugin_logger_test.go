@@ -17,9 +17,16 @@
 package log
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/go-spring/stdlib/flatten"
 	"github.com/go-spring/stdlib/testing/assert"
 )
 
@@ -40,6 +47,41 @@ func TestParseBufferFullPolicy(t *testing.T) {
 	assert.That(t, p).Equal(BufferFullPolicyDropOldest)
 }
 
+func TestParseRatio(t *testing.T) {
+	for s, want := range map[string]Ratio{
+		"10%":   0.1,
+		"0.1":   0.1,
+		"100%":  1,
+		"0%":    0,
+		"1":     1,
+		" 25 %": 0.25,
+	} {
+		r, err := ParseRatio(s)
+		assert.Error(t, err).Nil()
+		assert.That(t, r).Equal(want)
+	}
+
+	_, err := ParseRatio("abc")
+	assert.Error(t, err).Matches(`invalid ratio "abc"`)
+	_, err = ParseRatio("%")
+	assert.Error(t, err).Matches(`invalid ratio "%"`)
+	_, err = ParseRatio("101%")
+	assert.Error(t, err).Matches(`ratio "101%" out of range \[0,1]`)
+	_, err = ParseRatio("1.5")
+	assert.Error(t, err).Matches(`ratio "1.5" out of range \[0,1]`)
+	_, err = ParseRatio("-0.1")
+	assert.Error(t, err).Matches(`ratio "-0.1" out of range \[0,1]`)
+	_, err = ParseRatio("NaN")
+	assert.Error(t, err).Matches(`ratio "NaN" out of range \[0,1]`)
+
+	type SamplePlugin struct {
+		Rate Ratio `PluginAttribute:"rate,default=10%"`
+	}
+	v, err := newPlugin(reflect.TypeFor[SamplePlugin](), "test", flatten.NewPropertiesStorage(flatten.NewProperties(nil)))
+	assert.Error(t, err).Nil()
+	assert.That(t, v.Interface().(*SamplePlugin).Rate).Equal(Ratio(0.1))
+}
+
 type CountAppender struct {
 	Appender
 	count int
@@ -50,6 +92,40 @@ func (c *CountAppender) Append(e *Event) {
 	c.Appender.Append(e)
 }
 
+// BlockAppender blocks every Append until gate is closed.
+type BlockAppender struct {
+	Appender
+	gate  chan struct{}
+	count atomic.Int64
+}
+
+func (c *BlockAppender) Append(e *Event) {
+	<-c.gate
+	c.count.Add(1)
+	c.Appender.Append(e)
+}
+
+// BatchRecordAppender records the size of every batch. The first batch
+// signals entered and blocks until gate is closed.
+type BatchRecordAppender struct {
+	DiscardAppender
+	entered chan struct{}
+	gate    chan struct{}
+	batches []int
+}
+
+func (c *BatchRecordAppender) AppendBatch(events []*Event) {
+	if len(c.batches) == 0 {
+		close(c.entered)
+		<-c.gate
+	}
+	c.batches = append(c.batches, len(events))
+}
+
+func (c *BatchRecordAppender) Append(e *Event) {
+	c.AppendBatch([]*Event{e})
+}
+
 func TestLoggerConfig(t *testing.T) {
 
 	//t.Run("write", func(t *testing.T) {
@@ -261,6 +337,291 @@ func TestAsyncLoggerConfig(t *testing.T) {
 		assert.That(t, l.GetDiscardCounter() > 0).True()
 	})
 
+	t.Run("buffer full - overflow", func(t *testing.T) {
+		levels := LevelRange{
+			MinLevel: NoneLevel,
+			MaxLevel: MaxLevel,
+		}
+		for _, policy := range []BufferFullPolicy{
+			BufferFullPolicyDiscard,
+			BufferFullPolicyDropOldest,
+		} {
+			gate := make(chan struct{})
+			a := &BlockAppender{
+				Appender: &DiscardAppender{},
+				gate:     gate,
+			}
+			overflow := &CountAppender{
+				Appender: &DiscardAppender{},
+			}
+
+			l := &AsyncLogger{
+				LoggerBase: LoggerBase{
+					Level: LevelRange{
+						MinLevel: InfoLevel,
+						MaxLevel: MaxLevel,
+					},
+				},
+				AppenderRefs: []*AppenderRef{
+					{Appender: a, Level: levels},
+				},
+				OverflowRef:  &AppenderRef{Appender: overflow, Level: levels},
+				BufferSize:   100,
+				OnBufferFull: policy,
+			}
+
+			err := l.Start()
+			assert.Error(t, err).Nil()
+
+			for range 500 {
+				l.Append(&Event{Level: InfoLevel})
+			}
+
+			close(gate)
+			l.Stop()
+
+			assert.That(t, l.GetDiscardCounter() > 0).True()
+			assert.That(t, int64(overflow.count)).Equal(l.GetDiscardCounter())
+			assert.That(t, int64(a.count.Load())+l.GetDiscardCounter()).Equal(int64(500))
+		}
+	})
+
+	t.Run("flush level", func(t *testing.T) {
+		gate := make(chan struct{})
+		a := &BlockAppender{
+			Appender: &DiscardAppender{},
+			gate:     gate,
+		}
+
+		l := &AsyncLogger{
+			LoggerBase: LoggerBase{
+				Level: LevelRange{
+					MinLevel: InfoLevel,
+					MaxLevel: MaxLevel,
+				},
+			},
+			AppenderRefs: []*AppenderRef{
+				{
+					Appender: a,
+					Level: LevelRange{
+						MinLevel: NoneLevel,
+						MaxLevel: MaxLevel,
+					},
+				},
+			},
+			BufferSize:   100,
+			OnBufferFull: BufferFullPolicyDiscard,
+			FlushLevel:   ErrorLevel,
+		}
+
+		err := l.Start()
+		assert.Error(t, err).Nil()
+
+		for range 10 {
+			l.Append(&Event{Level: InfoLevel})
+		}
+
+		time.AfterFunc(50*time.Millisecond, func() { close(gate) })
+		l.Append(&Event{Level: ErrorLevel})
+
+		// The ERROR event has forced all buffered INFO events out.
+		assert.That(t, a.count.Load()).Equal(int64(11))
+
+		l.Stop()
+		assert.That(t, l.GetDiscardCounter()).Equal(int64(0))
+	})
+
+	t.Run("flush level - drop oldest", func(t *testing.T) {
+		a := &gatedAppender{gate: make(chan struct{})}
+		l := &AsyncLogger{
+			LoggerBase: LoggerBase{
+				Level: LevelRange{
+					MinLevel: InfoLevel,
+					MaxLevel: MaxLevel,
+				},
+			},
+			AppenderRefs: []*AppenderRef{{Appender: a}},
+			BufferSize:   100,
+			OnBufferFull: BufferFullPolicyDropOldest,
+			FlushLevel:   ErrorLevel,
+		}
+
+		err := l.Start()
+		assert.Error(t, err).Nil()
+
+		// The worker is blocked on the first event.
+		l.Append(&Event{Level: InfoLevel})
+		for len(l.buf) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			l.Append(&Event{Level: ErrorLevel, Fields: []Field{Msg("flush")}})
+			close(done)
+		}()
+		for len(l.buf) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		// The flush event is the oldest one when the buffer gets full.
+		for range 200 {
+			l.Append(&Event{Level: InfoLevel})
+		}
+		close(a.gate)
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("flush event was evicted")
+		}
+		l.Stop()
+
+		var flushed int
+		for _, e := range a.Events() {
+			if e.Message() == "flush" {
+				flushed++
+			}
+		}
+		assert.Number(t, flushed).Equal(1)
+		assert.Number(t, a.Len()+int(l.GetDiscardCounter())).Equal(202)
+	})
+
+	t.Run("flush level - stop", func(t *testing.T) {
+		a := &gatedAppender{gate: make(chan struct{})}
+		l := &AsyncLogger{
+			LoggerBase: LoggerBase{
+				Level: LevelRange{
+					MinLevel: InfoLevel,
+					MaxLevel: MaxLevel,
+				},
+			},
+			AppenderRefs: []*AppenderRef{{Appender: a}},
+			BufferSize:   100,
+			FlushLevel:   ErrorLevel,
+		}
+
+		err := l.Start()
+		assert.Error(t, err).Nil()
+
+		// The worker is blocked on the first event.
+		l.Append(&Event{Level: InfoLevel})
+		for len(l.buf) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			l.Stop()
+			close(stopped)
+		}()
+		for len(l.buf) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		// The flush event is queued behind the stop signal.
+		done := make(chan struct{})
+		go func() {
+			l.Append(&Event{Level: ErrorLevel})
+			close(done)
+		}()
+		for len(l.buf) < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		close(a.gate)
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("flush event blocked after stop")
+		}
+		<-stopped
+
+		assert.Number(t, a.Len()).Equal(1)
+		assert.That(t, l.GetDiscardCounter()).Equal(int64(1))
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			count         int
+			eventSize     int
+			maxBatchSize  int
+			maxBatchBytes int
+			expect        []int
+		}{
+			{
+				name:          "byte cap with many small events",
+				count:         20,
+				eventSize:     10,
+				maxBatchSize:  100,
+				maxBatchBytes: 50,
+				expect:        []int{5, 5, 5, 5},
+			},
+			{
+				name:          "count cap with few large events",
+				count:         5,
+				eventSize:     1000,
+				maxBatchSize:  2,
+				maxBatchBytes: 1 << 20,
+				expect:        []int{2, 2, 1},
+			},
+			{
+				name:          "single large event exceeds byte cap",
+				count:         3,
+				eventSize:     100,
+				maxBatchSize:  100,
+				maxBatchBytes: 50,
+				expect:        []int{1, 1, 1},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				a := &BatchRecordAppender{
+					entered: make(chan struct{}),
+					gate:    make(chan struct{}),
+				}
+
+				l := &AsyncLogger{
+					LoggerBase: LoggerBase{
+						Level: LevelRange{
+							MinLevel: InfoLevel,
+							MaxLevel: MaxLevel,
+						},
+					},
+					AppenderRefs: []*AppenderRef{
+						{
+							Appender: a,
+							Level: LevelRange{
+								MinLevel: NoneLevel,
+								MaxLevel: MaxLevel,
+							},
+						},
+					},
+					BufferSize:    100,
+					OnBufferFull:  BufferFullPolicyBlock,
+					MaxBatchSize:  tt.maxBatchSize,
+					MaxBatchBytes: tt.maxBatchBytes,
+				}
+
+				err := l.Start()
+				assert.Error(t, err).Nil()
+
+				// The first event blocks the worker until all others are buffered.
+				l.Append(&Event{Level: InfoLevel, RawBytes: []byte("first")})
+				<-a.entered
+				for range tt.count {
+					b := make([]byte, tt.eventSize)
+					l.Append(&Event{Level: InfoLevel, RawBytes: b})
+				}
+				close(a.gate)
+				l.Stop()
+
+				assert.That(t, a.batches).Equal(append([]int{1}, tt.expect...))
+			})
+		}
+	})
+
 	t.Run("buffer full - block", func(t *testing.T) {
 		a := &CountAppender{
 			Appender: &DiscardAppender{},
@@ -383,3 +744,320 @@ func TestAsyncLoggerConfig(t *testing.T) {
 	//	assert.That(t, l.GetDiscardCounter() > 0).True()
 	//})
 }
+
+func TestLoggerLayout(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	Stdout = buf
+	defer func() { Stdout = os.Stdout }()
+
+	newEvent := func() *Event {
+		e := getEvent()
+		e.Level = InfoLevel
+		e.Time = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		e.File = "file.go"
+		e.Line = 100
+		e.Tag = "_def"
+		e.Fields = []Field{Msg("hello")}
+		return e
+	}
+	const expect = `{"level":"info","time":"2025-06-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","msg":"hello"}` + "\n"
+
+	newRefs := func(c *CaptureAppender) []*AppenderRef {
+		fullRange := LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}
+		return []*AppenderRef{
+			{Appender: &ConsoleAppender{AppenderBase: AppenderBase{Layout: &TextLayout{}}}, Level: fullRange},
+			{Appender: c, Level: fullRange},
+		}
+	}
+
+	t.Run("sync logger", func(t *testing.T) {
+		buf.Reset()
+		c := &CaptureAppender{}
+		l := &SyncLogger{
+			LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: newRefs(c),
+			Layout:       &JSONLayout{},
+		}
+		l.Append(newEvent())
+		assert.String(t, buf.String()).Equal(expect)
+		assert.String(t, string(c.Events()[0].RawBytes)).Equal(expect)
+	})
+
+	t.Run("async logger", func(t *testing.T) {
+		buf.Reset()
+		c := &CaptureAppender{}
+		l := &AsyncLogger{
+			LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: newRefs(c),
+			BufferSize:   100,
+			MaxBatchSize: 10,
+			Layout:       &JSONLayout{},
+		}
+		err := l.Start()
+		assert.Error(t, err).Nil()
+		l.Append(newEvent())
+		l.Append(newEvent())
+		l.Stop()
+		assert.String(t, buf.String()).Equal(expect + expect)
+		assert.Number(t, c.Len()).Equal(2)
+	})
+
+	t.Run("without layout", func(t *testing.T) {
+		buf.Reset()
+		l := &SyncLogger{
+			LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: newRefs(&CaptureAppender{}),
+		}
+		l.Append(newEvent())
+		assert.String(t, buf.String()).Equal("[INFO][2025-06-01T00:00:00.000][file.go:100] _def||msg=hello\n")
+	})
+}
+
+func TestAppenderRefLayout(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	Stdout = buf
+	defer func() { Stdout = os.Stdout }()
+
+	newEvent := func() *Event {
+		e := getEvent()
+		e.Level = InfoLevel
+		e.Time = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		e.File = "file.go"
+		e.Line = 100
+		e.Tag = "_def"
+		e.Fields = []Field{Msg("hello")}
+		return e
+	}
+	const (
+		jsonExpect = `{"level":"info","time":"2025-06-01T00:00:00.000","fileLine":"file.go:100","tag":"_def","msg":"hello"}` + "\n"
+		textExpect = "[INFO][2025-06-01T00:00:00.000][file.go:100] _def||msg=hello\n"
+	)
+
+	// The console appender writes JSON and the capture appender gets text.
+	newRefs := func(c *CaptureAppender) []*AppenderRef {
+		return []*AppenderRef{
+			{Appender: &ConsoleAppender{AppenderBase: AppenderBase{Layout: &TextLayout{}}}, Layout: &JSONLayout{}},
+			{Appender: c, Layout: &TextLayout{}},
+		}
+	}
+
+	t.Run("sync logger", func(t *testing.T) {
+		buf.Reset()
+		c := &CaptureAppender{}
+		l := &SyncLogger{
+			LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: newRefs(c),
+		}
+		l.Append(newEvent())
+		assert.String(t, buf.String()).Equal(jsonExpect)
+		assert.String(t, string(c.Events()[0].RawBytes)).Equal(textExpect)
+	})
+
+	t.Run("overrides logger layout", func(t *testing.T) {
+		buf.Reset()
+		c := &CaptureAppender{}
+		l := &SyncLogger{
+			LoggerBase: LoggerBase{Level: LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: []*AppenderRef{
+				{Appender: &ConsoleAppender{}},
+				{Appender: c, Layout: &TextLayout{}},
+			},
+			Layout: &JSONLayout{},
+		}
+		l.Append(newEvent())
+		assert.String(t, buf.String()).Equal(jsonExpect)
+		assert.String(t, string(c.Events()[0].RawBytes)).Equal(textExpect)
+	})
+
+	t.Run("async logger", func(t *testing.T) {
+		buf.Reset()
+		c := &CaptureAppender{}
+		l := &AsyncLogger{
+			LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: newRefs(c),
+			BufferSize:   100,
+			MaxBatchSize: 10,
+		}
+		err := l.Start()
+		assert.Error(t, err).Nil()
+		l.Append(newEvent())
+		l.Append(newEvent())
+		l.Stop()
+		assert.String(t, buf.String()).Equal(jsonExpect + jsonExpect)
+		assert.Number(t, c.Len()).Equal(2)
+		for _, e := range c.Events() {
+			assert.String(t, string(e.RawBytes)).Equal(textExpect)
+		}
+	})
+
+	t.Run("raw data", func(t *testing.T) {
+		buf.Reset()
+		c := &CaptureAppender{}
+		l := &SyncLogger{
+			LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: newRefs(c),
+		}
+		e := getEvent()
+		e.Level = InfoLevel
+		e.RawBytes = []byte("raw\n")
+		l.Append(e)
+		assert.String(t, buf.String()).Equal("raw\n")
+		assert.String(t, string(c.Events()[0].RawBytes)).Equal("raw\n")
+	})
+}
+
+// syncCountAppender is a FileAppender that counts the calls to Sync and
+// checks that the flushed events are already written when it is called.
+type syncCountAppender struct {
+	*FileAppender
+	syncs   atomic.Int64
+	written atomic.Int64 // lines in the file at the last sync
+}
+
+func (c *syncCountAppender) Sync() error {
+	b, err := os.ReadFile(c.file.Load().Name())
+	if err != nil {
+		return err
+	}
+	c.written.Store(int64(bytes.Count(b, []byte("\n"))))
+	c.syncs.Add(1)
+	return c.FileAppender.Sync()
+}
+
+func TestLoggerFlushLevel(t *testing.T) {
+
+	newAppender := func(t *testing.T) *syncCountAppender {
+		a := &syncCountAppender{FileAppender: &FileAppender{
+			AppenderBase: AppenderBase{Layout: &TextLayout{}},
+			FileDir:      t.TempDir(),
+			FileName:     "app.log",
+		}}
+		err := a.Start()
+		assert.Error(t, err).Nil()
+		t.Cleanup(a.Stop)
+		return a
+	}
+
+	t.Run("sync logger", func(t *testing.T) {
+		a := newAppender(t)
+		l := &SyncLogger{
+			LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: InfoLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: []*AppenderRef{{Appender: a}},
+			FlushLevel:   ErrorLevel,
+		}
+		l.Append(&Event{Level: InfoLevel, Fields: []Field{Msg("info")}})
+		assert.That(t, a.syncs.Load()).Equal(int64(0))
+
+		l.Append(&Event{Level: FatalLevel, Fields: []Field{Msg("fatal")}})
+		assert.That(t, a.syncs.Load()).Equal(int64(1))
+		assert.That(t, a.written.Load()).Equal(int64(2))
+
+		// disabled by default
+		l.FlushLevel = Level{}
+		l.Append(&Event{Level: FatalLevel, Fields: []Field{Msg("fatal")}})
+		assert.That(t, a.syncs.Load()).Equal(int64(1))
+	})
+
+	t.Run("async logger", func(t *testing.T) {
+		a := newAppender(t)
+		l := &AsyncLogger{
+			LoggerBase:   LoggerBase{Level: LevelRange{MinLevel: InfoLevel, MaxLevel: MaxLevel}},
+			AppenderRefs: []*AppenderRef{{Appender: a}},
+			BufferSize:   100,
+			FlushLevel:   ErrorLevel,
+		}
+		err := l.Start()
+		assert.Error(t, err).Nil()
+		defer l.Stop()
+
+		for range 10 {
+			l.Append(&Event{Level: InfoLevel, Fields: []Field{Msg("info")}})
+		}
+		l.Append(&Event{Level: FatalLevel, Fields: []Field{Msg("fatal")}})
+		assert.That(t, a.syncs.Load()).Equal(int64(1))
+		assert.That(t, a.written.Load()).Equal(int64(11))
+	})
+
+	t.Run("file appenders", func(t *testing.T) {
+		a := &FileAppender{FileDir: t.TempDir(), FileName: "app.log"}
+		assert.Error(t, a.Sync()).Nil() // not started
+		err := a.Start()
+		assert.Error(t, err).Nil()
+		assert.Error(t, a.Sync()).Nil()
+		a.Stop()
+
+		r := &RollingFileAppender{FileDir: t.TempDir(), FileName: "app.log", Interval: time.Hour}
+		err = r.Start()
+		assert.Error(t, err).Nil()
+		assert.Error(t, r.Sync()).Nil() // no file yet
+		r.Append(&Event{Level: InfoLevel, Fields: []Field{Msg("hello")}})
+		assert.Error(t, r.Sync()).Nil()
+		r.Stop()
+	})
+}
+
+func TestRollingFileLoggerRoutes(t *testing.T) {
+	dir := t.TempDir()
+	s := flatten.NewPropertiesStorage(flatten.NewProperties(nil))
+	s.Set("logger.dir", dir)
+	s.Set("logger.route[0].level", "trace..debug")
+	s.Set("logger.route[0].file", "debug.log")
+	s.Set("logger.route[1].level", "info..warn")
+	s.Set("logger.route[1].file", "info.log")
+	s.Set("logger.route[2].level", "error")
+	s.Set("logger.route[2].file", "error.log")
+	v, err := newPlugin(reflect.TypeFor[RollingFileLogger](), "logger", s)
+	assert.Error(t, err).Nil()
+	l := v.Interface().(*RollingFileLogger)
+	l.Level = LevelRange{MinLevel: NoneLevel, MaxLevel: MaxLevel}
+
+	err = l.Start()
+	assert.Error(t, err).Nil()
+	for _, level := range []Level{TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, FatalLevel} {
+		l.Append(&Event{Level: level, Fields: []Field{Msg(level.LowerName())}})
+	}
+	l.Stop()
+
+	for file, want := range map[string][]string{
+		"debug.log": {"trace", "debug"},
+		"info.log":  {"info", "warn"},
+		"error.log": {"error", "fatal"},
+	} {
+		matches, err := filepath.Glob(filepath.Join(dir, file+".*"))
+		assert.Error(t, err).Nil()
+		assert.Number(t, len(matches)).Equal(1)
+		b, err := os.ReadFile(matches[0])
+		assert.Error(t, err).Nil()
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		assert.Number(t, len(lines)).Equal(len(want))
+		for i, line := range lines {
+			assert.String(t, line).HasSuffix("msg=" + want[i])
+		}
+	}
+
+	t.Run("overlap", func(t *testing.T) {
+		l := &RollingFileLogger{
+			FileDir: t.TempDir(),
+			Routes: []*LevelRoute{
+				{Level: LevelRange{MinLevel: TraceLevel, MaxLevel: WarnLevel}, File: "debug.log"},
+				{Level: LevelRange{MinLevel: InfoLevel, MaxLevel: MaxLevel}, File: "info.log"},
+			},
+		}
+		err := l.Start()
+		assert.Error(t, err).Matches("level ranges of routes debug.log and info.log overlap")
+	})
+
+	t.Run("separate", func(t *testing.T) {
+		l := &RollingFileLogger{
+			Separate: true,
+			Routes:   []*LevelRoute{{Level: LevelRange{MinLevel: TraceLevel, MaxLevel: MaxLevel}, File: "app.log"}},
+		}
+		err := l.Start()
+		assert.Error(t, err).Matches("separate cannot be used with routes")
+	})
+
+	t.Run("no file", func(t *testing.T) {
+		err := (&RollingFileLogger{}).Start()
+		assert.Error(t, err).Matches("file is required")
+	})
+}
@@ -17,9 +17,12 @@
 package log
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/go-spring/stdlib/errutil"
 )
 
 // File is a reference-counted wrapper around *os.File.
@@ -29,6 +32,7 @@ type File struct {
 	name  string
 	file  *os.File
 	count int
+	mutex sync.RWMutex // guards file, which ReopenFiles replaces
 }
 
 // Name returns the name of the file.
@@ -38,9 +42,42 @@ func (f *File) Name() string {
 
 // Write writes to the file.
 func (f *File) Write(p []byte) (int, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
 	return f.file.Write(p)
 }
 
+// Sync commits the content of the file to stable storage.
+func (f *File) Sync() error {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.file.Sync()
+}
+
+// reopen replaces the underlying file with a newly opened one at the
+// same path, and closes the previous one. Nothing is done if the path
+// still refers to the open file, i.e. it has not been moved or removed.
+func (f *File) reopen() error {
+	f.mutex.RLock()
+	curr, err := f.file.Stat()
+	f.mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(f.name); err == nil && os.SameFile(fi, curr) {
+		return nil
+	}
+	file, err := os.OpenFile(f.name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	old := f.file
+	f.file = file
+	f.mutex.Unlock()
+	return old.Close()
+}
+
 var fileManager = struct {
 	files map[string]*File
 	mutex sync.Mutex
@@ -50,8 +87,20 @@ var fileManager = struct {
 
 // OpenFile returns a shared File for the given name.
 // If the file is already open, its reference count is increased.
-// Otherwise, the file is opened and tracked.
+// Otherwise, the file is opened in append mode and tracked.
 func OpenFile(name string) (*File, error) {
+	return openFile(name, false)
+}
+
+// OpenTruncFile is like OpenFile, but truncates the file when it is opened.
+// A file that is already open is shared as is, without being truncated.
+func OpenTruncFile(name string) (*File, error) {
+	return openFile(name, true)
+}
+
+// openFile returns a shared File for the given name,
+// truncating the file if it is opened here and trunc is set.
+func openFile(name string, trunc bool) (*File, error) {
 	name, err := filepath.Abs(name)
 	if err != nil {
 		return nil, err
@@ -65,7 +114,10 @@ func OpenFile(name string) (*File, error) {
 		return v, nil
 	}
 
-	const fileFlag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	fileFlag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if trunc {
+		fileFlag |= os.O_TRUNC
+	}
 	f, err := os.OpenFile(name, fileFlag, 0644)
 	if err != nil {
 		return nil, err
@@ -93,5 +145,40 @@ func CloseFile(f *File) {
 	}
 
 	delete(fileManager.files, f.name)
-	_ = v.file.Close()
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if err := v.file.Close(); err != nil {
+		internalErrorf(err, "close file %s error", f.name)
+	}
+}
+
+// ReopenFiles closes and reopens the files opened by OpenFile whose paths
+// no longer refer to them, e.g. from a SIGHUP handler after logrotate has
+// moved them away. The writes in progress complete in the previous files,
+// and the following ones go to new files created at the same paths:
+//
+//	c := make(chan os.Signal, 1)
+//	signal.Notify(c, syscall.SIGHUP)
+//	go func() {
+//		for range c {
+//			if err := log.ReopenFiles(); err != nil {
+//				// report the error
+//			}
+//		}
+//	}()
+//
+// A file that cannot be reopened keeps being written as before, and the
+// errors are returned together. Rolling file appenders rotate their files
+// by themselves, so their files are normally left as they are.
+func ReopenFiles() error {
+	fileManager.mutex.Lock()
+	defer fileManager.mutex.Unlock()
+
+	var errs []error
+	for name, f := range fileManager.files {
+		if err := f.reopen(); err != nil {
+			errs = append(errs, errutil.Explain(err, "reopen file %s error", name))
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -17,8 +17,10 @@
 package log
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/go-spring/stdlib/flatten"
 	"github.com/go-spring/stdlib/testing/assert"
@@ -33,6 +35,47 @@ func TestRegisterPlugin(t *testing.T) {
 	}, "duplicate plugin name \"FileAppender\" in .*/plugin_appender.go:.* and .*/plugin_test.go:.*")
 }
 
+type testPoint struct{ X, Y int }
+
+func TestConverters(t *testing.T) {
+	defer ResetConverters()
+
+	builtins := []reflect.Type{
+		reflect.TypeFor[BufferFullPolicy](),
+		reflect.TypeFor[Level](),
+		reflect.TypeFor[LevelRange](),
+		reflect.TypeFor[Ratio](),
+		reflect.TypeFor[TimePrecision](),
+		reflect.TypeFor[time.Duration](),
+	}
+	assert.That(t, ListConverters()).Equal(builtins)
+
+	RegisterConverter(func(s string) (testPoint, error) {
+		var p testPoint
+		_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+		return p, err
+	})
+	assert.That(t, ListConverters()).Equal([]reflect.Type{
+		builtins[0], builtins[1], builtins[2], builtins[3], builtins[4],
+		reflect.TypeFor[testPoint](),
+		builtins[5],
+	})
+
+	type PointPlugin struct {
+		Point testPoint `PluginAttribute:"point"`
+	}
+	typ := reflect.TypeFor[PointPlugin]()
+	ps := flatten.NewProperties(nil)
+	s := flatten.NewPropertiesStorage(ps)
+	s.Set("test.point", "1,2")
+	v, err := newPlugin(typ, "test", s)
+	assert.Error(t, err).Nil()
+	assert.That(t, v.Interface().(*PointPlugin).Point).Equal(testPoint{X: 1, Y: 2})
+
+	ResetConverters()
+	assert.That(t, ListConverters()).Equal(builtins)
+}
+
 func TestInjectAttribute(t *testing.T) {
 
 	t.Run("no attribute - 1", func(t *testing.T) {
@@ -126,6 +169,51 @@ func TestInjectAttribute(t *testing.T) {
 		assert.Error(t, err).Matches(`inject field ErrorPlugin.N error >> parse "abc" to bool error: strconv.ParseBool: parsing "abc": invalid syntax`)
 	})
 
+	t.Run("lenient boolean", func(t *testing.T) {
+		type BoolPlugin struct {
+			B bool `PluginAttribute:"b"`
+		}
+		typ := reflect.TypeFor[BoolPlugin]()
+		for val, want := range map[string]bool{
+			"yes": true, "no": false,
+			"on": true, "off": false,
+			"enabled": true, "disabled": false,
+			"1": true, "0": false,
+			"YES": true, "Off": false,
+		} {
+			ps := flatten.NewProperties(nil)
+			s := flatten.NewPropertiesStorage(ps)
+			s.Set("test.b", val)
+			v, err := newPlugin(typ, "test", s)
+			assert.Error(t, err).Nil()
+			assert.That(t, v.Interface().(*BoolPlugin).B).Equal(want)
+		}
+
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.b", "maybe")
+		_, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Matches(`inject field BoolPlugin.B error >> parse "maybe" to bool error: strconv.ParseBool: parsing "maybe": invalid syntax`)
+	})
+
+	t.Run("strict boolean", func(t *testing.T) {
+		type BoolPlugin struct {
+			B  bool   `PluginAttribute:"b,strict=true"`
+			BS []bool `PluginAttribute:"bs,strict=true,default=true"`
+		}
+		typ := reflect.TypeFor[BoolPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.b", "yes")
+		_, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Matches(`inject field BoolPlugin.B error >> parse "yes" to bool error: strconv.ParseBool: parsing "yes": invalid syntax`)
+
+		s.Set("test.b", "true")
+		s.Set("test.bs", "1,on")
+		_, err = newPlugin(typ, "test", s)
+		assert.Error(t, err).Matches(`inject BS\[1\] error >> parse "on" to bool error`)
+	})
+
 	t.Run("type error", func(t *testing.T) {
 		type ErrorPlugin struct {
 			M chan error `PluginAttribute:"m,default=true"`
@@ -238,6 +326,46 @@ func TestInjectAttribute(t *testing.T) {
 		assert.Slice(t, p.Values).Equal([]string{"property", "value", "array"})
 	})
 
+	t.Run("property default - applied", func(t *testing.T) {
+		type TestPlugin struct {
+			Value string `PluginAttribute:"value"`
+		}
+		typ := reflect.TypeFor[TestPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.value", "${prop.value:-${prop.other}.log}")
+		s.Set("prop.other", "app")
+		v, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Nil()
+		assert.String(t, v.Interface().(*TestPlugin).Value).Equal("app.log")
+	})
+
+	t.Run("property default - value present", func(t *testing.T) {
+		type TestPlugin struct {
+			Value string `PluginAttribute:"value"`
+		}
+		typ := reflect.TypeFor[TestPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.value", "${prop.value:-default}")
+		s.Set("prop.value", "present")
+		v, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Nil()
+		assert.String(t, v.Interface().(*TestPlugin).Value).Equal("present")
+	})
+
+	t.Run("property required - missing", func(t *testing.T) {
+		type ErrorPlugin struct {
+			Value string `PluginAttribute:"value"`
+		}
+		typ := reflect.TypeFor[ErrorPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.value", "${prop.value:?must set prop.value}")
+		_, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Matches(`property "prop.value" is required: must set prop.value`)
+	})
+
 	t.Run("slice conversion error", func(t *testing.T) {
 		type ErrorPlugin struct {
 			Numbers []int `PluginAttribute:"numbers"`
@@ -310,6 +438,18 @@ func TestInjectElement(t *testing.T) {
 		assert.Error(t, err).Matches("inject field ErrorPlugin.Layout error >> plugin NotExistElement not found")
 	})
 
+	t.Run("plugin of another category", func(t *testing.T) {
+		type ErrorPlugin struct {
+			Layout Layout `PluginElement:"layout"`
+		}
+		typ := reflect.TypeFor[ErrorPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.layout.type", "FileAppender")
+		_, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Matches("inject field ErrorPlugin.Layout error >> plugin FileAppender is not a log.Layout")
+	})
+
 	t.Run("plugin not found - slice - interface - 2", func(t *testing.T) {
 		type ErrorPlugin struct {
 			Layout []Layout `PluginElement:"layout"`
@@ -467,4 +607,85 @@ func TestInjectElement(t *testing.T) {
 		assert.That(t, p.Layout).NotNil()
 	})
 
+	type RotationPolicy struct {
+		MaxSize int `PluginAttribute:"maxSize,default=10"`
+	}
+
+	t.Run("success - struct", func(t *testing.T) {
+		type SuccessPlugin struct {
+			Policy RotationPolicy `PluginElement:"policy"`
+		}
+		typ := reflect.TypeFor[SuccessPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.policy.maxSize", "20")
+		v, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Nil()
+		p := v.Interface().(*SuccessPlugin)
+		assert.That(t, p.Policy.MaxSize).Equal(20)
+	})
+
+	t.Run("success - struct pointer", func(t *testing.T) {
+		type SuccessPlugin struct {
+			Policy *RotationPolicy `PluginElement:"policy"`
+		}
+		typ := reflect.TypeFor[SuccessPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.policy.maxSize", "20")
+		v, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Nil()
+		p := v.Interface().(*SuccessPlugin)
+		assert.That(t, p.Policy.MaxSize).Equal(20)
+	})
+
+	t.Run("no element - struct", func(t *testing.T) {
+		type ErrorPlugin struct {
+			Policy RotationPolicy `PluginElement:"policy"`
+		}
+		typ := reflect.TypeFor[ErrorPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		_, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Matches("inject field ErrorPlugin.Policy error >> no plugin type configured and no default specified")
+	})
+
+	t.Run("plugin not found - struct", func(t *testing.T) {
+		type ErrorPlugin struct {
+			Policy RotationPolicy `PluginElement:"policy"`
+		}
+		typ := reflect.TypeFor[ErrorPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.policy.type", "NotExistElement")
+		_, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Matches("inject field ErrorPlugin.Policy error >> plugin NotExistElement not found")
+	})
+
+	t.Run("plugin type mismatch - struct", func(t *testing.T) {
+		type ErrorPlugin struct {
+			Policy RotationPolicy `PluginElement:"policy"`
+		}
+		typ := reflect.TypeFor[ErrorPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		s.Set("test.policy.type", "TextLayout")
+		_, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Matches("inject field ErrorPlugin.Policy error >> plugin TextLayout is not of type log.RotationPolicy")
+	})
+
+	t.Run("nullable - struct", func(t *testing.T) {
+		type SuccessPlugin struct {
+			Policy    RotationPolicy  `PluginElement:"policy?"`
+			PolicyPtr *RotationPolicy `PluginElement:"policyPtr?"`
+		}
+		typ := reflect.TypeFor[SuccessPlugin]()
+		ps := flatten.NewProperties(nil)
+		s := flatten.NewPropertiesStorage(ps)
+		v, err := newPlugin(typ, "test", s)
+		assert.Error(t, err).Nil()
+		p := v.Interface().(*SuccessPlugin)
+		assert.That(t, p.Policy.MaxSize).Equal(0)
+		assert.That(t, p.PolicyPtr).Nil()
+	})
 }